@@ -6,7 +6,6 @@ import (
 	"hooks/internal/hooks"
 	"io"
 	"os"
-	"path/filepath"
 )
 
 func main() {
@@ -26,13 +25,11 @@ func main() {
 		os.Exit(0)
 	}
 
-	auditDir := os.Getenv("HOOK_AUDIT_DIR")
-	if auditDir == "" {
-		home, _ := os.UserHomeDir()
-		auditDir = filepath.Join(home, ".config", "hooks", "audit")
-	}
+	auditDir := hooks.HookDir("HOOK_AUDIT_DIR", "audit")
+
+	stateDir := hooks.StateDir()
 
-	result, code := hooks.Audit(input, auditDir)
+	result, code := hooks.AuditWithState(input, auditDir, stateDir)
 	out, _ := json.Marshal(result)
 	fmt.Println(string(out))
 	os.Exit(code)
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hooks/internal/hooks"
+	"io"
+	"os"
+	"os/exec"
+)
+
+func main() {
+	typecheckDir := hooks.HookDir("HOOK_TYPECHECK_DIR", "typecheck")
+
+	// Detached worker started by the hook below
+	if len(os.Args) == 3 && os.Args[1] == "worker" {
+		hooks.RunCheckWorker(os.Args[2], typecheckDir)
+		return
+	}
+
+	if hooks.IsHookDisabled("background-checks") {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	var input hooks.HookInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	workDir := input.Cwd()
+	if workDir == "" {
+		if workDir, err = os.Getwd(); err != nil {
+			fmt.Println(`{"decision": "allow"}`)
+			os.Exit(0)
+		}
+	}
+
+	stateDir := hooks.StateDir()
+
+	result, code := hooks.EnqueueCheck(input, workDir, stateDir, startWorker)
+	out, _ := json.Marshal(result)
+	fmt.Println(string(out))
+	os.Exit(code)
+}
+
+// startWorker re-runs this binary as a worker that outlives the hook.
+func startWorker(sessionDir string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(self, "worker", sessionDir)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hooks/internal/hooks"
+	"io"
+	"os"
+)
+
+func main() {
+	if hooks.IsHookDisabled("background-results") {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	var input hooks.HookInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	workDir := input.Cwd()
+	if workDir == "" {
+		if workDir, err = os.Getwd(); err != nil {
+			fmt.Println(`{"decision": "allow"}`)
+			os.Exit(0)
+		}
+	}
+
+	stateDir := hooks.StateDir()
+
+	policies := hooks.LoadPolicies(workDir)
+	result, code := hooks.DeliverCheckResults(input, stateDir, policies.BackgroundChecks)
+	out, _ := json.Marshal(result)
+	fmt.Println(string(out))
+	os.Exit(code)
+}
@@ -6,7 +6,6 @@ import (
 	"hooks/internal/hooks"
 	"io"
 	"os"
-	"os/exec"
 	"strings"
 )
 
@@ -37,9 +36,13 @@ func main() {
 		protected = strings.Split(v, ",")
 	}
 
-	currentBranch := getCurrentBranch()
+	// The Shell tool reports where the command runs; fall back to our own cwd.
+	workDir := input.Cwd()
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
 
-	result, code := hooks.BranchGuard(input, protected, currentBranch)
+	result, code := hooks.BranchGuardInDir(input, protected, workDir)
 	out, _ := json.Marshal(result)
 	fmt.Println(string(out))
 	os.Exit(code)
@@ -49,12 +52,3 @@ func optIn(name string) bool {
 	v := strings.ToLower(strings.TrimSpace(os.Getenv(name)))
 	return v == "1" || v == "true" || v == "yes"
 }
-
-func getCurrentBranch() string {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	out, err := cmd.Output()
-	if err != nil {
-		return ""
-	}
-	return strings.TrimSpace(string(out))
-}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hooks/internal/hooks"
+	"io"
+	"os"
+)
+
+func main() {
+	if hooks.IsHookDisabled("checkpoint") {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	var input hooks.HookInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	// Snapshot the repository the tool call runs in; fall back to our own cwd.
+	workDir := input.Cwd()
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
+
+	policies := hooks.LoadPolicies(workDir)
+	result, code := hooks.CheckpointWithPolicy(input, workDir, policies.Checkpoint)
+	out, _ := json.Marshal(result)
+	fmt.Println(string(out))
+	os.Exit(code)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hooks/internal/hooks"
+	"io"
+	"os"
+)
+
+func main() {
+	if hooks.IsHookDisabled("commit-gate") {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	var input hooks.HookInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	workDir := input.Cwd()
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
+
+	policies := hooks.LoadPolicies(workDir)
+	result, code := hooks.CommitGateWithPolicy(input, workDir, policies.CommitGate)
+	out, _ := json.Marshal(result)
+	fmt.Println(string(out))
+	os.Exit(code)
+}
@@ -31,7 +31,13 @@ func main() {
 		os.Exit(0)
 	}
 
-	result, code := hooks.CommitMsgLint(input)
+	workDir := input.Cwd()
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
+
+	policies := hooks.LoadPolicies(workDir)
+	result, code := hooks.CommitMsgLintWithPolicy(input, workDir, policies.CommitMsgLint)
 	out, _ := json.Marshal(result)
 	fmt.Println(string(out))
 	os.Exit(code)
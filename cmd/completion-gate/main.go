@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hooks/internal/hooks"
+	"io"
+	"os"
+)
+
+func main() {
+	if hooks.IsHookDisabled("completion-gate") {
+		fmt.Println(`{}`)
+		os.Exit(0)
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Println(`{}`)
+		os.Exit(0)
+	}
+	var input hooks.HookInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		fmt.Println(`{}`)
+		os.Exit(0)
+	}
+
+	workDir := input.Cwd()
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
+
+	stateDir := hooks.StateDir()
+
+	policies := hooks.LoadPolicies(workDir)
+	result, code := hooks.CompletionGateWithPolicy(input, workDir, stateDir, policies.CompletionGate)
+	out, _ := json.Marshal(result)
+	fmt.Println(string(out))
+	os.Exit(code)
+}
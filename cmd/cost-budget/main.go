@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hooks/internal/hooks"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	if hooks.IsHookDisabled("cost-budget") {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	var input hooks.HookInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	workDir := input.Cwd()
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
+
+	home, _ := os.UserHomeDir()
+	logDir := os.Getenv("HOOK_COST_DIR")
+	if logDir == "" {
+		logDir = filepath.Join(home, ".config", "hooks", "cost")
+	}
+
+	policies := hooks.LoadPolicies(workDir)
+	result, code := hooks.CostBudget(input, logDir, policies.Cost)
+	out, _ := json.Marshal(result)
+	fmt.Println(string(out))
+	os.Exit(code)
+}
@@ -26,13 +26,19 @@ func main() {
 		os.Exit(0)
 	}
 
+	workDir := input.Cwd()
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
+
 	home, _ := os.UserHomeDir()
 	logDir := os.Getenv("HOOK_COST_DIR")
 	if logDir == "" {
 		logDir = filepath.Join(home, ".config", "hooks", "cost")
 	}
 
-	result, code := hooks.CostEstimator(input, logDir)
+	policies := hooks.LoadPolicies(workDir)
+	result, code := hooks.CostEstimatorWithPolicy(input, logDir, policies.Cost)
 	out, _ := json.Marshal(result)
 	fmt.Println(string(out))
 	os.Exit(code)
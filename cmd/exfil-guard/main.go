@@ -0,0 +1,7 @@
+package main
+
+import "hooks/internal/hooks"
+
+func main() {
+	hooks.RunOrDisabled("exfil-guard", hooks.ExfilGuard)
+}
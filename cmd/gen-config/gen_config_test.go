@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"hooks/internal/config"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestBuildAllowlistsJSON_EmitsDependencyTyposquatAndImportGuard(t *testing.T) {
@@ -58,3 +60,44 @@ func TestHasAnyAllowlist(t *testing.T) {
 		t.Error("dependencyTyposquat with packages should be true")
 	}
 }
+
+func TestBuildAllowlistsJSON_EmitsReadGuard(t *testing.T) {
+	a := &config.Allowlists{
+		ReadGuard: &struct {
+			ExemptPaths []string `yaml:"exemptPaths"`
+		}{ExemptPaths: []string{".env.test"}},
+	}
+	if !hasAnyAllowlist(a) {
+		t.Error("readGuard with exemptPaths should be true")
+	}
+	data, _ := json.Marshal(buildAllowlistsJSON(a))
+	var m map[string]map[string][]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m["readGuard"]["exemptPaths"]; len(got) != 1 || got[0] != ".env.test" {
+		t.Errorf("expected readGuard.exemptPaths [.env.test], got %v", got)
+	}
+}
+
+func TestDecodePolicies(t *testing.T) {
+	var cfg config.Config
+	if err := yaml.Unmarshal([]byte("version: 1\npolicies:\n  testRunner:\n    onStop: true\n    timeoutSeconds: 30\n"), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	p, err := decodePolicies(cfg)
+	if err != nil || p == nil || p.TestRunner == nil || !p.TestRunner.OnStop || p.TestRunner.TimeoutSeconds != 30 {
+		t.Fatalf("unexpected policies %+v (err %v)", p, err)
+	}
+
+	if err := yaml.Unmarshal([]byte("version: 1\npolicies:\n  testRunner:\n    timeoutSeconds: soon\n"), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodePolicies(cfg); err == nil {
+		t.Error("expected a wrongly typed policy to be rejected")
+	}
+
+	if p, err := decodePolicies(config.Config{}); p != nil || err != nil {
+		t.Errorf("expected no policies without a policies section, got %+v (err %v)", p, err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"sort"
 
 	"hooks/internal/config"
+	"hooks/internal/hooks"
 
 	"gopkg.in/yaml.v3"
 )
@@ -39,6 +40,19 @@ func allEntries(cfg config.Config) []config.HookEntry {
 	return out
 }
 
+// decodePolicies decodes the policies: section into the hooks' policy types,
+// or returns nil when there is none.
+func decodePolicies(cfg config.Config) (*hooks.Policies, error) {
+	if cfg.Policies.IsZero() {
+		return nil, nil
+	}
+	var p hooks.Policies
+	if err := cfg.Policies.Decode(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
 func validateHookBinaries(cfg config.Config, binDir string) error {
 	seen := make(map[string]bool)
 	for _, e := range allEntries(cfg) {
@@ -79,6 +93,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	policies, err := decodePolicies(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse config policies: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Resolve binPrefix: config.yaml output.binDir > default from config path
 	if cfg.Output != nil && cfg.Output.BinDir != "" {
 		bp := config.ExpandHome(cfg.Output.BinDir)
@@ -199,6 +219,17 @@ func main() {
 		fmt.Println("wrote", allowPath)
 	}
 
+	// Optional .cursor/hooks-policies.json from config.policies
+	if policies != nil {
+		policiesPath := filepath.Join(cursorDir, "hooks-policies.json")
+		data, _ := json.MarshalIndent(policies, "", "  ")
+		if err := os.WriteFile(policiesPath, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "write %s: %v\n", policiesPath, err)
+			os.Exit(1)
+		}
+		fmt.Println("wrote", policiesPath)
+	}
+
 	// Optional: write hooks.json to globalDir (uses same content as Cursor)
 	if wantCursor && cfg.Output != nil && cfg.Output.GlobalDir != "" && len(cursorJSON) > 0 {
 		globalDir := config.ExpandHome(cfg.Output.GlobalDir)
@@ -315,7 +346,7 @@ async function runHooks(manifestKey, toolNameContract, toolInput, directory) {
         else {
           try {
             const res = JSON.parse(out.split("` + "\\n" + `")[0] || "{}");
-            if (res.decision === "deny") reject(new Error(res.reason || "Hook denied"));
+            if (res.decision === "deny" || res.decision === "ask") reject(new Error(res.reason || "Hook denied"));
             else resolve();
           } catch (e) {
             resolve();
@@ -358,6 +389,9 @@ func hasAnyAllowlist(a *config.Allowlists) bool {
 	if a.ImportGuard != nil && len(a.ImportGuard.AllowedPatterns) > 0 {
 		return true
 	}
+	if a.ReadGuard != nil && len(a.ReadGuard.ExemptPaths) > 0 {
+		return true
+	}
 	return false
 }
 
@@ -372,6 +406,9 @@ func buildAllowlistsJSON(a *config.Allowlists) map[string]interface{} {
 	if a.ImportGuard != nil && len(a.ImportGuard.AllowedPatterns) > 0 {
 		out["importGuard"] = map[string]interface{}{"allowedPatterns": a.ImportGuard.AllowedPatterns}
 	}
+	if a.ReadGuard != nil && len(a.ReadGuard.ExemptPaths) > 0 {
+		out["readGuard"] = map[string]interface{}{"exemptPaths": a.ReadGuard.ExemptPaths}
+	}
 	return out
 }
 
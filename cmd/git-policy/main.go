@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hooks/internal/hooks"
+	"io"
+	"os"
+)
+
+func main() {
+	if hooks.IsHookDisabled("git-policy") {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	var input hooks.HookInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	// The Shell tool reports where the command runs; fall back to our own cwd.
+	workDir := input.Cwd()
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
+
+	policies := hooks.LoadPolicies(workDir)
+	result, code := hooks.GitPolicyWithConfig(input, workDir, policies.GitPolicy)
+	out, _ := json.Marshal(result)
+	fmt.Println(string(out))
+	os.Exit(code)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"hooks/internal/hooks"
+	"os"
+	"text/tabwriter"
+)
+
+func runCheckpoints(args []string) {
+	if len(args) == 0 {
+		usage()
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "checkpoints: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		cps, err := hooks.ListCheckpoints(cwd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "checkpoints: %v\n", err)
+			os.Exit(1)
+		}
+		if len(cps) == 0 {
+			fmt.Println("no checkpoints")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTIME\tCOMMIT\tTOOL\tBEFORE")
+		for _, cp := range cps {
+			fmt.Fprintf(w, "%s\t%s\t%.8s\t%s\t%s\n", cp.ID, cp.Time.Format("2006-01-02 15:04:05"), cp.Commit, cp.Tool, cp.Summary)
+		}
+		w.Flush()
+	case "restore":
+		if len(args) < 2 {
+			usage()
+		}
+		backup, err := hooks.RestoreCheckpoint(cwd, args[1], args[2:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "checkpoints: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("restored", args[1])
+		fmt.Println("previous state saved as", backup)
+	default:
+		usage()
+	}
+}
@@ -12,16 +12,38 @@ import (
 //go:embed config_default.yaml
 var defaultConfigYAML []byte
 
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: hooks init [path]\n")
+	fmt.Fprintf(os.Stderr, "  Initialize a repo with .hooks/config.yaml. Path defaults to current directory.\n")
+	fmt.Fprintf(os.Stderr, "       hooks checkpoints list\n")
+	fmt.Fprintf(os.Stderr, "  List working-tree checkpoints saved by the checkpoint hook, newest first.\n")
+	fmt.Fprintf(os.Stderr, "       hooks checkpoints restore <id> [path...]\n")
+	fmt.Fprintf(os.Stderr, "  Restore the working tree (or just paths) from a checkpoint.\n")
+	fmt.Fprintf(os.Stderr, "       hooks time [-since YYYY-MM-DD] [-until YYYY-MM-DD] [-repo name] [-by day|repo|session] [-idle 10m] [-json]\n")
+	fmt.Fprintf(os.Stderr, "  Report active and wall-clock session time per day, repo or session.\n")
+	os.Exit(1)
+}
+
 func main() {
-	if len(os.Args) < 2 || os.Args[1] != "init" {
-		fmt.Fprintf(os.Stderr, "usage: hooks init [path]\n")
-		fmt.Fprintf(os.Stderr, "  Initialize a repo with .hooks/config.yaml. Path defaults to current directory.\n")
-		os.Exit(1)
+	if len(os.Args) < 2 {
+		usage()
 	}
+	switch os.Args[1] {
+	case "init":
+		runInit(os.Args[2:])
+	case "checkpoints":
+		runCheckpoints(os.Args[2:])
+	case "time":
+		runTime(os.Args[2:])
+	default:
+		usage()
+	}
+}
 
+func runInit(args []string) {
 	target := "."
-	if len(os.Args) > 2 {
-		target = os.Args[2]
+	if len(args) > 0 {
+		target = args[0]
 	}
 	absTarget, err := filepath.Abs(target)
 	if err != nil {
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hooks/internal/hooks"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func runTime(args []string) {
+	fs := flag.NewFlagSet("time", flag.ExitOnError)
+	since := fs.String("since", "", "first day to report, YYYY-MM-DD (default: 6 days ago)")
+	until := fs.String("until", "", "last day to report, YYYY-MM-DD (default: today)")
+	repo := fs.String("repo", "", "only this repo")
+	by := fs.String("by", "day", "group by day, repo or session")
+	idle := fs.Duration("idle", 0, "longest gap between tool calls counted as active (default policies.timeTracker.idleMinutes or 10m)")
+	asJSON := fs.Bool("json", false, "print JSON")
+	fs.Parse(args)
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	from, to := today.AddDate(0, 0, -6), today
+	var err error
+	if *since != "" {
+		if from, err = time.ParseInLocation("2006-01-02", *since, time.Local); err != nil {
+			fmt.Fprintf(os.Stderr, "time: bad -since: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *until != "" {
+		if to, err = time.ParseInLocation("2006-01-02", *until, time.Local); err != nil {
+			fmt.Fprintf(os.Stderr, "time: bad -until: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	to = to.AddDate(0, 0, 1)
+
+	if *idle == 0 {
+		*idle = hooks.DefaultIdle
+		cwd, _ := os.Getwd()
+		if p := hooks.LoadPolicies(cwd).TimeTracker; p != nil && p.IdleMinutes > 0 {
+			*idle = time.Duration(p.IdleMinutes) * time.Minute
+		}
+	}
+
+	all, err := hooks.LoadTimeSessions(hooks.HookDir("HOOK_TIME_DIR", "time"), hooks.StateDir(), hooks.HookDir("HOOK_AUDIT_DIR", "audit"), *idle, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "time: %v\n", err)
+		os.Exit(1)
+	}
+	var sessions []hooks.TimeSession
+	for _, s := range all {
+		if s.End.Before(from) || !s.Start.Before(to) || (*repo != "" && s.Repo != *repo) {
+			continue
+		}
+		// Keep only the active time inside the range.
+		byDay := map[string]time.Duration{}
+		s.Active = 0
+		for day, d := range s.ActiveByDay {
+			if t, _ := time.ParseInLocation("2006-01-02", day, time.Local); !t.Before(from) && t.Before(to) {
+				byDay[day] = d
+				s.Active += d
+			}
+		}
+		s.ActiveByDay = byDay
+		sessions = append(sessions, s)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+	switch *by {
+	case "session":
+		if *asJSON {
+			printJSON(sessions)
+			return
+		}
+		fmt.Fprintln(w, "START\tSESSION\tREPO\tBRANCH\tACTIVE\tWALL\tCALLS\tSTATUS")
+		var active, wall time.Duration
+		for _, s := range sessions {
+			fmt.Fprintf(w, "%s\t%.8s\t%s\t%s\t%s\t%s\t%d\t%s\n", s.Start.Format("2006-01-02 15:04"), orDash(s.ID), orDash(s.Repo), orDash(s.Branch), hm(s.Active), hm(s.Wall()), s.ToolCalls, s.Status)
+			active += s.Active
+			wall += s.Wall()
+		}
+		fmt.Fprintf(w, "TOTAL\t%d sessions\t\t\t%s\t%s\t\t\n", len(sessions), hm(active), hm(wall))
+	case "day", "repo":
+		totals := hooks.TimeTotals(sessions, *by == "day")
+		if *asJSON {
+			printJSON(totals)
+			return
+		}
+		if *by == "day" {
+			fmt.Fprintln(w, "DAY\tREPO\tSESSIONS\tACTIVE\tWALL")
+		} else {
+			fmt.Fprintln(w, "REPO\tSESSIONS\tACTIVE\tWALL")
+		}
+		var n int
+		var active, wall time.Duration
+		for _, t := range totals {
+			if *by == "day" {
+				fmt.Fprintf(w, "%s\t", t.Day)
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", t.Repo, t.Sessions, hm(t.Active), hm(t.Wall))
+			n += t.Sessions
+			active += t.Active
+			wall += t.Wall
+		}
+		fmt.Fprint(w, "TOTAL\t")
+		if *by == "day" {
+			fmt.Fprint(w, "\t")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", n, hm(active), hm(wall))
+	default:
+		fmt.Fprintf(os.Stderr, "time: -by must be day, repo or session\n")
+		os.Exit(1)
+	}
+}
+
+// hm formats d as hours:minutes for timesheets.
+func hm(d time.Duration) string {
+	m := int(d.Round(time.Minute).Minutes())
+	return fmt.Sprintf("%d:%02d", m/60, m%60)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func printJSON(v interface{}) {
+	out, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(out))
+}
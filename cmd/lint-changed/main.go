@@ -26,13 +26,18 @@ func main() {
 		os.Exit(0)
 	}
 
-	cwd, err := os.Getwd()
-	if err != nil {
-		fmt.Println(`{"decision": "allow"}`)
-		os.Exit(0)
+	cwd := input.Cwd()
+	if cwd == "" {
+		cwd, _ = os.Getwd()
 	}
 
-	result, code := hooks.LintChanged(input, cwd)
+	policies := hooks.LoadPolicies(cwd)
+	result, code := hooks.LintChangedWithPolicy(input, cwd, policies.LintChanged)
+
+	// Remember open failures for hooks that look at the whole session
+	stateDir := hooks.StateDir()
+	hooks.RecordCheckResult(stateDir, "lint-changed", input, cwd, result, code)
+
 	out, _ := json.Marshal(result)
 	fmt.Println(string(out))
 	os.Exit(code)
@@ -1,7 +1,39 @@
 package main
 
-import "hooks/internal/hooks"
+import (
+	"encoding/json"
+	"fmt"
+	"hooks/internal/hooks"
+	"io"
+	"os"
+)
 
 func main() {
-	hooks.RunOrDisabled("lint-on-write", hooks.LintOnWrite)
+	if hooks.IsHookDisabled("lint-on-write") {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	var input hooks.HookInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	cwd := input.Cwd()
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+
+	policies := hooks.LoadPolicies(cwd)
+	result, code := hooks.LintOnWriteWithPolicy(input, cwd, policies.LintChanged)
+	out, _ := json.Marshal(result)
+	fmt.Println(string(out))
+	os.Exit(code)
 }
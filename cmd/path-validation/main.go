@@ -26,13 +26,13 @@ func main() {
 		os.Exit(0)
 	}
 
-	cwd, err := os.Getwd()
-	if err != nil {
-		fmt.Println(`{"decision": "allow"}`)
-		os.Exit(0)
+	workDir := input.Cwd()
+	if workDir == "" {
+		workDir, _ = os.Getwd()
 	}
 
-	result, code := hooks.PathValidation(input, cwd)
+	policies := hooks.LoadPolicies(workDir)
+	result, code := hooks.PathValidationWithPolicy(input, workDir, policies.PathValidation)
 	out, _ := json.Marshal(result)
 	fmt.Println(string(out))
 	os.Exit(code)
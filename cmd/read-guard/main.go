@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"hooks/internal/hooks"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	var exemptPaths []string
+	path := os.Getenv("HOOK_ALLOWLISTS_PATH")
+	if path == "" {
+		cwd, _ := os.Getwd()
+		path = filepath.Join(cwd, ".cursor", "hooks-allowlists.json")
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		var v struct {
+			RG *struct {
+				ExemptPaths []string `json:"exemptPaths"`
+			} `json:"readGuard"`
+		}
+		if json.Unmarshal(data, &v) == nil && v.RG != nil {
+			exemptPaths = v.RG.ExemptPaths
+		}
+	}
+	hooks.RunOrDisabled("read-guard", func(input hooks.HookInput) (hooks.HookResult, int) {
+		return hooks.ReadGuardWithExemptions(input, exemptPaths)
+	})
+}
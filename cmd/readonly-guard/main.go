@@ -1,7 +1,39 @@
 package main
 
-import "hooks/internal/hooks"
+import (
+	"encoding/json"
+	"fmt"
+	"hooks/internal/hooks"
+	"io"
+	"os"
+)
 
 func main() {
-	hooks.RunOrDisabled("readonly-guard", hooks.ReadonlyGuard)
+	if hooks.IsHookDisabled("readonly-guard") {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	var input hooks.HookInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		fmt.Println(`{"decision": "allow"}`)
+		os.Exit(0)
+	}
+
+	cwd := input.Cwd()
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+
+	policies := hooks.LoadPolicies(cwd)
+	result, code := hooks.ReadonlyGuardWithPolicy(input, cwd, policies.ReadonlyGuard)
+	out, _ := json.Marshal(result)
+	fmt.Println(string(out))
+	os.Exit(code)
 }
@@ -6,7 +6,6 @@ import (
 	"hooks/internal/hooks"
 	"io"
 	"os"
-	"path/filepath"
 )
 
 func main() {
@@ -18,17 +17,18 @@ func main() {
 	var input hooks.HookInput
 	json.Unmarshal(data, &input)
 
-	home, _ := os.UserHomeDir()
-	auditDir := os.Getenv("HOOK_AUDIT_DIR")
-	if auditDir == "" {
-		auditDir = filepath.Join(home, ".config", "hooks", "audit")
-	}
-	diaryDir := os.Getenv("HOOK_DIARY_DIR")
-	if diaryDir == "" {
-		diaryDir = filepath.Join(home, ".config", "hooks", "diary")
+	workDir := input.Cwd()
+	if workDir == "" {
+		workDir, _ = os.Getwd()
 	}
 
-	result, code := hooks.SessionDiary(input, auditDir, diaryDir)
+	auditDir := hooks.HookDir("HOOK_AUDIT_DIR", "audit")
+	stateDir := hooks.StateDir()
+	costDir := hooks.HookDir("HOOK_COST_DIR", "cost")
+	diaryDir := hooks.HookDir("HOOK_DIARY_DIR", "diary")
+
+	policies := hooks.LoadPolicies(workDir)
+	result, code := hooks.SessionDiaryWithState(input, auditDir, stateDir, costDir, diaryDir, policies.SessionDiary)
 	out, _ := json.Marshal(result)
 	fmt.Println(string(out))
 	os.Exit(code)
@@ -25,8 +25,13 @@ func main() {
 		os.Exit(0)
 	}
 
-	cwd, _ := os.Getwd()
-	result, code := hooks.TestBuddy(input, cwd)
+	workDir := input.Cwd()
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
+
+	policies := hooks.LoadPolicies(workDir)
+	result, code := hooks.TestBuddyWithPolicy(input, workDir, policies.TestBuddy)
 	out, _ := json.Marshal(result)
 	fmt.Println(string(out))
 	os.Exit(code)
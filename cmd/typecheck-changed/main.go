@@ -26,13 +26,20 @@ func main() {
 		os.Exit(0)
 	}
 
-	cwd, err := os.Getwd()
-	if err != nil {
-		fmt.Println(`{"decision": "allow"}`)
-		os.Exit(0)
+	cwd := input.Cwd()
+	if cwd == "" {
+		cwd, _ = os.Getwd()
 	}
 
-	result, code := hooks.TypecheckChanged(input, cwd)
+	cacheDir := hooks.HookDir("HOOK_TYPECHECK_DIR", "typecheck")
+
+	policies := hooks.LoadPolicies(cwd)
+	result, code := hooks.TypecheckChangedWithPolicy(input, cwd, cacheDir, policies.TypecheckChanged)
+
+	// Remember open failures for hooks that look at the whole session
+	stateDir := hooks.StateDir()
+	hooks.RecordCheckResult(stateDir, "typecheck-changed", input, cwd, result, code)
+
 	out, _ := json.Marshal(result)
 	fmt.Println(string(out))
 	os.Exit(code)
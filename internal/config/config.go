@@ -48,6 +48,9 @@ type Allowlists struct {
 	ImportGuard *struct {
 		AllowedPatterns map[string][]string `yaml:"allowedPatterns"`
 	} `yaml:"importGuard,omitempty"`
+	ReadGuard *struct {
+		ExemptPaths []string `yaml:"exemptPaths"`
+	} `yaml:"readGuard,omitempty"`
 }
 
 type Output struct {
@@ -64,6 +67,7 @@ type Config struct {
 	Env                map[string]string `yaml:"env,omitempty"`
 	Output             *Output           `yaml:"output,omitempty"`
 	Allowlists         *Allowlists       `yaml:"allowlists,omitempty"`
+	Policies           yaml.Node         `yaml:"policies,omitempty"` // decoded into hooks.Policies by gen-config
 	SessionStart       []HookEntry       `yaml:"sessionStart"`
 	BeforeSubmitPrompt []HookEntry       `yaml:"beforeSubmitPrompt"`
 	PreToolUse         []HookEntry       `yaml:"preToolUse"`
@@ -30,6 +30,15 @@ func Audit(input HookInput, auditDir string) (HookResult, int) {
 	return Allow(), 0
 }
 
+// AuditWithState runs Audit and also records the call in the session's
+// activity log under stateDir, which session-scoped hooks read.
+func AuditWithState(input HookInput, auditDir, stateDir string) (HookResult, int) {
+	if stateDir != "" {
+		RecordSessionEvent(stateDir, input)
+	}
+	return Audit(input, auditDir)
+}
+
 func buildSummary(input HookInput) string {
 	switch input.ToolName {
 	case "Shell":
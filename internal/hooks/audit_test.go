@@ -117,3 +117,21 @@ func TestAudit_AppendsToExisting(t *testing.T) {
 		t.Errorf("expected 2 log lines, got %d: %s", len(lines), string(data))
 	}
 }
+
+func TestAuditWithState_RecordsSessionActivity(t *testing.T) {
+	stateDir := t.TempDir()
+	AuditWithState(sessionInput("Write", "s1", map[string]string{"path": "a.go", "cwd": "/repo"}), t.TempDir(), stateDir)
+	AuditWithState(sessionInput("Shell", "s1", map[string]string{"command": "go test ./..."}), t.TempDir(), stateDir)
+	AuditWithState(sessionInput("Shell", "s2", map[string]string{"command": "ls"}), t.TempDir(), stateDir)
+
+	events := loadSessionEvents(stateDir, "s1")
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events for s1, got %+v", events)
+	}
+	if events[0].Tool != "Write" || events[0].Path != filepath.Join("/repo", "a.go") {
+		t.Errorf("expected write with path resolved against cwd, got %+v", events[0])
+	}
+	if events[1].Command != "go test ./..." {
+		t.Errorf("expected shell command, got %+v", events[1])
+	}
+}
@@ -0,0 +1,139 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const autofixTimeout = 30 * time.Second
+
+// fixerRegistry lists the built-in formatters and fixers autofix mode runs.
+// Every fixer that handles a file and is configured runs, in this order, so
+// a linter's fixes are formatted afterwards.
+var fixerRegistry = []linterSpec{
+	{Name: "biome", Exts: []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".json", ".jsonc"}, Configs: []string{"biome.json", "biome.jsonc"}, Command: "biome check --write {file}"},
+	{Name: "eslint --fix", Exts: []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".vue"}, Configs: linterConfigFiles("eslint"), Command: "eslint --fix {file}"},
+	{Name: "prettier", Exts: linterExts("prettier"), Configs: linterConfigFiles("prettier"), Command: "prettier --write {file}"},
+	{Name: "ruff --fix", Exts: []string{".py", ".pyi"}, Configs: linterConfigFiles("ruff"), Command: "ruff check --fix --exit-zero --quiet {file}"},
+	{Name: "ruff format", Exts: []string{".py", ".pyi"}, Configs: linterConfigFiles("ruff"), Command: "ruff format --quiet {file}"},
+	{Name: "gofmt", Exts: []string{".go"}, Command: "gofmt -w {file}"},
+	{Name: "rustfmt", Exts: []string{".rs"}, Command: "rustfmt {file}"},
+	{Name: "shfmt", Exts: []string{".sh", ".bash"}, Command: "shfmt -w {file}"},
+	{Name: "terraform fmt", Exts: []string{".tf", ".tfvars"}, Command: "terraform fmt {file}"},
+}
+
+// fixersFor returns the fixers for file with the directory each runs in:
+// user linters with a fix command first, then the built-ins.
+func fixersFor(root, file string, policy *LintPolicy) ([]linterSpec, []string) {
+	var specs []linterSpec
+	disabled := map[string]bool{}
+	if policy != nil {
+		for _, d := range policy.Disable {
+			disabled[d] = true
+		}
+		for _, l := range policy.Linters {
+			if l.Fix != "" {
+				specs = append(specs, linterSpec{Name: l.Name, Exts: l.Extensions, Files: l.Files, Configs: l.ConfigFiles, Command: l.Fix})
+			}
+		}
+	}
+	specs = append(specs, fixerRegistry...)
+
+	var fixers []linterSpec
+	var dirs []string
+	for _, s := range specs {
+		if disabled[s.Name] || !s.matches(file) {
+			continue
+		}
+		dir := s.configDir(root, file)
+		if dir == "" {
+			continue
+		}
+		if args := s.args(file, dir); len(args) == 0 || !commandExists(args[0]) {
+			continue
+		}
+		fixers = append(fixers, s)
+		dirs = append(dirs, dir)
+	}
+	return fixers, dirs
+}
+
+// applyFixes runs every fixer for file and returns the names of those that
+// changed it. Fixer failures are ignored; the check that follows reports
+// whatever is left.
+func applyFixes(root, file string, policy *LintPolicy) []string {
+	fixers, dirs := fixersFor(root, file, policy)
+	var changed []string
+	for i, f := range fixers {
+		before, err := os.ReadFile(file)
+		if err != nil {
+			return changed
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), autofixTimeout)
+		args := f.args(file, dirs[i])
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Dir = dirs[i]
+		_ = cmd.Run()
+		cancel()
+		if after, err := os.ReadFile(file); err == nil && !bytes.Equal(before, after) {
+			changed = append(changed, f.Name)
+		}
+	}
+	return changed
+}
+
+// applyFixesOnce runs applyFixes unless the file already holds what a fix run
+// left in it. lint-on-write and lint-changed both fix the written file when
+// autofix is on; whichever runs second (they may run at the same time) waits
+// for the first and finds nothing to do. The state is one file per path
+// under StateDir()/autofix with the hash of its contents after fixing.
+func applyFixesOnce(root, file string, policy *LintPolicy) []string {
+	dir := filepath.Join(StateDir(), "autofix")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return applyFixes(root, file, policy)
+	}
+	marker := filepath.Join(dir, shortHash(file))
+	if unlock, ok := lockStateFile(marker); ok {
+		defer unlock()
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	if prev, err := os.ReadFile(marker); err == nil && string(prev) == shortHash(string(data)) {
+		return nil
+	}
+	fixed := applyFixes(root, file, policy)
+	if after, err := os.ReadFile(file); err == nil {
+		writeFileAtomic(marker, []byte(shortHash(string(after))))
+	}
+	return fixed
+}
+
+// autofixNote tells the agent the file it just wrote changed on disk.
+func autofixNote(file string, fixers []string) string {
+	return "Auto-fixed " + file + " with " + strings.Join(fixers, ", ") + "; the file changed on disk, re-read it before editing it again."
+}
+
+func linterConfigFiles(name string) []string {
+	for _, s := range linterRegistry {
+		if s.Name == name {
+			return s.Configs
+		}
+	}
+	return nil
+}
+
+func linterExts(name string) []string {
+	for _, s := range linterRegistry {
+		if s.Name == name {
+			return s.Exts
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeTool puts an executable shell script named name on PATH.
+func fakeTool(t *testing.T, name, script string) {
+	t.Helper()
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestLintOnWrite_Autofix(t *testing.T) {
+	if !commandExists("gofmt") {
+		t.Skip("gofmt not installed")
+	}
+	t.Setenv("HOOK_STATE_DIR", t.TempDir())
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	os.WriteFile(file, []byte("package main\nfunc  main( ) {\n}\n"), 0644)
+
+	policy := &LintPolicy{Autofix: true}
+	result, code := LintOnWriteWithPolicy(writeInput(file, ""), dir, policy)
+	if code != 0 || !strings.Contains(result.Message, "Auto-fixed main.go with gofmt") || !strings.Contains(result.Message, "re-read") {
+		t.Fatalf("expected autofix message, got code=%d %+v", code, result)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "package main\n\nfunc main() {\n}\n" {
+		t.Errorf("file not formatted: %q", data)
+	}
+
+	// Already formatted: nothing to say
+	result, _ = LintOnWriteWithPolicy(writeInput(file, ""), dir, policy)
+	if result.Message != "" || result.LintCommand != "" {
+		t.Errorf("expected a quiet allow for a clean file, got %+v", result)
+	}
+
+	// Autofix off keeps suggesting the command
+	result, _ = LintOnWriteWithPolicy(writeInput(file, ""), dir, nil)
+	if !strings.Contains(result.LintCommand, "gofmt") {
+		t.Errorf("expected lint_command without autofix, got %+v", result)
+	}
+}
+
+func TestLintChanged_Autofix(t *testing.T) {
+	t.Setenv("HOOK_STATE_DIR", t.TempDir())
+	dir := t.TempDir()
+	file := filepath.Join(dir, "deploy.sh")
+	os.WriteFile(file, []byte("echo  $1\n"), 0644)
+	fakeTool(t, "shfmt", `printf 'echo $1\n' > "$2"`)
+	fakeTool(t, "shellcheck", `echo '{"comments":[]}'`)
+
+	policy := &LintPolicy{Autofix: true}
+	result, code := LintChangedWithPolicy(writeInput(file, ""), dir, policy)
+	if code != 0 || !strings.Contains(result.Message, "Auto-fixed deploy.sh with shfmt") {
+		t.Fatalf("expected allow with autofix note, got code=%d %+v", code, result)
+	}
+
+	// Issues left after fixing are reported along with the note
+	os.WriteFile(file, []byte("echo  $1\n"), 0644)
+	fakeTool(t, "shellcheck", `echo '{"comments":[{"file":"`+file+`","line":1,"column":6,"level":"info","code":2086,"message":"Double quote to prevent globbing","fix":null}]}'; exit 1`)
+	result, code = LintChangedWithPolicy(writeInput(file, ""), dir, policy)
+	if code != 2 || !strings.Contains(result.Reason, "SC2086") || !strings.Contains(result.Reason, "could not be fixed automatically") {
+		t.Errorf("expected remaining issues after autofix, got code=%d %q", code, result.Reason)
+	}
+
+	// Disabled fixers do not run
+	os.WriteFile(file, []byte("echo  $1\n"), 0644)
+	policy.Disable = []string{"shfmt"}
+	LintChangedWithPolicy(writeInput(file, ""), dir, policy)
+	if data, _ := os.ReadFile(file); string(data) != "echo  $1\n" {
+		t.Errorf("disabled fixer changed the file: %q", data)
+	}
+}
+
+func TestAutofix_RunsOncePerWrite(t *testing.T) {
+	t.Setenv("HOOK_STATE_DIR", t.TempDir())
+	dir := t.TempDir()
+	file := filepath.Join(dir, "deploy.sh")
+	runs := filepath.Join(t.TempDir(), "runs")
+	fakeTool(t, "shfmt", `echo run >> '`+runs+`'; printf 'echo $1\n' > "$2"`)
+	fakeTool(t, "shellcheck", `echo '{"comments":[]}'`)
+	policy := &LintPolicy{Autofix: true}
+	count := func() int {
+		data, _ := os.ReadFile(runs)
+		return strings.Count(string(data), "run")
+	}
+
+	// Both hooks handle the same write, in either order
+	os.WriteFile(file, []byte("echo  $1\n"), 0644)
+	if result, _ := LintOnWriteWithPolicy(writeInput(file, ""), dir, policy); !strings.Contains(result.Message, "Auto-fixed") {
+		t.Fatalf("expected the first hook to fix the file, got %+v", result)
+	}
+	if result, code := LintChangedWithPolicy(writeInput(file, ""), dir, policy); code != 0 || result.Message != "" {
+		t.Errorf("expected the second hook to only lint, got code=%d %+v", code, result)
+	}
+	if n := count(); n != 1 {
+		t.Errorf("fixer ran %d times, want 1", n)
+	}
+
+	// A new write of unfixed contents is fixed again
+	os.WriteFile(file, []byte("echo  $1\n"), 0644)
+	LintChangedWithPolicy(writeInput(file, ""), dir, policy)
+	LintOnWriteWithPolicy(writeInput(file, ""), dir, policy)
+	if n := count(); n != 2 {
+		t.Errorf("fixer ran %d times after the second write, want 2", n)
+	}
+}
+
+func TestFixersFor_UserFix(t *testing.T) {
+	fakeTool(t, "buf", "exit 0")
+	dir := t.TempDir()
+	policy := &LintPolicy{Linters: []LinterConfig{{Name: "buf", Extensions: []string{".proto"}, Command: "buf lint {file}", Fix: "buf format -w {file}"}}}
+	fixers, _ := fixersFor(dir, filepath.Join(dir, "a.proto"), policy)
+	if len(fixers) != 1 || fixers[0].Command != "buf format -w {file}" {
+		t.Errorf("expected the user fix command, got %+v", fixers)
+	}
+}
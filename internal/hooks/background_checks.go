@@ -0,0 +1,319 @@
+package hooks
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackgroundPolicy configures background-checks and background-results
+// (policies.backgroundChecks in config.yaml).
+type BackgroundPolicy struct {
+	// DebounceMs is the quiet period after the last write before checks run
+	// (default 1500), so a burst of writes is checked once.
+	DebounceMs int `yaml:"debounceMs,omitempty" json:"debounceMs,omitempty"`
+	// Checks are the checks to run: lint-changed, typecheck-changed (default both).
+	Checks []string `yaml:"checks,omitempty" json:"checks,omitempty"`
+	// Block makes background-results deny the next tool call with the
+	// failures instead of only reporting them.
+	Block bool `yaml:"block,omitempty" json:"block,omitempty"`
+}
+
+const (
+	checkQueueFile     = "checks-queue.jsonl"
+	checkResultsFile   = "checks-results.json"
+	checkDeliveredFile = "checks-delivered.json"
+	checkLockFile      = "checks-worker.lock"
+	checkLockStale     = 10 * time.Minute
+	defaultDebounce    = 1500 * time.Millisecond
+)
+
+// checkQueueEntry is one written file waiting to be checked.
+type checkQueueEntry struct {
+	Path    string    `json:"path"`
+	WorkDir string    `json:"work_dir"`
+	Session string    `json:"session_id,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// CheckResult is the latest outcome of one check for one file.
+type CheckResult struct {
+	Path        string       `json:"path"`
+	Check       string       `json:"check"`
+	Failed      bool         `json:"failed"`
+	Reason      string       `json:"reason,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+	Time        time.Time    `json:"time"`
+}
+
+// EnqueueCheck is a postToolUse hook that queues a written file for checking
+// in the background and returns at once. startWorker is called when no worker
+// is running for the session; it should start RunCheckWorker detached.
+func EnqueueCheck(input HookInput, workDir, stateDir string, startWorker func(sessionDir string) error) (HookResult, int) {
+	if input.ToolName != "Write" {
+		return Allow(), 0
+	}
+	path := input.Path()
+	if path == "" {
+		return Allow(), 0
+	}
+	if !filepath.IsAbs(path) && workDir != "" {
+		path = filepath.Join(workDir, path)
+	}
+
+	dir := SessionStateDir(stateDir, input.SessionID())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Allow(), 0
+	}
+	entry, _ := json.Marshal(checkQueueEntry{Path: path, WorkDir: workDir, Session: input.SessionID(), Time: time.Now()})
+	f, err := os.OpenFile(filepath.Join(dir, checkQueueFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return Allow(), 0
+	}
+	f.Write(append(entry, '\n'))
+	f.Close()
+
+	if !checkWorkerRunning(dir) && startWorker != nil {
+		startWorker(dir)
+	}
+	return Allow(), 0
+}
+
+// RunCheckWorker processes the queue in sessionDir until it stays empty: it
+// waits for writes to settle, checks each queued file once with lint-changed
+// and typecheck-changed, and stores the latest result per file and check.
+// Only one worker runs per session; a second one returns immediately.
+func RunCheckWorker(sessionDir, typecheckDir string) error {
+	if !acquireCheckLock(sessionDir) {
+		return nil
+	}
+	queue := filepath.Join(sessionDir, checkQueueFile)
+	for {
+		info, err := os.Stat(queue)
+		if err != nil {
+			os.Remove(filepath.Join(sessionDir, checkLockFile))
+			// A write may have been queued while we were releasing the lock
+			if _, err := os.Stat(queue); err == nil && acquireCheckLock(sessionDir) {
+				continue
+			}
+			return nil
+		}
+
+		entries := readCheckQueue(queue)
+		policy := backgroundPolicyFor(entries)
+		debounce := defaultDebounce
+		if policy.DebounceMs > 0 {
+			debounce = time.Duration(policy.DebounceMs) * time.Millisecond
+		}
+		if wait := debounce - time.Since(info.ModTime()); wait > 0 {
+			time.Sleep(wait)
+			continue
+		}
+
+		processing := queue + fmt.Sprintf(".%d", os.Getpid())
+		if err := os.Rename(queue, processing); err != nil {
+			continue
+		}
+		entries = readCheckQueue(processing)
+		os.Remove(processing)
+
+		results := runBackgroundChecks(coalesceCheckQueue(entries), typecheckDir, policy)
+		saveCheckResults(sessionDir, results)
+		touchCheckLock(sessionDir)
+	}
+}
+
+// DeliverCheckResults is a preToolUse/beforeSubmitPrompt hook that reports
+// background check failures the agent has not seen yet. With policy.Block the
+// report denies the tool call; otherwise it is attached as a message.
+func DeliverCheckResults(input HookInput, stateDir string, policy *BackgroundPolicy) (HookResult, int) {
+	dir := SessionStateDir(stateDir, input.SessionID())
+	results := loadCheckResults(dir)
+	if len(results) == 0 {
+		return Allow(), 0
+	}
+	delivered := map[string]time.Time{}
+	if data, err := os.ReadFile(filepath.Join(dir, checkDeliveredFile)); err == nil {
+		json.Unmarshal(data, &delivered)
+	}
+
+	var pending []CheckResult
+	for key, r := range results {
+		if r.Failed && r.Time.After(delivered[key]) {
+			pending = append(pending, r)
+			delivered[key] = r.Time
+		}
+	}
+	if len(pending) == 0 {
+		return Allow(), 0
+	}
+	data, _ := json.Marshal(delivered)
+	os.WriteFile(filepath.Join(dir, checkDeliveredFile), data, 0644)
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Time.Before(pending[j].Time) })
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Background checks] %d problem(s) found after your recent edits:", len(pending))
+	var diags []Diagnostic
+	for _, r := range pending {
+		b.WriteString("\n\n" + r.Check + ": " + r.Reason)
+		diags = append(diags, r.Diagnostics...)
+	}
+
+	if policy != nil && policy.Block && input.ToolName != "" {
+		result := Deny(b.String() + "\n\nFix these before continuing.")
+		result.Diagnostics = diags
+		return result, 2
+	}
+	result := AllowMsg(b.String())
+	result.Diagnostics = diags
+	return result, 0
+}
+
+// RecordCheckResult stores the outcome of a synchronous lint-changed or
+// typecheck-changed run in the session state under stateDir, so later hooks
+// know which failures are still open. The agent has already seen the result,
+// so background-results does not report it again.
+func RecordCheckResult(stateDir, check string, input HookInput, workDir string, result HookResult, code int) {
+	path := input.Path()
+	if input.ToolName != "Write" || path == "" {
+		return
+	}
+	if !filepath.IsAbs(path) && workDir != "" {
+		path = filepath.Join(workDir, path)
+	}
+	dir := SessionStateDir(stateDir, input.SessionID())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	r := CheckResult{Path: path, Check: check, Failed: code != 0, Reason: result.Reason, Diagnostics: result.Diagnostics, Time: time.Now()}
+	saveCheckResults(dir, []CheckResult{r})
+
+	delivered := map[string]time.Time{}
+	if data, err := os.ReadFile(filepath.Join(dir, checkDeliveredFile)); err == nil {
+		json.Unmarshal(data, &delivered)
+	}
+	delivered[checkResultKey(r)] = r.Time
+	data, _ := json.Marshal(delivered)
+	os.WriteFile(filepath.Join(dir, checkDeliveredFile), data, 0644)
+}
+
+// runBackgroundChecks runs the enabled checks on each entry.
+func runBackgroundChecks(entries []checkQueueEntry, typecheckDir string, policy BackgroundPolicy) []CheckResult {
+	enabled := func(name string) bool { return len(policy.Checks) == 0 || hasString(policy.Checks, name) }
+	var results []CheckResult
+	for _, e := range entries {
+		ti, _ := json.Marshal(map[string]string{"path": e.Path, "session_id": e.Session})
+		input := HookInput{ToolName: "Write", ToolInput: ti}
+		policies := LoadPolicies(e.WorkDir)
+
+		if enabled("lint-changed") {
+			r, code := LintChangedWithPolicy(input, e.WorkDir, policies.LintChanged)
+			results = append(results, CheckResult{Path: e.Path, Check: "lint-changed", Failed: code != 0, Reason: r.Reason, Diagnostics: r.Diagnostics, Time: time.Now()})
+		}
+		if enabled("typecheck-changed") {
+			r, code := TypecheckChangedWithPolicy(input, e.WorkDir, typecheckDir, policies.TypecheckChanged)
+			results = append(results, CheckResult{Path: e.Path, Check: "typecheck-changed", Failed: code != 0, Reason: r.Reason, Diagnostics: r.Diagnostics, Time: time.Now()})
+		}
+	}
+	return results
+}
+
+// coalesceCheckQueue keeps one entry per file, the latest, in first-written order.
+func coalesceCheckQueue(entries []checkQueueEntry) []checkQueueEntry {
+	latest := map[string]int{}
+	var order []string
+	for i, e := range entries {
+		if _, ok := latest[e.Path]; !ok {
+			order = append(order, e.Path)
+		}
+		latest[e.Path] = i
+	}
+	out := make([]checkQueueEntry, 0, len(order))
+	for _, p := range order {
+		out = append(out, entries[latest[p]])
+	}
+	return out
+}
+
+func readCheckQueue(path string) []checkQueueEntry {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var entries []checkQueueEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e checkQueueEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil && e.Path != "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// backgroundPolicyFor loads policies.backgroundChecks for the queued files' project.
+func backgroundPolicyFor(entries []checkQueueEntry) BackgroundPolicy {
+	if len(entries) == 0 {
+		return BackgroundPolicy{}
+	}
+	if p := LoadPolicies(entries[0].WorkDir).BackgroundChecks; p != nil {
+		return *p
+	}
+	return BackgroundPolicy{}
+}
+
+func checkResultKey(r CheckResult) string {
+	return r.Check + "\x00" + r.Path
+}
+
+func loadCheckResults(dir string) map[string]CheckResult {
+	results := map[string]CheckResult{}
+	if data, err := os.ReadFile(filepath.Join(dir, checkResultsFile)); err == nil {
+		json.Unmarshal(data, &results)
+	}
+	return results
+}
+
+// saveCheckResults merges results into the session's latest-result map.
+func saveCheckResults(dir string, results []CheckResult) {
+	all := loadCheckResults(dir)
+	for _, r := range results {
+		all[checkResultKey(r)] = r
+	}
+	data, _ := json.MarshalIndent(all, "", "  ")
+	tmp := filepath.Join(dir, checkResultsFile+".tmp")
+	if os.WriteFile(tmp, data, 0644) == nil {
+		os.Rename(tmp, filepath.Join(dir, checkResultsFile))
+	}
+}
+
+// acquireCheckLock takes the session's worker lock, breaking a stale one.
+func acquireCheckLock(dir string) bool {
+	lock := filepath.Join(dir, checkLockFile)
+	if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > checkLockStale {
+		os.Remove(lock)
+	}
+	f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return false
+	}
+	fmt.Fprintf(f, "%d\n", os.Getpid())
+	f.Close()
+	return true
+}
+
+func touchCheckLock(dir string) {
+	now := time.Now()
+	os.Chtimes(filepath.Join(dir, checkLockFile), now, now)
+}
+
+func checkWorkerRunning(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, checkLockFile))
+	return err == nil && time.Since(info.ModTime()) <= checkLockStale
+}
@@ -0,0 +1,118 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// backgroundProject sets up a project whose .proto files are linted by a fake
+// protolint that records each run in runs.log.
+func backgroundProject(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "runs.log")
+	fakeTool(t, "protolint", "echo run >> '"+log+"'\necho 'api.proto:3: missing comment'\nexit 1")
+	os.MkdirAll(filepath.Join(dir, ".cursor"), 0755)
+	os.WriteFile(filepath.Join(dir, ".cursor", "hooks-policies.json"), []byte(`{
+		"lintChanged": {"linters": [{"name": "protolint", "extensions": [".proto"], "command": "protolint lint {file}"}]},
+		"backgroundChecks": {"debounceMs": 10, "checks": ["lint-changed"]}
+	}`), 0644)
+	os.WriteFile(filepath.Join(dir, "api.proto"), []byte("syntax = \"proto3\";"), 0644)
+	return dir
+}
+
+func TestBackgroundChecks_CoalescesWrites(t *testing.T) {
+	dir := backgroundProject(t)
+	stateDir := t.TempDir()
+	file := filepath.Join(dir, "api.proto")
+
+	started := 0
+	start := func(string) error { started++; return nil }
+	for i := 0; i < 3; i++ {
+		if result, code := EnqueueCheck(writeInput(file, ""), dir, stateDir, start); code != 0 || result.Decision != "allow" {
+			t.Fatalf("enqueue should allow at once, got code=%d", code)
+		}
+	}
+	if started != 3 {
+		t.Errorf("expected a worker start per write while none holds the lock, got %d", started)
+	}
+
+	sessionDir := SessionStateDir(stateDir, "")
+	if err := RunCheckWorker(sessionDir, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	runs, _ := os.ReadFile(filepath.Join(dir, "runs.log"))
+	if n := strings.Count(string(runs), "run"); n != 1 {
+		t.Errorf("expected 3 writes of one file to be checked once, got %d runs", n)
+	}
+	if _, err := os.Stat(filepath.Join(sessionDir, checkLockFile)); err == nil {
+		t.Error("worker should release its lock when the queue is empty")
+	}
+}
+
+func TestBackgroundChecks_DeliversFailuresOnce(t *testing.T) {
+	dir := backgroundProject(t)
+	stateDir := t.TempDir()
+	file := filepath.Join(dir, "api.proto")
+	EnqueueCheck(writeInput(file, ""), dir, stateDir, nil)
+	RunCheckWorker(SessionStateDir(stateDir, ""), t.TempDir())
+
+	result, code := DeliverCheckResults(shellInput("ls"), stateDir, nil)
+	if code != 0 || !strings.Contains(result.Message, "[Background checks]") || !strings.Contains(result.Message, "missing comment") {
+		t.Errorf("expected failure message, got code=%d %q", code, result.Message)
+	}
+	if len(result.Diagnostics) != 1 {
+		t.Errorf("expected diagnostics to be passed on, got %+v", result.Diagnostics)
+	}
+	if result, _ := DeliverCheckResults(shellInput("ls"), stateDir, nil); result.Message != "" {
+		t.Errorf("failures should be delivered once, got %q", result.Message)
+	}
+}
+
+func TestBackgroundChecks_Block(t *testing.T) {
+	dir := backgroundProject(t)
+	stateDir := t.TempDir()
+	EnqueueCheck(writeInput(filepath.Join(dir, "api.proto"), ""), dir, stateDir, nil)
+	RunCheckWorker(SessionStateDir(stateDir, ""), t.TempDir())
+
+	result, code := DeliverCheckResults(shellInput("ls"), stateDir, &BackgroundPolicy{Block: true})
+	if code != 2 || result.Decision != "deny" {
+		t.Errorf("expected deny with block, got code=%d %s", code, result.Decision)
+	}
+}
+
+func TestBackgroundChecks_PassClearsFailure(t *testing.T) {
+	dir := backgroundProject(t)
+	stateDir := t.TempDir()
+	file := filepath.Join(dir, "api.proto")
+	sessionDir := SessionStateDir(stateDir, "")
+	EnqueueCheck(writeInput(file, ""), dir, stateDir, nil)
+	RunCheckWorker(sessionDir, t.TempDir())
+
+	fakeTool(t, "protolint", "exit 0")
+	EnqueueCheck(writeInput(file, ""), dir, stateDir, nil)
+	RunCheckWorker(sessionDir, t.TempDir())
+
+	if result, code := DeliverCheckResults(shellInput("ls"), stateDir, nil); code != 0 || result.Message != "" {
+		t.Errorf("fixed file should not be reported, got %q", result.Message)
+	}
+}
+
+func TestEnqueueCheck_IgnoresNonWrites(t *testing.T) {
+	stateDir := t.TempDir()
+	EnqueueCheck(shellInput("ls"), t.TempDir(), stateDir, nil)
+	if _, err := os.Stat(SessionStateDir(stateDir, "")); err == nil {
+		t.Error("non-write tool calls should not be queued")
+	}
+}
+
+func TestSessionStateDir(t *testing.T) {
+	if got := SessionStateDir("/s", ""); got != filepath.Join("/s", "sessions", "default") {
+		t.Errorf("empty session: %s", got)
+	}
+	if got := SessionStateDir("/s", "../x"); got != filepath.Join("/s", "sessions", ".._x") {
+		t.Errorf("session id should not escape the base: %s", got)
+	}
+}
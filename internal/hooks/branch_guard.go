@@ -1,21 +1,35 @@
 package hooks
 
 import (
-	"regexp"
+	"path"
+	"path/filepath"
 	"strings"
 )
 
-var (
-	gitCheckoutRe    = regexp.MustCompile(`\bgit\s+(?:checkout|switch)\s+(\S+)`)
-	gitCheckoutNewRe = regexp.MustCompile(`\bgit\s+(?:checkout|switch)\s+-[bB]\s`)
-	gitCommitRe      = regexp.MustCompile(`\bgit\s+commit\b`)
-	gitMergeRe       = regexp.MustCompile(`\bgit\s+merge\b`)
-	gitRebaseRe      = regexp.MustCompile(`\bgit\s+rebase\b`)
-	gitReadOnlyRe    = regexp.MustCompile(`\bgit\s+(?:status|log|diff|show|branch|remote|fetch|stash\s+list|tag\s+-l)\b`)
-)
-
 // BranchGuard is a preToolUse hook that prevents operations on protected branches.
+// currentBranch is used for every git command, wherever it runs; use
+// BranchGuardInDir to resolve the branch per directory.
 func BranchGuard(input HookInput, protected []string, currentBranch string) (HookResult, int) {
+	return branchGuard(input, protected, "", func(string) string { return currentBranch })
+}
+
+// BranchGuardInDir runs BranchGuard for commands started in workDir. The
+// current branch is looked up in the directory each git command actually runs
+// in (after cd and git -C), so linked worktrees are handled. Protected entries
+// may be globs such as release/*.
+func BranchGuardInDir(input HookInput, protected []string, workDir string) (HookResult, int) {
+	cache := map[string]string{}
+	return branchGuard(input, protected, workDir, func(dir string) string {
+		if b, ok := cache[dir]; ok {
+			return b
+		}
+		b := gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD")
+		cache[dir] = b
+		return b
+	})
+}
+
+func branchGuard(input HookInput, protected []string, workDir string, branchFor func(dir string) string) (HookResult, int) {
 	if input.ToolName != "Shell" {
 		return Allow(), 0
 	}
@@ -25,44 +39,286 @@ func BranchGuard(input HookInput, protected []string, currentBranch string) (Hoo
 		return Allow(), 0
 	}
 
-	// Always allow read-only git commands
-	if gitReadOnlyRe.MatchString(cmd) && !gitCommitRe.MatchString(cmd) && !gitMergeRe.MatchString(cmd) {
-		return Allow(), 0
+	bg := branchGuardCheck{protected: protected, branchFor: branchFor}
+	dir := workDir
+	for _, c := range shellCommands(cmd) {
+		args := commandArgs(c.Args)
+		switch commandName(args) {
+		case "cd":
+			if len(args) > 1 {
+				dir = joinDir(dir, args[1])
+			}
+		case "git":
+			gitDir, sub, subArgs := gitInvocation(dir, args[1:])
+			if reason := bg.check(gitDir, sub, subArgs); reason != "" {
+				return Deny("Blocked: " + reason), 2
+			}
+		}
 	}
 
-	// Block checkout/switch to protected branch (but allow -b new branch)
-	if gitCheckoutRe.MatchString(cmd) && !gitCheckoutNewRe.MatchString(cmd) {
-		matches := gitCheckoutRe.FindStringSubmatch(cmd)
-		if len(matches) > 1 {
-			target := matches[1]
-			for _, p := range protected {
-				if target == p {
-					return Deny("Blocked: cannot checkout protected branch '" + p + "'. Use a feature branch."), 2
-				}
+	return Allow(), 0
+}
+
+type branchGuardCheck struct {
+	protected []string
+	branchFor func(dir string) string
+}
+
+// check returns why git sub subArgs, run in dir, is not allowed, or "".
+func (bg branchGuardCheck) check(dir, sub string, subArgs []string) string {
+	flags, ops := splitGitArgs(subArgs, gitValueFlags[sub])
+	current := bg.branchFor(dir)
+
+	switch sub {
+	case "checkout", "switch":
+		if flags["-B"] || flags["-C"] || flags["--force-create"] {
+			// Force-creating resets an existing branch
+			if len(ops) > 0 && bg.isProtected(ops[0]) {
+				return "cannot reset protected branch '" + ops[0] + "'. Use a feature branch."
+			}
+			return ""
+		}
+		if flags["-b"] || flags["-c"] || flags["--create"] || flags["--orphan"] || flags["--detach"] || flags["-d"] {
+			return ""
+		}
+		// git checkout main -- file restores files without switching branches
+		if len(ops) == 1 && !hasArg(subArgs, "--") && bg.isProtected(ops[0]) {
+			return "cannot checkout protected branch '" + ops[0] + "'. Use a feature branch."
+		}
+
+	case "commit", "merge", "rebase", "cherry-pick", "revert", "am":
+		if flags["--abort"] || flags["--quit"] || flags["--skip"] {
+			return ""
+		}
+		if !bg.isProtected(current) {
+			return ""
+		}
+		if sub == "commit" {
+			return "cannot commit on protected branch '" + current + "'. Create a feature branch."
+		}
+		return "cannot " + sub + " on protected branch '" + current + "'."
+
+	case "push":
+		return bg.checkPush(flags, ops, current)
+
+	case "branch":
+		var targets []string
+		switch {
+		case flags["-d"] || flags["-D"] || flags["--delete"]:
+			targets = ops
+		case flags["-m"] || flags["-M"] || flags["--move"] || flags["-c"] || flags["-C"] || flags["--copy"]:
+			targets = ops
+			if len(ops) == 1 {
+				targets = []string{current, ops[0]}
+			}
+			if flags["-c"] || flags["-C"] || flags["--copy"] {
+				targets = ops[len(ops)-1:] // copying leaves the source untouched
+			}
+		case flags["-f"] || flags["--force"]:
+			if len(ops) > 0 {
+				targets = ops[:1]
+			}
+		}
+		for _, t := range targets {
+			if bg.isProtected(t) {
+				return "cannot rewrite protected branch '" + t + "' with git branch."
+			}
+		}
+
+	case "update-ref":
+		if len(ops) > 0 {
+			if b := strings.TrimPrefix(ops[0], "refs/heads/"); b != ops[0] && bg.isProtected(b) {
+				return "cannot update protected branch ref '" + ops[0] + "'."
 			}
 		}
+
+	case "worktree":
+		if len(ops) == 0 || ops[0] != "add" {
+			return ""
+		}
+		addArgs := subArgs[indexOf(subArgs, "add")+1:]
+		wtFlags, wtOps := splitGitArgs(addArgs, map[string]bool{"-b": true, "-B": true, "--reason": true})
+		if b := gitFlagValue(addArgs, "-B"); b != "" && bg.isProtected(b) {
+			return "cannot reset protected branch '" + b + "' in a new worktree."
+		}
+		if wtFlags["-b"] || wtFlags["-B"] || wtFlags["--detach"] || wtFlags["-d"] {
+			return ""
+		}
+		// git worktree add <path> <branch>
+		if len(wtOps) > 1 && bg.isProtected(wtOps[1]) {
+			return "cannot checkout protected branch '" + wtOps[1] + "' in a new worktree. Use a feature branch."
+		}
+	}
+	return ""
+}
+
+// checkPush checks every destination ref of a push, including the implicit
+// current branch when no refspec is given.
+func (bg branchGuardCheck) checkPush(flags map[string]bool, ops []string, current string) string {
+	if flags["--all"] || flags["--branches"] || flags["--mirror"] {
+		if len(bg.protected) == 0 {
+			return ""
+		}
+		return "cannot push all branches while " + strings.Join(bg.protected, ", ") + " are protected. Push a feature branch."
+	}
+	if len(ops) < 2 {
+		// git push [remote] pushes the current branch
+		if bg.isProtected(current) {
+			return "cannot push to protected branch '" + current + "'. Push a feature branch and open a pull request."
+		}
+		return ""
+	}
+	for _, spec := range ops[1:] {
+		dst := pushDestination(spec, current)
+		if flags["-d"] || flags["--delete"] {
+			dst = strings.TrimPrefix(spec, "refs/heads/")
+		}
+		if bg.isProtected(dst) {
+			return "cannot push to protected branch '" + dst + "' (refspec " + spec + "). Push a feature branch and open a pull request."
+		}
+	}
+	return ""
+}
+
+// pushDestination returns the branch a push refspec updates: the part after
+// ":" (an empty source deletes it), or the source itself, with HEAD meaning
+// the current branch.
+func pushDestination(spec, current string) string {
+	spec = strings.TrimPrefix(spec, "+")
+	src, dst, ok := strings.Cut(spec, ":")
+	if !ok {
+		dst = src
+	}
+	if dst == "HEAD" || dst == "@" {
+		dst = current
+	}
+	return strings.TrimPrefix(dst, "refs/heads/")
+}
+
+// isProtected reports whether branch matches a protected name or glob.
+func (bg branchGuardCheck) isProtected(branch string) bool {
+	if branch == "" {
+		return false
+	}
+	for _, p := range bg.protected {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if ok, _ := path.Match(p, branch); ok || p == branch {
+			return true
+		}
 	}
+	return false
+}
+
+// gitValueFlags are per-subcommand options that take a separate value, so the
+// value is not mistaken for a branch or refspec.
+var gitValueFlags = map[string]map[string]bool{
+	"commit":      {"-m": true, "--message": true, "-F": true, "--file": true, "-C": true, "-c": true, "--author": true, "--date": true, "--fixup": true, "--squash": true, "-t": true, "--template": true},
+	"merge":       {"-m": true, "-F": true, "--file": true, "-s": true, "--strategy": true, "-X": true, "--strategy-option": true},
+	"push":        {"-o": true, "--push-option": true, "--repo": true, "--receive-pack": true, "--exec": true},
+	"checkout":    {"--conflict": true},
+	"switch":      {"--conflict": true},
+	"rebase":      {"-s": true, "--strategy": true, "-X": true, "--strategy-option": true, "--onto": true, "-x": true, "--exec": true},
+	"update-ref":  {"-m": true},
+	"cherry-pick": {"-m": true, "--mainline": true, "-X": true, "--strategy-option": true},
+	"revert":      {"-m": true, "--mainline": true},
+	"restore":     {"-s": true, "--source": true},
+	"clean":       {"-e": true, "--exclude": true},
+}
 
-	// Block commit/merge/rebase on protected branch
-	isOnProtected := false
-	for _, p := range protected {
-		if currentBranch == p {
-			isOnProtected = true
+// splitGitArgs separates a subcommand's flags from its operands. Arguments
+// after "--" are paths and are dropped.
+func splitGitArgs(args []string, valueFlags map[string]bool) (map[string]bool, []string) {
+	flags := map[string]bool{}
+	var ops []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
 			break
 		}
+		if !strings.HasPrefix(a, "-") || a == "-" {
+			ops = append(ops, a)
+			continue
+		}
+		if name, _, ok := strings.Cut(a, "="); ok {
+			flags[name] = true
+			continue
+		}
+		if valueFlags[a] {
+			i++
+		}
+		if strings.HasPrefix(a, "--") || len(a) == 2 {
+			flags[a] = true
+			continue
+		}
+		// Combined short flags (-fD); the last may take the next value (-am msg)
+		for _, c := range a[1:] {
+			flags["-"+string(c)] = true
+		}
+		if valueFlags["-"+a[len(a)-1:]] {
+			i++
+		}
 	}
+	return flags, ops
+}
 
-	if isOnProtected {
-		if gitCommitRe.MatchString(cmd) {
-			return Deny("Blocked: cannot commit on protected branch '" + currentBranch + "'. Create a feature branch."), 2
+// gitFlagValue returns the value given to flag in args, or "".
+func gitFlagValue(args []string, flag string) string {
+	for i, a := range args {
+		if a == flag && i+1 < len(args) {
+			return args[i+1]
 		}
-		if gitMergeRe.MatchString(cmd) {
-			return Deny("Blocked: cannot merge on protected branch '" + currentBranch + "'."), 2
+		if v, ok := strings.CutPrefix(a, flag+"="); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+func hasArg(args []string, want string) bool {
+	return indexOf(args, want) >= 0
+}
+
+func indexOf(args []string, want string) int {
+	for i, a := range args {
+		if a == want {
+			return i
 		}
-		if gitRebaseRe.MatchString(cmd) {
-			return Deny("Blocked: cannot rebase on protected branch '" + currentBranch + "'."), 2
+	}
+	return -1
+}
+
+// gitInvocation applies git's -C options to dir and returns the directory the
+// command runs in with its subcommand and arguments.
+func gitInvocation(dir string, args []string) (string, string, []string) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "-C" && i+1 < len(args):
+			dir = joinDir(dir, args[i+1])
+			i++
+		case a == "--work-tree" && i+1 < len(args):
+			dir = joinDir(dir, args[i+1])
+			i++
+		case strings.HasPrefix(a, "--work-tree="):
+			dir = joinDir(dir, strings.TrimPrefix(a, "--work-tree="))
+		case a == "-c" || a == "--git-dir" || a == "--namespace" || a == "--exec-path":
+			i++
+		case strings.HasPrefix(a, "-"):
+		default:
+			return dir, a, args[i+1:]
 		}
 	}
+	return dir, "", nil
+}
 
-	return Allow(), 0
+// joinDir resolves a cd/-C argument against dir.
+func joinDir(dir, arg string) string {
+	arg = expandUserHome(arg)
+	if filepath.IsAbs(arg) || dir == "" {
+		return arg
+	}
+	return filepath.Join(dir, arg)
 }
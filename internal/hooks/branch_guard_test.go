@@ -1,6 +1,9 @@
 package hooks
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 )
 
@@ -103,3 +106,107 @@ func TestBranchGuard_PassthroughNonShell(t *testing.T) {
 		t.Error("should passthrough non-Shell tools")
 	}
 }
+
+func TestBranchGuard_RefsAndOptions(t *testing.T) {
+	protected := []string{"main", "master", "release/*"}
+
+	tests := []struct {
+		name     string
+		cmd      string
+		current  string
+		wantDeny bool
+	}{
+		{"push HEAD to main", "git push origin HEAD:main", "feature-x", true},
+		{"push feature to master", "git push origin feature:master", "feature-x", true},
+		{"force push refspec", "git push origin +feature:refs/heads/main", "feature-x", true},
+		{"push delete main", "git push origin :main", "feature-x", true},
+		{"push --delete main", "git push --delete origin main", "feature-x", true},
+		{"push local main", "git push origin main", "feature-x", true},
+		{"push release glob", "git push origin HEAD:release/1.2", "feature-x", true},
+		{"bare push on main", "git push", "main", true},
+		{"push -u HEAD on main", "git push -u origin HEAD", "main", true},
+		{"push --all", "git push --all origin", "feature-x", true},
+		{"push feature", "git push -u origin HEAD", "feature-x", false},
+		{"push feature refspec", "git push origin feature:feature", "feature-x", false},
+		{"push with option value", "git push -o ci.skip origin feature-x", "feature-x", false},
+		{"branch -f main", "git branch -f main HEAD~1", "feature-x", true},
+		{"branch -D release", "git branch -D release/2.0", "feature-x", true},
+		{"branch -m current main", "git branch -m main old-main", "feature-x", true},
+		{"branch rename feature", "git branch -m feature-y", "feature-x", false},
+		{"branch create", "git branch feature-y", "main", false},
+		{"update-ref main", "git update-ref refs/heads/main abc123", "feature-x", true},
+		{"update-ref other", "git update-ref refs/heads/feature abc123", "feature-x", false},
+		{"checkout -B main", "git checkout -B main", "feature-x", true},
+		{"checkout files from main", "git checkout main -- go.mod", "feature-x", false},
+		{"switch --detach main", "git switch --detach main", "feature-x", false},
+		{"checkout release glob", "git checkout release/1.0", "feature-x", true},
+		{"global options before checkout", "git -c core.pager=cat checkout main", "feature-x", true},
+		{"commit on release glob", "git commit -m wip", "release/3.1", true},
+		{"commit message names main", "git commit -m 'merge main'", "feature-x", false},
+		{"merge abort on main", "git merge --abort", "main", false},
+		{"cherry-pick on main", "git cherry-pick abc123", "main", true},
+		{"worktree add main", "git worktree add ../wt main", "feature-x", true},
+		{"worktree add new branch", "git worktree add -b feature-y ../wt main", "feature-x", false},
+		{"env prefix", "GIT_EDITOR=true git commit --amend", "main", true},
+		{"sudo wrapper", "sudo git push origin HEAD:main", "feature-x", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := BranchGuard(shellInput(tt.cmd), protected, tt.current)
+			if tt.wantDeny && (code != 2 || result.Decision != "deny") {
+				t.Errorf("expected deny for %q on %q, got code=%d", tt.cmd, tt.current, code)
+			}
+			if !tt.wantDeny && code != 0 {
+				t.Errorf("expected allow for %q on %q, got code=%d reason=%s", tt.cmd, tt.current, code, result.Reason)
+			}
+		})
+	}
+}
+
+func TestBranchGuardInDir_ResolvesCommandDir(t *testing.T) {
+	repo := initGitRepo(t)
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	git(repo, "branch", "-M", "main")
+	wt := filepath.Join(t.TempDir(), "wt")
+	git(repo, "worktree", "add", "-b", "feature-x", wt)
+	other := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	protected := []string{"main"}
+	tests := []struct {
+		name     string
+		workDir  string
+		cmd      string
+		wantDeny bool
+	}{
+		{"commit in worktree on feature", wt, "git commit -m x", false},
+		{"commit in main checkout", repo, "git commit -m x", true},
+		{"commit with -C into main checkout", wt, "git -C " + repo + " commit -m x", true},
+		{"cd into main checkout", other, "cd " + repo + "/sub && git commit -m x", true},
+		{"-C into worktree", repo, "git -C " + wt + " commit -m x", false},
+		{"bare push from worktree", wt, "git push", false},
+		{"bare push from main checkout", repo, "git push origin", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := BranchGuardInDir(shellInput(tt.cmd), protected, tt.workDir)
+			if tt.wantDeny && code != 2 {
+				t.Errorf("expected deny for %q in %s, got code=%d", tt.cmd, tt.workDir, code)
+			}
+			if !tt.wantDeny && code != 0 {
+				t.Errorf("expected allow for %q in %s, got reason=%s", tt.cmd, tt.workDir, result.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package hooks
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkpointRefPrefix is the private ref namespace checkpoints live under. The
+// refs keep snapshots safe from gc without showing up in git branch or git tag.
+const checkpointRefPrefix = "refs/hooks/checkpoints/"
+
+// defaultCheckpointKeep is how many checkpoints a repository keeps by default.
+const defaultCheckpointKeep = 100
+
+// defaultRiskPatterns are shell commands worth a checkpoint that the write
+// target extraction does not see.
+var defaultRiskPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bfind\b.*\s-delete\b`),
+	regexp.MustCompile(`\bfind\b.*-exec\s+(?:rm|mv|sed)\b`),
+	regexp.MustCompile(`\bgit\s+(?:apply|am)\b`),
+	regexp.MustCompile(`\b(?:go\s+fix|gofmt\s+-w|goimports\s+-w|prettier\s+--write|eslint\s+--fix|ruff\s+(?:check\s+--fix|format))\b`),
+}
+
+// checkpointGitSubcommands move or rewrite the working tree.
+var checkpointGitSubcommands = map[string]bool{
+	"reset": true, "rebase": true, "merge": true, "pull": true, "stash": true, "cherry-pick": true,
+	"revert": true, "checkout": true, "switch": true, "restore": true, "clean": true, "rm": true, "mv": true,
+}
+
+// CheckpointPolicy configures the checkpoint hook (policies.checkpoint in config.yaml).
+type CheckpointPolicy struct {
+	// RiskPatterns are extra regexes; a Shell command matching one is checkpointed.
+	RiskPatterns []string `yaml:"riskPatterns,omitempty" json:"riskPatterns,omitempty"`
+	// Keep is how many checkpoints to keep per repository (default 100).
+	Keep int `yaml:"keep,omitempty" json:"keep,omitempty"`
+}
+
+// CheckpointInfo describes one saved checkpoint.
+type CheckpointInfo struct {
+	ID      string // <session>/<unix millis>, the ref name below checkpointRefPrefix
+	Commit  string
+	Time    time.Time
+	Session string
+	Tool    string
+	Summary string
+}
+
+// Checkpoint is a preToolUse hook that snapshots the working tree before an
+// agent modifies tracked files or runs a risky Shell command, so the change
+// can be undone with "hooks checkpoints restore". It never blocks.
+func Checkpoint(input HookInput, workDir string) (HookResult, int) {
+	return CheckpointWithPolicy(input, workDir, nil)
+}
+
+// CheckpointWithPolicy runs Checkpoint with extra risk patterns and retention from policy.
+func CheckpointWithPolicy(input HookInput, workDir string, policy *CheckpointPolicy) (HookResult, int) {
+	if policy == nil {
+		policy = &CheckpointPolicy{}
+	}
+	root := gitOutput(workDir, "rev-parse", "--show-toplevel")
+	if root == "" {
+		return Allow(), 0
+	}
+
+	var why string
+	switch input.ToolName {
+	case "Write", "Edit", "MultiEdit":
+		path := input.WritePath()
+		if path != "" && isTrackedFile(workDir, path) {
+			why = "edit of " + path
+		}
+	case "Shell":
+		why = shellRisk(input.Command(), workDir, policy.RiskPatterns)
+	}
+	if why == "" {
+		return Allow(), 0
+	}
+
+	session := input.SessionID()
+	if session == "" {
+		session = "default"
+	}
+	cp, created, err := CreateCheckpoint(root, session, input.ToolName, why)
+	if err != nil {
+		return Allow(), 0
+	}
+	if created {
+		keep := policy.Keep
+		if keep <= 0 {
+			keep = defaultCheckpointKeep
+		}
+		pruneCheckpoints(root, keep)
+	}
+	return AllowMsg("checkpoint " + cp.ID + " before " + why + " (undo: hooks checkpoints restore " + cp.ID + ")"), 0
+}
+
+// shellRisk returns why cmd deserves a checkpoint, or "".
+func shellRisk(cmd, workDir string, extra []string) string {
+	if cmd == "" {
+		return ""
+	}
+	for _, p := range extra {
+		if re, err := regexp.Compile(p); err == nil && re.MatchString(cmd) {
+			return "command matching " + p
+		}
+	}
+	for _, re := range defaultRiskPatterns {
+		if re.MatchString(cmd) {
+			return "'" + re.FindString(cmd) + "'"
+		}
+	}
+	for _, gc := range gitCalls(cmd, workDir) {
+		if checkpointGitSubcommands[gc.Sub] {
+			return "git " + gc.Sub
+		}
+	}
+	for _, t := range shellWriteTargets(cmd, workDir) {
+		switch {
+		case t.Kind == shellDelete:
+			return "'" + t.Command + "'"
+		case t.Kind == shellWrite && isTrackedFile(workDir, t.Path):
+			return "'" + t.Command + "'"
+		}
+	}
+	return ""
+}
+
+// isTrackedFile reports whether path (or anything under it) is tracked by git.
+func isTrackedFile(workDir, path string) bool {
+	return gitOutput(workDir, "ls-files", "--", expandUserHome(path)) != ""
+}
+
+// CreateCheckpoint snapshots every tracked and untracked (not ignored) file
+// under root into a commit stored at refs/hooks/checkpoints/<session>/<millis>.
+// The real index and working tree are not touched. If the tree is unchanged
+// since the session's last checkpoint, that checkpoint is returned instead.
+func CreateCheckpoint(root, session, tool, summary string) (CheckpointInfo, bool, error) {
+	session = sanitizeRefPart(session)
+
+	tree, err := snapshotTree(root)
+	if err != nil {
+		return CheckpointInfo{}, false, err
+	}
+	if latest := latestCheckpoint(root, session); latest != nil {
+		if gitOutput(root, "rev-parse", latest.Commit+"^{tree}") == tree {
+			return *latest, false, nil
+		}
+	}
+
+	msg := fmt.Sprintf("checkpoint: %s %s\n\nSession: %s\nTool: %s\n", tool, summary, session, tool)
+	args := []string{"commit-tree", tree, "-m", msg}
+	if head := gitOutput(root, "rev-parse", "--verify", "-q", "HEAD"); head != "" {
+		args = append(args, "-p", head)
+	}
+	commit, err := runGit(root, checkpointEnv(""), args...)
+	if err != nil {
+		return CheckpointInfo{}, false, err
+	}
+
+	now := time.Now()
+	ms := now.UnixMilli()
+	id := session + "/" + strconv.FormatInt(ms, 10)
+	for gitOutput(root, "rev-parse", "--verify", "-q", checkpointRefPrefix+id) != "" {
+		ms++ // two checkpoints in the same millisecond
+		id = session + "/" + strconv.FormatInt(ms, 10)
+	}
+	if _, err := runGit(root, nil, "update-ref", checkpointRefPrefix+id, commit); err != nil {
+		return CheckpointInfo{}, false, err
+	}
+	return CheckpointInfo{ID: id, Commit: commit, Time: now, Session: session, Tool: tool, Summary: summary}, true, nil
+}
+
+// snapshotTree writes the working tree to a tree object using a throwaway
+// copy of the index, so staged state is left exactly as it was.
+func snapshotTree(root string) (string, error) {
+	tmp, err := os.CreateTemp("", "hooks-checkpoint-index-*")
+	if err != nil {
+		return "", err
+	}
+	tmpIndex := tmp.Name()
+	tmp.Close()
+	// git refuses an empty index file; without a copy below it creates a fresh one.
+	os.Remove(tmpIndex)
+	defer os.Remove(tmpIndex)
+
+	// Starting from the real index keeps git's stat cache, so add -A stays fast.
+	if indexPath := gitOutput(root, "rev-parse", "--git-path", "index"); indexPath != "" {
+		if data, err := os.ReadFile(absFrom(root, indexPath)); err == nil {
+			if err := os.WriteFile(tmpIndex, data, 0o600); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	env := checkpointEnv(tmpIndex)
+	if _, err := runGit(root, env, "add", "-A", "--", "."); err != nil {
+		return "", err
+	}
+	return runGit(root, env, "write-tree")
+}
+
+// checkpointEnv sets a fixed identity (checkpoints must work without user.name)
+// and optionally a private index file.
+func checkpointEnv(index string) []string {
+	env := append(os.Environ(),
+		"GIT_AUTHOR_NAME=hooks checkpoint", "GIT_AUTHOR_EMAIL=hooks@localhost",
+		"GIT_COMMITTER_NAME=hooks checkpoint", "GIT_COMMITTER_EMAIL=hooks@localhost",
+	)
+	if index != "" {
+		env = append(env, "GIT_INDEX_FILE="+index)
+	}
+	return env
+}
+
+// runGit runs git in dir with env (nil for the current environment) and
+// returns trimmed stdout, or an error including stderr.
+func runGit(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = env
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ListCheckpoints returns the checkpoints of the repository containing workDir, newest first.
+func ListCheckpoints(workDir string) ([]CheckpointInfo, error) {
+	out, err := runGit(workDir, nil, "for-each-ref", "--format=%(refname)%09%(objectname)%09%(creatordate:unix)%09%(subject)", checkpointRefPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var cps []CheckpointInfo
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) < 4 {
+			continue
+		}
+		id := strings.TrimPrefix(fields[0], checkpointRefPrefix)
+		session, millis, _ := strings.Cut(id, "/")
+		cp := CheckpointInfo{ID: id, Commit: fields[1], Session: session}
+		if ms, err := strconv.ParseInt(millis, 10, 64); err == nil {
+			cp.Time = time.UnixMilli(ms)
+		} else if sec, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+			cp.Time = time.Unix(sec, 0)
+		}
+		subject := strings.TrimPrefix(fields[3], "checkpoint: ")
+		cp.Tool, cp.Summary, _ = strings.Cut(subject, " ")
+		cps = append(cps, cp)
+	}
+	sort.Slice(cps, func(i, j int) bool { return cps[i].Time.After(cps[j].Time) })
+	return cps, nil
+}
+
+// CountCheckpoints returns how many checkpoints the repository at workDir has.
+func CountCheckpoints(workDir string) int {
+	cps, err := ListCheckpoints(workDir)
+	if err != nil {
+		return 0
+	}
+	return len(cps)
+}
+
+// RestoreCheckpoint overwrites the working tree (all files, or just paths) with
+// checkpoint id. The current state is checkpointed first so the restore can be
+// undone too; that checkpoint's id is returned. The index is not changed.
+func RestoreCheckpoint(workDir, id string, paths []string) (string, error) {
+	root := gitOutput(workDir, "rev-parse", "--show-toplevel")
+	if root == "" {
+		return "", fmt.Errorf("%s is not inside a git repository", workDir)
+	}
+	commit := gitOutput(root, "rev-parse", "--verify", "-q", checkpointRefPrefix+id+"^{commit}")
+	if commit == "" {
+		return "", fmt.Errorf("no checkpoint %q (see hooks checkpoints list)", id)
+	}
+
+	backup, _, err := CreateCheckpoint(root, "restore", "restore", "before restoring "+id)
+	if err != nil {
+		return "", fmt.Errorf("saving current state: %w", err)
+	}
+
+	args := []string{"restore", "--source=" + commit, "--worktree", "--"}
+	if len(paths) == 0 {
+		args = append(args, ".")
+	}
+	for _, p := range paths {
+		// Paths are given relative to where the user runs the command
+		if rel, err := filepath.Rel(root, absFrom(workDir, p)); err == nil {
+			p = filepath.ToSlash(rel)
+		}
+		args = append(args, p)
+	}
+	if _, err := runGit(root, nil, args...); err != nil {
+		return backup.ID, err
+	}
+	return backup.ID, nil
+}
+
+// latestCheckpoint returns the session's newest checkpoint, or nil.
+func latestCheckpoint(root, session string) *CheckpointInfo {
+	cps, err := ListCheckpoints(root)
+	if err != nil {
+		return nil
+	}
+	for i := range cps {
+		if cps[i].Session == session {
+			return &cps[i]
+		}
+	}
+	return nil
+}
+
+// pruneCheckpoints deletes all but the newest keep checkpoints.
+func pruneCheckpoints(root string, keep int) {
+	cps, err := ListCheckpoints(root)
+	if err != nil || len(cps) <= keep {
+		return
+	}
+	for _, cp := range cps[keep:] {
+		runGit(root, nil, "update-ref", "-d", checkpointRefPrefix+cp.ID)
+	}
+}
+
+var refUnsafeRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// sanitizeRefPart makes s usable as one component of a ref name.
+func sanitizeRefPart(s string) string {
+	s = strings.Trim(refUnsafeRe.ReplaceAllString(s, "-"), ".-")
+	if len(s) > 64 {
+		s = s[:64]
+	}
+	s = strings.TrimSuffix(s, ".lock")
+	if s == "" {
+		s = "default"
+	}
+	return s
+}
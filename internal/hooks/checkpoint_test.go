@@ -0,0 +1,166 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initCheckpointRepo returns a repo with a committed tracked.txt.
+func initCheckpointRepo(t *testing.T) string {
+	t.Helper()
+	dir := initGitRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, "tracked.txt"), []byte("v1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"add", "tracked.txt"}, {"commit", "-m", "add tracked"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = checkpointEnv("")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func sessionInput(tool, session string, fields map[string]string) HookInput {
+	m := map[string]string{"session_id": session}
+	for k, v := range fields {
+		m[k] = v
+	}
+	ti, _ := json.Marshal(m)
+	return HookInput{ToolName: tool, ToolInput: ti}
+}
+
+func TestCheckpoint_Triggers(t *testing.T) {
+	dir := initCheckpointRepo(t)
+
+	tests := []struct {
+		name  string
+		input HookInput
+		want  bool
+	}{
+		{"edit tracked file", sessionInput("Edit", "s1", map[string]string{"file_path": "tracked.txt"}), true},
+		{"write new file", sessionInput("Write", "s1", map[string]string{"path": "new.txt"}), false},
+		{"git reset", shellInput("git reset --hard"), true},
+		{"git clean", shellInput("git clean -fd"), true},
+		{"rm", shellInput("rm -rf build"), true},
+		{"redirect to tracked", shellInput("echo x > tracked.txt"), true},
+		{"redirect to new file", shellInput("echo x > notes.txt"), false},
+		{"find -delete", shellInput("find . -name '*.tmp' -delete"), true},
+		{"read only", shellInput("git status && ls"), false},
+		{"read tool", HookInput{ToolName: "Read"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := Checkpoint(tt.input, dir)
+			if code != 0 || result.Decision != "allow" {
+				t.Fatalf("checkpoint must never block, got decision=%s code=%d", result.Decision, code)
+			}
+			if got := strings.HasPrefix(result.Message, "checkpoint "); got != tt.want {
+				t.Errorf("checkpoint taken = %v, want %v (message %q)", got, tt.want, result.Message)
+			}
+		})
+	}
+}
+
+func TestCheckpoint_RiskPatternsPolicy(t *testing.T) {
+	dir := initCheckpointRepo(t)
+	policy := &CheckpointPolicy{RiskPatterns: []string{`\bmake\s+migrate\b`}}
+	result, _ := CheckpointWithPolicy(shellInput("make migrate"), dir, policy)
+	if !strings.Contains(result.Message, "checkpoint ") {
+		t.Errorf("expected checkpoint for policy risk pattern, got %q", result.Message)
+	}
+}
+
+func TestCheckpoint_NotGitRepo(t *testing.T) {
+	result, code := Checkpoint(shellInput("rm -rf build"), t.TempDir())
+	if code != 0 || result.Message != "" {
+		t.Errorf("expected silent allow outside git, got %q", result.Message)
+	}
+}
+
+func TestCheckpoint_RestoreRoundTrip(t *testing.T) {
+	dir := initCheckpointRepo(t)
+	tracked := filepath.Join(dir, "tracked.txt")
+	untracked := filepath.Join(dir, "scratch.txt")
+	os.WriteFile(tracked, []byte("v2 uncommitted\n"), 0o644)
+	os.WriteFile(untracked, []byte("notes\n"), 0o644)
+	stage := exec.Command("git", "add", "tracked.txt")
+	stage.Dir = dir
+	stage.Run()
+	stagedBefore := gitOutput(dir, "diff", "--cached", "--name-only")
+
+	result, _ := Checkpoint(sessionInput("Shell", "sess/one", map[string]string{"command": "git reset --hard && git clean -fd"}), dir)
+	if !strings.Contains(result.Message, "checkpoint sess-one/") {
+		t.Fatalf("expected checkpoint keyed by sanitized session, got %q", result.Message)
+	}
+	if got := gitOutput(dir, "diff", "--cached", "--name-only"); got != stagedBefore {
+		t.Errorf("checkpoint changed the index: %q -> %q", stagedBefore, got)
+	}
+
+	// A second identical snapshot in the same session is deduplicated
+	Checkpoint(sessionInput("Shell", "sess/one", map[string]string{"command": "git stash"}), dir)
+	cps, err := ListCheckpoints(dir)
+	if err != nil || len(cps) != 1 {
+		t.Fatalf("expected 1 checkpoint, got %d (%v)", len(cps), err)
+	}
+	if cps[0].Tool != "Shell" || !strings.Contains(cps[0].Summary, "git reset") {
+		t.Errorf("unexpected checkpoint info %+v", cps[0])
+	}
+
+	// Simulate the destructive command
+	for _, args := range [][]string{{"reset", "--hard"}, {"clean", "-fd"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	backup, err := RestoreCheckpoint(dir, cps[0].ID, nil)
+	if err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if !strings.HasPrefix(backup, "restore/") {
+		t.Errorf("expected a pre-restore checkpoint, got %q", backup)
+	}
+	if data, _ := os.ReadFile(tracked); string(data) != "v2 uncommitted\n" {
+		t.Errorf("tracked file not restored, got %q", data)
+	}
+	if data, _ := os.ReadFile(untracked); string(data) != "notes\n" {
+		t.Errorf("untracked file not restored, got %q", data)
+	}
+	if n := CountCheckpoints(dir); n != 2 {
+		t.Errorf("expected 2 checkpoints after restore, got %d", n)
+	}
+
+	if _, err := RestoreCheckpoint(dir, "nope/1", nil); err == nil {
+		t.Error("expected error for unknown checkpoint")
+	}
+}
+
+func TestCheckpoint_Prune(t *testing.T) {
+	dir := initCheckpointRepo(t)
+	policy := &CheckpointPolicy{Keep: 2}
+	for i := 0; i < 4; i++ {
+		os.WriteFile(filepath.Join(dir, "tracked.txt"), []byte(strings.Repeat("x", i+1)), 0o644)
+		CheckpointWithPolicy(shellInput("git stash"), dir, policy)
+	}
+	if n := CountCheckpoints(dir); n != 2 {
+		t.Errorf("expected 2 checkpoints kept, got %d", n)
+	}
+}
+
+func TestSessionGuard_ReportsCheckpoints(t *testing.T) {
+	dir := initCheckpointRepo(t)
+	Checkpoint(shellInput("git reset --hard"), dir)
+	result, _ := SessionGuard(HookInput{}, dir)
+	if !strings.Contains(result.Reason, "1 checkpoint(s) available") {
+		t.Errorf("expected checkpoint count, got %q", result.Reason)
+	}
+}
@@ -0,0 +1,496 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	commitGateTimeout   = 120 * time.Second
+	commitGateMaxOutput = 20 // output lines shown per failing check
+)
+
+// commitGateDependencyDirs are untracked directories linked into the staged
+// snapshot so checkers find installed packages.
+var commitGateDependencyDirs = []string{"node_modules", ".venv", "venv"}
+
+// CommitGatePolicy configures the commit-gate hook (policies.commitGate in config.yaml).
+type CommitGatePolicy struct {
+	// Checks limits the checks to these names (default: every detected check).
+	Checks []string `yaml:"checks,omitempty" json:"checks,omitempty"`
+	// Skip turns checks off by name.
+	Skip []string `yaml:"skip,omitempty" json:"skip,omitempty"`
+	// TimeoutSeconds bounds all checks of one commit together (default 120).
+	TimeoutSeconds int `yaml:"timeoutSeconds,omitempty" json:"timeoutSeconds,omitempty"`
+}
+
+// CommitGate is a preToolUse hook that runs the project's formatters, linters
+// and type checkers over the staged contents of the files a git commit will
+// record, and blocks the commit if any of them fail.
+func CommitGate(input HookInput, workDir string) (HookResult, int) {
+	return CommitGateWithPolicy(input, workDir, nil)
+}
+
+// CommitGateWithPolicy runs CommitGate with the given policy; nil means defaults.
+func CommitGateWithPolicy(input HookInput, workDir string, policy *CommitGatePolicy) (HookResult, int) {
+	if input.ToolName != "Shell" {
+		return Allow(), 0
+	}
+	cmd := input.Command()
+	if !strings.Contains(cmd, "commit") {
+		return Allow(), 0
+	}
+	if policy == nil {
+		policy = &CommitGatePolicy{}
+	}
+	timeout := commitGateTimeout
+	if policy.TimeoutSeconds > 0 {
+		timeout = time.Duration(policy.TimeoutSeconds) * time.Second
+	}
+
+	// Files added earlier in the same command (git add x && git commit) are
+	// not staged yet when this hook runs.
+	pending := map[string][]string{}
+	checked := map[string]bool{}
+	var notes []string
+	for _, gc := range gitCalls(cmd, workDir) {
+		root := gitOutput(gc.Dir, "rev-parse", "--show-toplevel")
+		if root == "" {
+			continue
+		}
+		switch gc.Sub {
+		case "add":
+			pending[root] = append(pending[root], gitAddFiles(gc)...)
+		case "commit":
+			if checked[root] || gc.Flags["--dry-run"] {
+				continue
+			}
+			checked[root] = true
+			files, worktree := commitFiles(gc, root, pending[root])
+			if len(files) == 0 {
+				continue
+			}
+			snapshot, err := stagedSnapshot(root, files, worktree)
+			if err != nil {
+				continue // fail open when the index cannot be copied
+			}
+			// Files the commit deletes have nothing to check
+			var present []string
+			for _, f := range files {
+				if exists(filepath.Join(snapshot, f)) {
+					present = append(present, f)
+				}
+			}
+			failures, skipped := runCommitChecks(root, snapshot, present, policy, timeout)
+			os.RemoveAll(snapshot)
+			notes = append(notes, skipped...)
+			if len(failures) > 0 {
+				result := Deny(commitGateReason(present, failures, skipped))
+				for _, f := range failures {
+					for _, d := range f.Diagnostics {
+						d.File = filepath.Join(root, d.File)
+						result.Diagnostics = append(result.Diagnostics, d)
+					}
+				}
+				return result, 2
+			}
+		}
+	}
+	if len(notes) > 0 {
+		return AllowMsg("commit-gate: " + strings.Join(notes, "; ")), 0
+	}
+	return Allow(), 0
+}
+
+// commitCheckFailure is what a failing check reported: diagnostics when its
+// output could be parsed, otherwise the trimmed output lines.
+type commitCheckFailure struct {
+	Name        string
+	Diagnostics []Diagnostic
+	Output      []string
+}
+
+// commitCheckRun is one command commit-gate runs over the snapshot.
+type commitCheckRun struct {
+	Name      string
+	Dir       string
+	Args      []string
+	Format    string
+	Succeeded func(code int) bool
+	// Files are the staged files (absolute, in the snapshot) the run covers;
+	// problems elsewhere are ignored.
+	Files []string
+	// Reformat runs are formatters: the check fails when they would change
+	// the file.
+	Reformat bool
+	// TypeCheck runs see the whole project, so only errors count.
+	TypeCheck bool
+}
+
+// stagedSnapshot copies what the commit will record into a temporary
+// directory laid out like root: the index (git checkout-index), then the
+// working-tree version of the files committed straight from the working tree
+// (pathspecs, -a, or added earlier in the same command). Untracked dependency
+// directories are linked in so checkers can resolve imports.
+func stagedSnapshot(root string, files, worktree []string) (string, error) {
+	dir, err := os.MkdirTemp("", "commit-gate-")
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	if out, err := exec.Command("git", "-C", root, "checkout-index", "-a", "-f", "--prefix="+dir+string(filepath.Separator)).CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("git checkout-index: %v: %s", err, out)
+	}
+	for _, f := range worktree {
+		src, dst := filepath.Join(root, f), filepath.Join(dir, f)
+		info, err := os.Stat(src)
+		if err != nil {
+			os.Remove(dst) // deleted in the working tree, so gone from the commit
+			continue
+		}
+		data, err := os.ReadFile(src)
+		if err != nil {
+			continue
+		}
+		os.MkdirAll(filepath.Dir(dst), 0o755)
+		os.WriteFile(dst, data, info.Mode().Perm())
+	}
+	for _, f := range files {
+		for _, d := range dirsUpTo(filepath.Dir(filepath.Join(root, f)), root) {
+			rel, err := filepath.Rel(root, d)
+			if err != nil {
+				continue
+			}
+			for _, name := range commitGateDependencyDirs {
+				if src, dst := filepath.Join(d, name), filepath.Join(dir, rel, name); exists(src) && !exists(dst) {
+					os.Symlink(src, dst)
+				}
+			}
+		}
+	}
+	return dir, nil
+}
+
+// commitCheckRuns picks the checks for each staged file from the shared
+// registries, as lint-changed and typecheck-changed would for a written file:
+// the formatters autofix mode runs (reported when they would change the
+// file), the file's linter and its type checkers. Identical commands, such as
+// go vet for several files of one package, run once.
+func commitCheckRuns(root, snapshot string, files []string, policy *CommitGatePolicy) []commitCheckRun {
+	policies := LoadPolicies(root)
+	lint := &LintPolicy{}
+	if policies.LintChanged != nil {
+		*lint = *policies.LintChanged
+	}
+	lint.Disable = append(append([]string{}, lint.Disable...), policy.Skip...)
+	typecheck := &TypecheckPolicy{}
+	if policies.TypecheckChanged != nil {
+		*typecheck = *policies.TypecheckChanged
+	}
+	typecheck.Disable = append(append([]string{}, typecheck.Disable...), policy.Skip...)
+
+	var formats, lints, typechecks []commitCheckRun
+	type runRef struct {
+		runs *[]commitCheckRun
+		i    int
+	}
+	seen := map[string]runRef{}
+	add := func(runs *[]commitCheckRun, run commitCheckRun) {
+		key := run.Dir + "\x00" + strings.Join(run.Args, "\x00")
+		if ref, ok := seen[key]; ok {
+			(*ref.runs)[ref.i].Files = append((*ref.runs)[ref.i].Files, run.Files...)
+			return
+		}
+		seen[key] = runRef{runs, len(*runs)}
+		*runs = append(*runs, run)
+	}
+	for _, f := range files {
+		file := filepath.Join(snapshot, f)
+		linter, lintDir, hasLinter := linterFor(snapshot, file, lint)
+		fixers, fixDirs := fixersFor(snapshot, file, lint)
+		for i, fx := range fixers {
+			// Fixers that belong to a linter report through it instead
+			if strings.HasSuffix(fx.Name, " --fix") || (hasLinter && fx.Name == linter.Name) || !commitCheckEnabled(fx.Name, policy) {
+				continue
+			}
+			formats = append(formats, commitCheckRun{Name: fx.Name, Dir: fixDirs[i], Args: fx.args(file, fixDirs[i]), Files: []string{file}, Reformat: true})
+		}
+		if hasLinter && commitCheckEnabled(linter.Name, policy) {
+			add(&lints, commitCheckRun{Name: linter.Name, Dir: lintDir, Args: linter.args(file, lintDir), Format: linter.Format, Succeeded: linter.succeeded, Files: []string{file}})
+		}
+		checkers, tcDirs := typeCheckersFor(snapshot, file, typecheck)
+		for i, tc := range checkers {
+			if args := tc.Args(file, tcDirs[i], ""); len(args) > 0 && commitCheckEnabled(tc.Name, policy) {
+				add(&typechecks, commitCheckRun{Name: tc.Name, Dir: tcDirs[i], Args: args, Format: tc.Format, Files: []string{file}, TypeCheck: true})
+			}
+		}
+	}
+	return append(append(formats, lints...), typechecks...)
+}
+
+// runCommitChecks runs the checks for files (repo-relative) in the snapshot
+// of root and returns the failures plus notes about checks that could not
+// finish.
+func runCommitChecks(root, snapshot string, files []string, policy *CommitGatePolicy, timeout time.Duration) ([]commitCheckFailure, []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var failures []commitCheckFailure
+	var skipped []string
+	for _, run := range commitCheckRuns(root, snapshot, files, policy) {
+		if ctx.Err() != nil {
+			skipped = append(skipped, run.Name+" skipped (time limit reached)")
+			continue
+		}
+		var failure *commitCheckFailure
+		var err error
+		if run.Reformat {
+			failure, err = runFormatCheck(ctx, snapshot, run)
+		} else {
+			failure, err = runCommitCheck(ctx, snapshot, run)
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			skipped = append(skipped, fmt.Sprintf("%s timed out after %s", run.Name, timeout))
+			continue
+		}
+		if err != nil {
+			continue // not installed or not runnable; fail open
+		}
+		if failure != nil {
+			failures = append(failures, *failure)
+		}
+	}
+	return failures, skipped
+}
+
+// runFormatCheck runs a formatter over the snapshot copy of a file and
+// reports the file when the formatter changed it. The copy is put back
+// afterwards so the linters see the staged contents.
+func runFormatCheck(ctx context.Context, snapshot string, run commitCheckRun) (*commitCheckFailure, error) {
+	file := run.Files[0]
+	before, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	c := exec.CommandContext(ctx, run.Args[0], run.Args[1:]...)
+	c.Dir = run.Dir
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, err
+		}
+	}
+	after, err := os.ReadFile(file)
+	if err != nil || bytes.Equal(before, after) {
+		return nil, err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	os.WriteFile(file, before, info.Mode().Perm())
+	rel, _ := filepath.Rel(snapshot, file)
+	return &commitCheckFailure{Name: run.Name, Output: []string{rel + ": not formatted"}}, nil
+}
+
+// runCommitCheck runs a linter or type checker and keeps what it reports
+// about the run's files. Output that cannot be parsed counts when the tool
+// failed; for type checkers only lines naming a staged file count, so a
+// checker that cannot start fails open.
+func runCommitCheck(ctx context.Context, snapshot string, run commitCheckRun) (*commitCheckFailure, error) {
+	c := exec.CommandContext(ctx, run.Args[0], run.Args[1:]...)
+	c.Dir = run.Dir
+	var out bytes.Buffer
+	c.Stdout = &out
+	c.Stderr = &out
+	code := 0
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, err
+		}
+		code = exitErr.ExitCode()
+	}
+	succeeded := code == 0
+	if run.Succeeded != nil {
+		succeeded = run.Succeeded(code)
+	}
+	if succeeded {
+		return nil, nil
+	}
+
+	all, parsed := parseDiagnostics(run.Format, out.String(), run.Dir, run.Name)
+	if parsed {
+		var diags []Diagnostic
+		for _, f := range run.Files {
+			for _, d := range diagnosticsFor(all, f) {
+				if !run.TypeCheck || d.Severity == "error" {
+					d.File, _ = filepath.Rel(snapshot, f)
+					diags = append(diags, d)
+				}
+			}
+		}
+		if len(diags) == 0 {
+			return nil, nil // problems elsewhere in the project are not this commit's doing
+		}
+		return &commitCheckFailure{Name: run.Name, Diagnostics: dedupeDiagnostics(diags)}, nil
+	}
+
+	lines := nonEmptyLines(out.String())
+	if run.TypeCheck {
+		var rel []string
+		for _, f := range run.Files {
+			if r, err := filepath.Rel(run.Dir, f); err == nil {
+				rel = append(rel, r)
+			}
+		}
+		if lines = linesMentioning(lines, rel); len(lines) == 0 {
+			return nil, nil
+		}
+	}
+	for i, line := range lines {
+		lines[i] = strings.ReplaceAll(line, snapshot+string(filepath.Separator), "")
+	}
+	return &commitCheckFailure{Name: run.Name, Output: lines}, nil
+}
+
+func commitCheckEnabled(name string, policy *CommitGatePolicy) bool {
+	for _, s := range policy.Skip {
+		if s == name {
+			return false
+		}
+	}
+	if len(policy.Checks) == 0 {
+		return true
+	}
+	for _, c := range policy.Checks {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// commitFiles returns the repo-relative files a git commit will record: the
+// given pathspecs, or the index (plus tracked changes with -a) and anything
+// added earlier in the same command. worktree lists the ones whose
+// working-tree contents go into the commit rather than what is staged now.
+func commitFiles(gc gitCall, root string, pending []string) (files, worktree []string) {
+	if paths := commitPathspecs(gc); len(paths) > 0 {
+		files = gitLines(gc.Dir, append([]string{"ls-files", "--full-name", "-m", "--"}, paths...)...)
+		files = append(files, gitLines(gc.Dir, append([]string{"diff", "--cached", "--name-only", "--diff-filter=ACMR", "--"}, paths...)...)...)
+		// git commit <paths> records the working-tree copies of those paths
+		worktree = files
+	} else {
+		files = gitLines(root, "diff", "--cached", "--name-only", "--diff-filter=ACMR")
+		if gc.Flags["-a"] || gc.Flags["--all"] {
+			worktree = gitLines(root, "diff", "--name-only", "--diff-filter=ACMR")
+		}
+		worktree = append(worktree, pending...)
+		files = append(files, worktree...)
+	}
+	return sortedUnique(files), sortedUnique(worktree)
+}
+
+func sortedUnique(list []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// commitPathspecs returns the paths given to git commit (git commit -m x a.go, or after --).
+func commitPathspecs(gc gitCall) []string {
+	paths := append([]string{}, gc.Ops...)
+	if i := indexOf(gc.Args, "--"); i >= 0 {
+		paths = append(paths, gc.Args[i+1:]...)
+	}
+	return paths
+}
+
+// gitAddFiles returns the repo-relative files a git add call will stage.
+func gitAddFiles(gc gitCall) []string {
+	args := []string{"ls-files", "--full-name", "-m", "--exclude-standard"}
+	if !gc.Flags["-u"] && !gc.Flags["--update"] {
+		args = append(args, "-o")
+	}
+	paths := commitPathspecs(gc)
+	if len(paths) == 0 && !gc.Flags["-A"] && !gc.Flags["--all"] && !gc.Flags["-u"] && !gc.Flags["--update"] {
+		return nil
+	}
+	return gitLines(gc.Dir, append(append(args, "--"), paths...)...)
+}
+
+func nonEmptyLines(s string) []string {
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) != "" {
+			out = append(out, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	return out
+}
+
+// linesMentioning keeps the lines that refer to one of files.
+func linesMentioning(lines, files []string) []string {
+	var out []string
+	for _, line := range lines {
+		for _, f := range files {
+			if strings.Contains(line, f) {
+				out = append(out, line)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func commitGateReason(files []string, failures []commitCheckFailure, notes []string) string {
+	var b strings.Builder
+	names := make([]string, len(failures))
+	for i, f := range failures {
+		names[i] = f.Name
+	}
+	fmt.Fprintf(&b, "Blocked: pre-commit checks failed (%s) on %s", strings.Join(names, ", "), describeFiles(files, "staged file", "staged files"))
+	for _, f := range failures {
+		b.WriteString("\n  " + f.Name + ":")
+		if len(f.Diagnostics) > 0 {
+			b.WriteString(formatDiagnostics(f.Diagnostics, ""))
+			continue
+		}
+		lines := f.Output
+		if len(lines) > commitGateMaxOutput {
+			lines = append(lines[:commitGateMaxOutput:commitGateMaxOutput], fmt.Sprintf("... %d more lines", len(f.Output)-commitGateMaxOutput))
+		}
+		if len(lines) == 0 {
+			b.WriteString(" failed without output")
+		}
+		for _, line := range lines {
+			b.WriteString("\n    " + line)
+		}
+	}
+	for _, n := range notes {
+		b.WriteString("\n  Note: " + n)
+	}
+	b.WriteString("\n  Hints:")
+	b.WriteString("\n    - Checks ran on the staged contents; fix the issues, stage the files again and retry the commit")
+	b.WriteString("\n    - Set policies.commitGate.skip to turn off a check for this project")
+	return b.String()
+}
@@ -0,0 +1,142 @@
+package hooks
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommitGate(t *testing.T) {
+	if !commandExists("gofmt") {
+		t.Skip("gofmt not installed")
+	}
+	dir := initGitRepo(t)
+	git := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "good.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "bad.go"), []byte("package main\nfunc  helper( ) {\n}\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x\n"), 0o644)
+
+	policy := &CommitGatePolicy{Checks: []string{"gofmt"}}
+	tests := []struct {
+		name     string
+		stage    []string
+		cmd      string
+		wantDeny bool
+	}{
+		{"nothing staged", nil, `git commit -m "feat: x"`, false},
+		{"clean file staged", []string{"good.go"}, `git commit -m "feat: x"`, false},
+		{"unformatted file staged", []string{"bad.go"}, `git commit -m "feat: x"`, true},
+		{"add in same command", nil, `git add bad.go && git commit -m "feat: x"`, true},
+		{"add all in same command", nil, `git add -A && git commit -m "feat: x"`, true},
+		{"unrelated add", nil, `git add notes.txt && git commit -m "docs: x"`, false},
+		{"cd and commit", []string{"bad.go"}, `cd ` + dir + ` && git commit -m "feat: x"`, true},
+		{"dry run", []string{"bad.go"}, `git commit --dry-run -m "feat: x"`, false},
+		{"not a commit", []string{"bad.go"}, `git status`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			git("reset", "-q")
+			for _, f := range tt.stage {
+				git("add", f)
+			}
+			result, code := CommitGateWithPolicy(shellInput(tt.cmd), dir, policy)
+			if tt.wantDeny {
+				if code != 2 {
+					t.Fatalf("expected deny for %q", tt.cmd)
+				}
+				if !strings.Contains(result.Reason, "gofmt") || !strings.Contains(result.Reason, "bad.go") {
+					t.Errorf("reason should name the check and file: %s", result.Reason)
+				}
+				return
+			}
+			if code != 0 {
+				t.Errorf("expected allow for %q, got %s", tt.cmd, result.Reason)
+			}
+		})
+	}
+
+	// The staged contents are checked, not the working tree
+	bad, _ := os.ReadFile(filepath.Join(dir, "bad.go"))
+	good := []byte("package main\n\nfunc helper() {\n}\n")
+	git("reset", "-q")
+	git("add", "bad.go")
+	os.WriteFile(filepath.Join(dir, "bad.go"), good, 0o644)
+	if _, code := CommitGateWithPolicy(shellInput(`git commit -m "feat: x"`), dir, policy); code != 2 {
+		t.Error("unformatted staged contents should block even when the working tree is fixed")
+	}
+	if _, code := CommitGateWithPolicy(shellInput(`git commit -am "feat: x"`), dir, policy); code != 0 {
+		t.Error("commit -a records the fixed working-tree copy")
+	}
+	git("add", "bad.go")
+	os.WriteFile(filepath.Join(dir, "bad.go"), bad, 0o644)
+	if _, code := CommitGateWithPolicy(shellInput(`git commit -m "feat: x"`), dir, policy); code != 0 {
+		t.Error("formatted staged contents should pass even when the working tree is not")
+	}
+
+	git("reset", "-q")
+	git("add", "bad.go")
+	if _, code := CommitGateWithPolicy(shellInput(`git commit -m "feat: x"`), dir, &CommitGatePolicy{Skip: []string{"gofmt", "go vet"}}); code != 0 {
+		t.Error("skipped checks should not block")
+	}
+}
+
+func TestCommitGate_Pathspecs(t *testing.T) {
+	dir := initGitRepo(t)
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.go"), []byte("package a\n"), 0o644)
+	exec.Command("git", "-C", dir, "add", ".").Run()
+	exec.Command("git", "-C", dir, "-c", "user.name=t", "-c", "user.email=t@t", "commit", "-qm", "add").Run()
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nvar A = 1\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.go"), []byte("package a\n\nvar B = 1\n"), 0o644)
+
+	os.WriteFile(filepath.Join(dir, "c.go"), []byte("package a\n"), 0o644)
+	exec.Command("git", "-C", dir, "add", "c.go").Run()
+
+	tests := []struct {
+		cmd          string
+		pending      []string
+		wantFiles    []string
+		wantWorktree []string
+	}{
+		{`git commit -m x -- a.go`, nil, []string{"a.go"}, []string{"a.go"}},
+		{`git commit -am x`, nil, []string{"a.go", "b.go", "c.go"}, []string{"a.go", "b.go"}},
+		{`git commit -m x`, nil, []string{"c.go"}, nil},
+		{`git commit -m x`, []string{"b.go"}, []string{"b.go", "c.go"}, []string{"b.go"}},
+	}
+	for _, tt := range tests {
+		calls := gitCalls(tt.cmd, dir)
+		files, worktree := commitFiles(calls[0], dir, tt.pending)
+		if !reflect.DeepEqual(files, tt.wantFiles) || !reflect.DeepEqual(worktree, tt.wantWorktree) {
+			t.Errorf("%s (pending %v): files = %v, worktree = %v, want %v, %v", tt.cmd, tt.pending, files, worktree, tt.wantFiles, tt.wantWorktree)
+		}
+	}
+}
+
+func TestCommitGate_GoVet(t *testing.T) {
+	if !commandExists("go") {
+		t.Skip("go not installed")
+	}
+	dir := initGitRepo(t)
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/x\n\ngo 1.21\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Printf(\"%d\\n\", \"x\")\n}\n"), 0o644)
+	exec.Command("git", "-C", dir, "add", ".").Run()
+
+	result, code := CommitGateWithPolicy(shellInput(`git commit -m "feat: x"`), dir, &CommitGatePolicy{Checks: []string{"go vet"}})
+	if code != 2 {
+		t.Fatalf("expected go vet to block, got %s", result.Reason)
+	}
+	if !strings.Contains(result.Reason, "main.go:6") || strings.Contains(result.Reason, os.TempDir()) {
+		t.Errorf("reason should name the repo file, not the snapshot: %s", result.Reason)
+	}
+	if len(result.Diagnostics) == 0 || result.Diagnostics[0].File != filepath.Join(dir, "main.go") {
+		t.Errorf("diagnostics = %+v", result.Diagnostics)
+	}
+}
@@ -1,45 +1,447 @@
 package hooks
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
-)
+	"strings"
 
-var (
-	commitMsgRe       = regexp.MustCompile(`\bgit\s+commit\s+.*-m\s+"([^"]*)"`)
-	commitMsgSingleRe = regexp.MustCompile(`\bgit\s+commit\s+.*-m\s+'([^']*)'`)
-	conventionalRe    = regexp.MustCompile(`^(feat|fix|chore|docs|refactor|test|ci|perf|style|build|revert)(\(.+\))?!?:\s+.+`)
+	"gopkg.in/yaml.v3"
 )
 
+var defaultCommitTypes = []string{"feat", "fix", "chore", "docs", "refactor", "test", "ci", "perf", "style", "build", "revert"}
+
+// defaultForbiddenTrailers reject attribution lines that tools add on the agent's behalf.
+var defaultForbiddenTrailers = []string{
+	`(?i)^co-authored-by:.*\b(claude|anthropic|copilot|openai|chatgpt|gpt-\d|cursor|codex|gemini|aider)\b`,
+	`(?i)generated (with|by) .*\b(claude|copilot|chatgpt|cursor|codex|gemini|aider|ai)\b`,
+}
+
+// commitHeaderRe splits a conventional header into type, scope, breaking mark and description.
+var commitHeaderRe = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^)]*)\))?(!)?: (.*)$`)
+
+// CommitPolicy configures CommitMsgLint (policies.commitMsgLint in config.yaml).
+// Without it, CommitMsgLint reads a commitlint config from the repo root.
+type CommitPolicy struct {
+	// Types are the allowed conventional commit types (default feat, fix, chore, ...).
+	Types []string `yaml:"types,omitempty" json:"types,omitempty"`
+	// Scopes, when set, is the allowlist for the (scope) part.
+	Scopes []string `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+	// RequireScope rejects headers without a (scope).
+	RequireScope bool `yaml:"requireScope,omitempty" json:"requireScope,omitempty"`
+	// SubjectMaxLength caps the header line (default 72; negative disables).
+	SubjectMaxLength int `yaml:"subjectMaxLength,omitempty" json:"subjectMaxLength,omitempty"`
+	// BodyMaxLineLength caps body lines (default 100; negative disables; URLs are exempt).
+	BodyMaxLineLength int `yaml:"bodyMaxLineLength,omitempty" json:"bodyMaxLineLength,omitempty"`
+	// TicketPattern, when set, is a regex the message must contain (e.g. "[A-Z]+-[0-9]+").
+	TicketPattern string `yaml:"ticketPattern,omitempty" json:"ticketPattern,omitempty"`
+	// ForbiddenTrailers are regexes no message line may match (default: AI attribution lines).
+	ForbiddenTrailers []string `yaml:"forbiddenTrailers,omitempty" json:"forbiddenTrailers,omitempty"`
+}
+
 // CommitMsgLint is a preToolUse hook that validates conventional commit messages.
 func CommitMsgLint(input HookInput) (HookResult, int) {
+	return CommitMsgLintWithPolicy(input, "", nil)
+}
+
+// CommitMsgLintWithPolicy lints every git commit in a Shell command, whatever
+// form the message takes: repeated -m, --message=, -F file, -F - with a
+// heredoc, or -m "$(cat <<EOF ...)". A nil policy means the repo's commitlint
+// config if there is one, else the built-in conventional commit rules.
+func CommitMsgLintWithPolicy(input HookInput, workDir string, policy *CommitPolicy) (HookResult, int) {
 	if input.ToolName != "Shell" {
 		return Allow(), 0
 	}
 
 	cmd := input.Command()
-	if cmd == "" {
+	if cmd == "" || !strings.Contains(cmd, "commit") {
 		return Allow(), 0
 	}
 
-	// Only care about git commit commands
-	var msg string
-	if matches := commitMsgRe.FindStringSubmatch(cmd); len(matches) > 1 {
-		msg = matches[1]
-	} else if matches := commitMsgSingleRe.FindStringSubmatch(cmd); len(matches) > 1 {
-		msg = matches[1]
-	} else {
-		// Not a git commit with -m, or uses heredoc — pass through
-		return Allow(), 0
+	for _, c := range shellCommands(cmd) {
+		args := commandArgs(c.Args)
+		if commandName(args) != "git" {
+			continue
+		}
+		dir, sub, subArgs := gitInvocation(workDir, args[1:])
+		if sub != "commit" {
+			continue
+		}
+		msg, ok := commitMessage(subArgs, c.Redirects, dir)
+		if !ok {
+			continue // editor, --no-edit, -C/--fixup or an unreadable -F file
+		}
+		if strings.TrimSpace(msg) == "" {
+			return Deny("Blocked: empty commit message"), 2
+		}
+		p := policy
+		if p == nil {
+			p = loadCommitlintPolicy(dir)
+		}
+		if problems := lintCommitMessage(msg, p); len(problems) > 0 {
+			return Deny(commitLintReason(msg, problems)), 2
+		}
 	}
 
-	if msg == "" {
-		return Deny("Blocked: empty commit message"), 2
+	return Allow(), 0
+}
+
+func commitLintReason(msg string, problems []string) string {
+	header, _, _ := strings.Cut(msg, "\n")
+	var reason strings.Builder
+	reason.WriteString("Blocked: commit message doesn't follow the commit conventions")
+	reason.WriteString("\n  Subject: " + header)
+	for _, p := range problems {
+		reason.WriteString("\n  - " + p)
 	}
+	reason.WriteString("\n\nExpected: type(scope): description (e.g., 'feat: add auth', 'fix(api): handle timeout')")
+	return reason.String()
+}
+
+// commitMessage assembles the message git commit would use from its arguments.
+// ok is false when the message cannot be known (editor, reused message, file
+// that cannot be read, unsupported substitution).
+func commitMessage(args []string, redirects []shellRedirect, dir string) (string, bool) {
+	var paragraphs []string
+	fromFile := false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		var value string
+		isMsg, isFile := false, false
+		switch {
+		case a == "--":
+			i = len(args)
+			continue
+		case a == "-m" || a == "--message" || a == "-F" || a == "--file":
+			if i+1 >= len(args) {
+				return "", false
+			}
+			i++
+			value = args[i]
+			isMsg, isFile = a == "-m" || a == "--message", a == "-F" || a == "--file"
+		case strings.HasPrefix(a, "--message="):
+			value, isMsg = strings.TrimPrefix(a, "--message="), true
+		case strings.HasPrefix(a, "--file="):
+			value, isFile = strings.TrimPrefix(a, "--file="), true
+		case strings.HasPrefix(a, "-m") && !strings.HasPrefix(a, "--"):
+			value, isMsg = a[2:], true // -m"msg"
+		case strings.HasPrefix(a, "-") && !strings.HasPrefix(a, "--") && strings.HasSuffix(a, "m") && i+1 < len(args):
+			i++
+			value, isMsg = args[i], true // -am "msg"
+		case a == "-C" || a == "-c" || a == "--reuse-message" || a == "--reedit-message" ||
+			strings.HasPrefix(a, "--reuse-message=") || strings.HasPrefix(a, "--reedit-message=") ||
+			a == "--fixup" || strings.HasPrefix(a, "--fixup=") || a == "--no-edit":
+			return "", false
+		}
 
-	if !conventionalRe.MatchString(msg) {
-		return Deny("Blocked: commit message doesn't follow conventional commits format. " +
-			"Expected: type(scope): description (e.g., 'feat: add auth', 'fix(api): handle timeout')"), 2
+		switch {
+		case isMsg:
+			text, ok := expandCommandSubst(value)
+			if !ok {
+				return "", false
+			}
+			paragraphs = append(paragraphs, strings.TrimRight(text, "\n"))
+		case isFile:
+			text, ok := readCommitFile(value, redirects, dir)
+			if !ok {
+				return "", false
+			}
+			fromFile = true
+			paragraphs = append(paragraphs, strings.TrimRight(text, "\n"))
+		}
 	}
+	if len(paragraphs) == 0 {
+		return "", false
+	}
+	if fromFile {
+		// git drops # comment lines from message files by default (cleanup=strip)
+		var kept []string
+		for _, line := range strings.Split(strings.Join(paragraphs, "\n\n"), "\n") {
+			if !strings.HasPrefix(line, "#") {
+				kept = append(kept, line)
+			}
+		}
+		return strings.TrimSpace(strings.Join(kept, "\n")), true
+	}
+	// Repeated -m options become separate paragraphs
+	return strings.Join(paragraphs, "\n\n"), true
+}
 
-	return Allow(), 0
+// readCommitFile returns the contents of -F file; "-" reads the heredoc or
+// here-string attached to the command.
+func readCommitFile(name string, redirects []shellRedirect, dir string) (string, bool) {
+	if name == "-" {
+		for _, r := range redirects {
+			switch r.Op {
+			case "<<", "<<-":
+				return r.Body, true
+			case "<<<":
+				return r.Target, true
+			}
+		}
+		return "", false
+	}
+	data, err := os.ReadFile(absFrom(dir, expandUserHome(name)))
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// expandCommandSubst replaces $(cat <<EOF ...), $(echo ...) and $(printf ...)
+// in a message with their output. Other substitutions cannot be evaluated.
+func expandCommandSubst(s string) (string, bool) {
+	var out strings.Builder
+	for {
+		i := strings.Index(s, "$(")
+		if i < 0 {
+			out.WriteString(s)
+			return out.String(), true
+		}
+		out.WriteString(s[:i])
+		body, end := scanShellParen(s, i+1)
+		text, ok := evalMessageCommand(body)
+		if !ok {
+			return "", false
+		}
+		// $(...) strips trailing newlines
+		out.WriteString(strings.TrimRight(text, "\n"))
+		s = s[end:]
+	}
+}
+
+func evalMessageCommand(body string) (string, bool) {
+	cmds := shellCommands(body)
+	if len(cmds) != 1 {
+		return "", false
+	}
+	c := cmds[0]
+	args := commandArgs(c.Args)
+	switch commandName(args) {
+	case "cat":
+		if len(args) == 1 {
+			for _, r := range c.Redirects {
+				if r.Op == "<<" || r.Op == "<<-" {
+					return r.Body, true
+				}
+			}
+		}
+	case "echo":
+		rest := args[1:]
+		if len(rest) > 0 && rest[0] == "-e" {
+			rest = rest[1:]
+		}
+		return strings.Join(rest, " ") + "\n", true
+	case "printf":
+		if len(args) == 2 {
+			return strings.ReplaceAll(args[1], `\n`, "\n"), true
+		}
+	}
+	return "", false
+}
+
+// lintCommitMessage returns every way msg breaks the policy.
+func lintCommitMessage(msg string, p *CommitPolicy) []string {
+	if p == nil {
+		p = &CommitPolicy{}
+	}
+	lines := strings.Split(strings.TrimRight(msg, "\n"), "\n")
+	header := lines[0]
+	var problems []string
+
+	// git's own generated headers are not held to the convention
+	generated := strings.HasPrefix(header, "Merge ") || strings.HasPrefix(header, "Revert \"") ||
+		strings.HasPrefix(header, "fixup! ") || strings.HasPrefix(header, "squash! ") || strings.HasPrefix(header, "amend! ")
+
+	if !generated {
+		problems = append(problems, lintCommitHeader(header, p)...)
+	}
+
+	maxSubject := p.SubjectMaxLength
+	if maxSubject == 0 {
+		maxSubject = 72
+	}
+	if n := len([]rune(header)); maxSubject > 0 && n > maxSubject {
+		problems = append(problems, fmt.Sprintf("subject is %d characters (max %d)", n, maxSubject))
+	}
+
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		problems = append(problems, "missing blank line between subject and body")
+	}
+
+	maxBody := p.BodyMaxLineLength
+	if maxBody == 0 {
+		maxBody = 100
+	}
+	for i, line := range lines[1:] {
+		if n := len([]rune(line)); maxBody > 0 && n > maxBody && !strings.Contains(line, "://") {
+			problems = append(problems, fmt.Sprintf("body line %d is %d characters (max %d); wrap the body", i+2, n, maxBody))
+			break
+		}
+	}
+
+	if p.TicketPattern != "" {
+		if re, err := regexp.Compile(p.TicketPattern); err == nil && !re.MatchString(msg) {
+			problems = append(problems, "no ticket reference matching "+p.TicketPattern)
+		}
+	}
+
+	forbidden := p.ForbiddenTrailers
+	if forbidden == nil {
+		forbidden = defaultForbiddenTrailers
+	}
+	for _, pattern := range forbidden {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		for _, line := range lines {
+			if re.MatchString(strings.TrimSpace(line)) {
+				problems = append(problems, "forbidden line: "+strings.TrimSpace(line))
+				break
+			}
+		}
+	}
+	return problems
+}
+
+func lintCommitHeader(header string, p *CommitPolicy) []string {
+	m := commitHeaderRe.FindStringSubmatch(header)
+	if m == nil {
+		return []string{"subject is not 'type(scope): description'"}
+	}
+	typ, scope, desc := m[1], m[2], m[4]
+	var problems []string
+
+	types := p.Types
+	if len(types) == 0 {
+		types = defaultCommitTypes
+	}
+	if !containsString(types, typ) {
+		problems = append(problems, "type '"+typ+"' is not one of "+strings.Join(types, ", "))
+	}
+
+	if scope == "" && p.RequireScope {
+		problems = append(problems, "a (scope) is required")
+	}
+	if scope != "" && len(p.Scopes) > 0 {
+		for _, s := range strings.Split(scope, ",") {
+			if s = strings.TrimSpace(s); !containsString(p.Scopes, s) {
+				problems = append(problems, "scope '"+s+"' is not one of "+strings.Join(p.Scopes, ", "))
+			}
+		}
+	}
+
+	if strings.TrimSpace(desc) == "" {
+		problems = append(problems, "description is empty")
+	}
+	return problems
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// commitlintFiles are the commitlint config files CommitMsgLint can read.
+// JavaScript configs (commitlint.config.js) cannot be evaluated and are ignored.
+var commitlintFiles = []string{
+	".commitlintrc", ".commitlintrc.json", ".commitlintrc.yaml", ".commitlintrc.yml", "package.json",
+}
+
+// loadCommitlintPolicy translates the repo's commitlint rules into a
+// CommitPolicy, or returns nil (built-in defaults) when there is none.
+func loadCommitlintPolicy(dir string) *CommitPolicy {
+	root := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if root == "" {
+		root = dir
+	}
+	for _, name := range commitlintFiles {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			continue
+		}
+		var cfg struct {
+			Rules map[string][]interface{} `json:"rules" yaml:"rules"`
+		}
+		if name == "package.json" {
+			var pkg struct {
+				Commitlint json.RawMessage `json:"commitlint"`
+			}
+			if json.Unmarshal(data, &pkg) != nil || len(pkg.Commitlint) == 0 {
+				continue
+			}
+			data = pkg.Commitlint
+		}
+		// YAML is a superset of JSON, so one decoder handles every rc format
+		if yaml.Unmarshal(data, &cfg) != nil {
+			continue
+		}
+		return commitlintRulesToPolicy(cfg.Rules)
+	}
+	return nil
+}
+
+// commitlintRulesToPolicy maps the commitlint rules CommitMsgLint understands.
+// A rule is [level, "always"|"never", value]; level 0 disables it.
+func commitlintRulesToPolicy(rules map[string][]interface{}) *CommitPolicy {
+	p := &CommitPolicy{}
+	for name, rule := range rules {
+		if len(rule) < 2 || fmt.Sprint(rule[0]) == "0" {
+			if name == "header-max-length" {
+				p.SubjectMaxLength = -1
+			}
+			if name == "body-max-line-length" {
+				p.BodyMaxLineLength = -1
+			}
+			continue
+		}
+		when := fmt.Sprint(rule[1])
+		var value interface{}
+		if len(rule) > 2 {
+			value = rule[2]
+		}
+		switch name {
+		case "type-enum":
+			p.Types = toStrings(value)
+		case "scope-enum":
+			p.Scopes = toStrings(value)
+		case "scope-empty":
+			p.RequireScope = when == "never"
+		case "header-max-length":
+			p.SubjectMaxLength = toInt(value)
+		case "body-max-line-length":
+			p.BodyMaxLineLength = toInt(value)
+		case "references-empty":
+			if when == "never" {
+				p.TicketPattern = `(#\d+|[A-Z][A-Z0-9]+-\d+)`
+			}
+		}
+	}
+	return p
+}
+
+func toStrings(v interface{}) []string {
+	list, _ := v.([]interface{})
+	var out []string
+	for _, item := range list {
+		out = append(out, fmt.Sprint(item))
+	}
+	return out
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 0
 }
@@ -1,6 +1,9 @@
 package hooks
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -81,3 +84,130 @@ EOF
 		t.Logf("heredoc blocked with reason: %s (acceptable)", result.Reason)
 	}
 }
+
+func TestCommitMsgLint_MessageForms(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "good.txt"), []byte("# comment\nfeat: from file\n\nBody text.\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "bad.txt"), []byte("added stuff\n"), 0o644)
+
+	tests := []struct {
+		name     string
+		cmd      string
+		wantDeny bool
+	}{
+		{"heredoc good", "git commit -m \"$(cat <<'EOF'\nfeat: add something\n\nDetailed description here.\nEOF\n)\"", false},
+		{"heredoc bad", "git commit -m \"$(cat <<'EOF'\nadded something\nEOF\n)\"", true},
+		{"heredoc apostrophe bad", "git commit -m \"$(cat <<'EOF'\nbad subject don't\nEOF\n)\"", true},
+		{"heredoc apostrophe good", "git commit -m \"$(cat <<'EOF'\nfix: don't drop the last line\n\nIt's gone now.\nEOF\n)\"", false},
+		{"heredoc apostrophe then second commit", "git commit -m \"$(cat <<'EOF'\nfix: it's ok\nEOF\n)\" && git commit -m \"wip\"", true},
+		{"heredoc paren in body", "git commit -m \"$(cat <<'EOF'\nbad subject (don't\nEOF\n)\"", true},
+		{"heredoc no blank line", "git commit -m \"$(cat <<EOF\nfeat: add something\nbody right away\nEOF\n)\"", true},
+		{"-F - heredoc", "git commit -F - <<EOF\nfix: handle nil\nEOF", false},
+		{"-F - heredoc bad", "git commit -F - <<EOF\nWIP\nEOF", true},
+		{"-F file", "git commit -F good.txt", false},
+		{"--file= bad", "git commit --file=bad.txt", true},
+		{"-F missing file", "git commit -F missing.txt", false},
+		{"repeated -m", `git commit -m "feat: add x" -m "Longer explanation."`, false},
+		{"second -m header ignored", `git commit -m "bad subject" -m "feat: not a header"`, true},
+		{"--message=", `git commit --message="fix: typo"`, false},
+		{"--message= bad", `git commit --message="typo"`, true},
+		{"-am", `git commit -am "oops"`, true},
+		{"-C dir", `git -C sub commit -m "chore: bump"`, false},
+		{"second commit checked", `git commit -m "feat: a" && git commit -m "b"`, true},
+		{"amend no-edit", "git commit --amend --no-edit", false},
+		{"editor", "git commit", false},
+		{"fixup", "git commit --fixup HEAD~1", false},
+		{"merge header", `git commit -m "Merge branch 'x' into main"`, false},
+		{"long subject", `git commit -m "feat: ` + strings.Repeat("x", 80) + `"`, true},
+		{"long body line", `git commit -m "feat: x" -m "` + strings.Repeat("word ", 30) + `"`, true},
+		{"url body line", `git commit -m "feat: x" -m "See https://example.com/` + strings.Repeat("a", 120) + `"`, false},
+		{"AI attribution", `git commit -m "feat: x" -m "Co-Authored-By: Claude <noreply@anthropic.com>"`, true},
+		{"human co-author", `git commit -m "feat: x" -m "Co-authored-by: Jane <jane@example.com>"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := CommitMsgLintWithPolicy(shellInput(tt.cmd), dir, nil)
+			if tt.wantDeny && code != 2 {
+				t.Errorf("expected deny for %q", tt.cmd)
+			}
+			if !tt.wantDeny && code != 0 {
+				t.Errorf("expected allow for %q, got %s", tt.cmd, result.Reason)
+			}
+		})
+	}
+}
+
+func TestCommitMsgLint_Policy(t *testing.T) {
+	policy := &CommitPolicy{
+		Types:         []string{"feat", "fix"},
+		Scopes:        []string{"api", "ui"},
+		RequireScope:  true,
+		TicketPattern: `[A-Z]+-[0-9]+`,
+	}
+
+	tests := []struct {
+		name     string
+		msg      string
+		wantDeny string
+	}{
+		{"ok", "feat(api): add x\n\nRefs: ENG-12", ""},
+		{"type not allowed", "chore(api): bump ENG-1", "type 'chore'"},
+		{"scope not allowed", "fix(db): x ENG-1", "scope 'db'"},
+		{"scope required", "fix: x ENG-1", "scope) is required"},
+		{"multiple scopes", "fix(api,ui): x ENG-1", ""},
+		{"ticket missing", "fix(ui): x", "no ticket reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := "git commit -F - <<'EOF'\n" + tt.msg + "\nEOF"
+			result, code := CommitMsgLintWithPolicy(shellInput(cmd), t.TempDir(), policy)
+			if tt.wantDeny == "" {
+				if code != 0 {
+					t.Errorf("expected allow, got %s", result.Reason)
+				}
+				return
+			}
+			if code != 2 || !strings.Contains(result.Reason, tt.wantDeny) {
+				t.Errorf("expected deny mentioning %q, got code=%d %q", tt.wantDeny, code, result.Reason)
+			}
+		})
+	}
+}
+
+func TestCommitMsgLint_CommitlintConfig(t *testing.T) {
+	dir := initGitRepo(t)
+	rc := `{
+  "extends": ["@commitlint/config-conventional"],
+  "rules": {
+    "type-enum": [2, "always", ["feat", "fix", "release"]],
+    "header-max-length": [2, "always", 50],
+    "body-max-line-length": [0]
+  }
+}`
+	os.WriteFile(filepath.Join(dir, ".commitlintrc.json"), []byte(rc), 0o644)
+
+	tests := []struct {
+		msg      string
+		wantDeny bool
+	}{
+		{"release: v1.2.0", false},
+		{"chore: bump deps", true},
+		{"feat: " + strings.Repeat("x", 50), true},
+		{"feat: x\n\n" + strings.Repeat("long ", 40), false},
+	}
+	for _, tt := range tests {
+		cmd := "git commit -F - <<'EOF'\n" + tt.msg + "\nEOF"
+		result, code := CommitMsgLintWithPolicy(shellInput(cmd), dir, nil)
+		if tt.wantDeny != (code == 2) {
+			t.Errorf("%q: wantDeny=%v, got code=%d %s", tt.msg, tt.wantDeny, code, result.Reason)
+		}
+	}
+
+	yamlRC := "rules:\n  scope-enum: [2, always, [core]]\n"
+	os.Remove(filepath.Join(dir, ".commitlintrc.json"))
+	os.WriteFile(filepath.Join(dir, ".commitlintrc.yml"), []byte(yamlRC), 0o644)
+	if _, code := CommitMsgLintWithPolicy(shellInput(`git commit -m "feat(web): x"`), dir, nil); code != 2 {
+		t.Error("expected scope-enum from YAML commitlint config to deny")
+	}
+}
@@ -0,0 +1,236 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CompletionGatePolicy configures completion-gate (policies.completionGate in config.yaml).
+type CompletionGatePolicy struct {
+	// Conditions are the checks to enforce: verified, checks, todos,
+	// protected-branch (default all).
+	Conditions []string `yaml:"conditions,omitempty" json:"conditions,omitempty"`
+	// VerifyCommands are extra regexes for Shell commands that count as
+	// testing or building the work, matched against each command in a Shell
+	// command line (e.g. ^just check$).
+	VerifyCommands []string `yaml:"verifyCommands,omitempty" json:"verifyCommands,omitempty"`
+	// ProtectedBranches are names or globs where uncommitted work must not be
+	// left behind (default main, master).
+	ProtectedBranches []string `yaml:"protectedBranches,omitempty" json:"protectedBranches,omitempty"`
+}
+
+// verifyCommandRes match a single command that tests or builds a project,
+// as its arguments joined by spaces with the program's base name first.
+var verifyCommandRes = []*regexp.Regexp{
+	regexp.MustCompile(`^go (test|build|vet)\b`),
+	regexp.MustCompile(`^cargo (test|build|check|clippy|nextest)\b`),
+	regexp.MustCompile(`^(npm|pnpm|yarn|bun)( run)? (test|build|check|typecheck|lint)\b`),
+	regexp.MustCompile(`^((npx|bunx|pnpm exec|pnpm dlx|yarn|uv run|poetry run) )?(jest|vitest|tsc|pytest|tox|nox|mypy|pyright|ruff|eslint|biome)\b`),
+	regexp.MustCompile(`^((uv run|poetry run) )?python3? -m (pytest|unittest|mypy)\b`),
+	regexp.MustCompile(`^(mvn|mvnw|gradle|gradlew|bazel|ctest|dotnet (test|build))\b`),
+	regexp.MustCompile(`^make\b`),
+}
+
+var defaultCompletionBranches = []string{"main", "master"}
+
+// CompletionGate is a Stop hook that blocks ending the session while the work
+// is unverified.
+func CompletionGate(input HookInput, workDir, stateDir string) (HookResult, int) {
+	return CompletionGateWithPolicy(input, workDir, stateDir, nil)
+}
+
+// CompletionGateWithPolicy blocks the stop when files were written after the
+// last test or build command, lint or type failures from this session are
+// still open, TODOs were added, or uncommitted work sits on a protected
+// branch. Conditions come from the session's activity log and check results
+// under stateDir and from git, not from the transcript. A stop that is
+// already the result of a block (stop_hook_active) is let through.
+func CompletionGateWithPolicy(input HookInput, workDir, stateDir string, policy *CompletionGatePolicy) (HookResult, int) {
+	if input.StopHookActive() || stateDir == "" {
+		return NoOp(), 0
+	}
+	if policy == nil {
+		policy = &CompletionGatePolicy{}
+	}
+	enabled := func(name string) bool { return len(policy.Conditions) == 0 || hasString(policy.Conditions, name) }
+
+	events := loadSessionEvents(stateDir, input.SessionID())
+	written := writtenFiles(events)
+
+	var problems []string
+	if enabled("verified") {
+		if unverified := unverifiedFiles(events, policy); len(unverified) > 0 {
+			problems = append(problems, fmt.Sprintf("%s written after the last test or build command; run the tests or build", describeFiles(relPaths(unverified, workDir), "file", "files")))
+		}
+	}
+	if enabled("checks") {
+		var open []string
+		for _, r := range loadCheckResults(SessionStateDir(stateDir, input.SessionID())) {
+			if r.Failed {
+				open = append(open, fmt.Sprintf("%s still failing for %s", r.Check, relPaths([]string{r.Path}, workDir)[0]))
+			}
+		}
+		sort.Strings(open)
+		problems = append(problems, open...)
+	}
+	if enabled("todos") {
+		if todos := addedTodos(workDir, written); len(todos) > 0 {
+			problems = append(problems, fmt.Sprintf("%d TODO/FIXME comment(s) added: %s", len(todos), strings.Join(todos, "; ")))
+		}
+	}
+	if enabled("protected-branch") {
+		if p := protectedBranchChanges(workDir, written, policy); p != "" {
+			problems = append(problems, p)
+		}
+	}
+
+	if len(problems) == 0 {
+		return NoOp(), 0
+	}
+	var reason strings.Builder
+	reason.WriteString("The work is not finished:")
+	for _, p := range problems {
+		reason.WriteString("\n  - " + p)
+	}
+	reason.WriteString("\n\nResolve these before finishing, or explain to the user why they don't apply.")
+	return Block(reason.String()), 0
+}
+
+// writtenFiles returns the files written in the session, in first-written order.
+func writtenFiles(events []SessionEvent) []string {
+	var files []string
+	for _, ev := range events {
+		switch ev.Tool {
+		case "Write", "Edit", "MultiEdit":
+			if ev.Path != "" && !hasString(files, ev.Path) {
+				files = append(files, ev.Path)
+			}
+		}
+	}
+	return files
+}
+
+// unverifiedFiles returns files written after the last verify command.
+func unverifiedFiles(events []SessionEvent, policy *CompletionGatePolicy) []string {
+	res := append([]*regexp.Regexp{}, verifyCommandRes...)
+	for _, p := range policy.VerifyCommands {
+		if re, err := regexp.Compile(p); err == nil {
+			res = append(res, re)
+		}
+	}
+	var files []string
+	for _, ev := range events {
+		switch {
+		case (ev.Tool == "Write" || ev.Tool == "Edit" || ev.Tool == "MultiEdit") && ev.Path != "":
+			if !hasString(files, ev.Path) {
+				files = append(files, ev.Path)
+			}
+		case ev.Tool == "Shell" && isVerifyCommand(res, ev.Command):
+			files = nil
+		}
+	}
+	return files
+}
+
+// isVerifyCommand reports whether one of the commands in a Shell command line
+// matches res. Each command is matched on its own, wrappers like sudo or env
+// stripped, so `echo go test` or `grep pytest notes.md` don't count.
+func isVerifyCommand(res []*regexp.Regexp, command string) bool {
+	for _, c := range shellCommands(command) {
+		args := commandArgs(c.Args)
+		if len(args) == 0 {
+			continue
+		}
+		line := strings.Join(append([]string{filepath.Base(args[0])}, args[1:]...), " ")
+		if matchesAny(res, line) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// addedTodos returns TODO-style comments on lines the working tree adds to
+// files relative to HEAD (whole files when untracked).
+func addedTodos(workDir string, files []string) []string {
+	if len(files) == 0 || gitOutput(workDir, "rev-parse", "--git-dir") == "" {
+		return nil
+	}
+	var todos []string
+	for _, f := range files {
+		rel := relPaths([]string{f}, workDir)[0]
+		var added []string
+		if gitOutput(workDir, "ls-files", "--", f) == "" {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				continue
+			}
+			added = strings.Split(string(data), "\n")
+		} else {
+			for _, line := range strings.Split(gitOutput(workDir, "diff", "HEAD", "-U0", "--no-color", "--", f), "\n") {
+				if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+					added = append(added, line[1:])
+				}
+			}
+		}
+		for _, line := range added {
+			if m := todoRe.FindString(line); m != "" {
+				todos = append(todos, rel+": "+strings.TrimSpace(m))
+			}
+		}
+	}
+	return todos
+}
+
+// protectedBranchChanges describes uncommitted changes to the files written
+// in the session when the checkout is on a protected branch. Changes the
+// session did not make (the user's own work in progress) are left alone.
+func protectedBranchChanges(workDir string, written []string, policy *CompletionGatePolicy) string {
+	if len(written) == 0 {
+		return ""
+	}
+	protected := policy.ProtectedBranches
+	if len(protected) == 0 {
+		protected = defaultCompletionBranches
+	}
+	branch := gitOutput(workDir, "rev-parse", "--abbrev-ref", "HEAD")
+	if !(branchGuardCheck{protected: protected}).isProtected(branch) {
+		return ""
+	}
+	var files []string
+	for _, line := range gitLines(workDir, append([]string{"status", "--porcelain", "--"}, written...)...) {
+		// gitOutput trims the first line's leading space, so the status
+		// code may be one or two characters wide
+		if len(line) > 2 {
+			files = append(files, strings.TrimSpace(line[2:]))
+		}
+	}
+	if len(files) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("uncommitted changes on protected branch %s: %s; commit them on a feature branch", branch, describeFiles(files, "file", "files"))
+}
+
+// relPaths makes paths relative to dir where they are inside it.
+func relPaths(paths []string, dir string) []string {
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		out[i] = p
+		if rel, err := filepath.Rel(dir, p); err == nil && !strings.HasPrefix(rel, "..") {
+			out[i] = rel
+		}
+	}
+	return out
+}
@@ -0,0 +1,197 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// completionRepo returns a repo on a feature branch with a committed tracked.txt.
+func completionRepo(t *testing.T) string {
+	t.Helper()
+	dir := initCheckpointRepo(t)
+	cmd := exec.Command("git", "checkout", "-q", "-b", "feature")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git checkout failed: %v\n%s", err, out)
+	}
+	return dir
+}
+
+func recordWrite(t *testing.T, stateDir, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	RecordSessionEvent(stateDir, sessionInput("Write", "s1", map[string]string{"path": path}))
+}
+
+func recordShell(stateDir, command string) {
+	RecordSessionEvent(stateDir, sessionInput("Shell", "s1", map[string]string{"command": command}))
+}
+
+func gateStop(active bool) HookInput {
+	ti, _ := json.Marshal(map[string]interface{}{"session_id": "s1", "stop_hook_active": active})
+	return HookInput{ToolName: "Stop", ToolInput: ti}
+}
+
+func TestCompletionGate_Verified(t *testing.T) {
+	dir := completionRepo(t)
+	stateDir := t.TempDir()
+
+	if result, _ := CompletionGate(gateStop(false), dir, stateDir); result.Decision != "" {
+		t.Errorf("session without writes should stop, got %+v", result)
+	}
+
+	recordWrite(t, stateDir, dir, "tracked.txt", "v2\n")
+	result, code := CompletionGate(gateStop(false), dir, stateDir)
+	if code != 0 || result.Decision != "block" || !strings.Contains(result.Reason, "tracked.txt") || !strings.Contains(result.Reason, "after the last test or build command") {
+		t.Errorf("expected block for unverified write, got code=%d %+v", code, result)
+	}
+
+	recordShell(stateDir, "cd api && go test ./...")
+	if result, _ := CompletionGate(gateStop(false), dir, stateDir); result.Decision != "" {
+		t.Errorf("test run after the write should verify it, got %q", result.Reason)
+	}
+
+	recordWrite(t, stateDir, dir, "tracked.txt", "v3\n")
+	recordShell(stateDir, "ls -la")
+	if result, _ := CompletionGate(gateStop(false), dir, stateDir); result.Decision != "block" {
+		t.Error("a write after the last test run should block again")
+	}
+
+	policy := &CompletionGatePolicy{VerifyCommands: []string{`^just check$`}}
+	recordShell(stateDir, "just check")
+	if result, _ := CompletionGateWithPolicy(gateStop(false), dir, stateDir, policy); result.Decision != "" {
+		t.Errorf("custom verify command should count, got %q", result.Reason)
+	}
+}
+
+func TestCompletionGate_EditTools(t *testing.T) {
+	for _, tool := range []string{"Edit", "MultiEdit"} {
+		t.Run(tool, func(t *testing.T) {
+			dir := completionRepo(t)
+			stateDir := t.TempDir()
+			path := filepath.Join(dir, "tracked.txt")
+			os.WriteFile(path, []byte("v2\n"), 0644)
+			RecordSessionEvent(stateDir, sessionInput(tool, "s1", map[string]string{"file_path": path}))
+
+			if got := writtenFiles(loadSessionEvents(stateDir, "s1")); len(got) != 1 || got[0] != path {
+				t.Errorf("written files = %v, want [%s]", got, path)
+			}
+			result, _ := CompletionGate(gateStop(false), dir, stateDir)
+			if result.Decision != "block" || !strings.Contains(result.Reason, "tracked.txt") {
+				t.Errorf("expected an unverified %s to block, got %+v", tool, result)
+			}
+			recordShell(stateDir, "go test ./...")
+			if result, _ := CompletionGateWithPolicy(gateStop(false), dir, stateDir, &CompletionGatePolicy{Conditions: []string{"verified"}}); result.Decision != "" {
+				t.Errorf("test run after the %s should verify it, got %q", tool, result.Reason)
+			}
+		})
+	}
+}
+
+func TestCompletionGate_StopHookActive(t *testing.T) {
+	dir := completionRepo(t)
+	stateDir := t.TempDir()
+	recordWrite(t, stateDir, dir, "tracked.txt", "v2\n")
+	if result, _ := CompletionGate(gateStop(true), dir, stateDir); result.Decision != "" {
+		t.Errorf("stop_hook_active should let the session stop, got %+v", result)
+	}
+}
+
+func TestCompletionGate_OpenCheckFailures(t *testing.T) {
+	dir := completionRepo(t)
+	stateDir := t.TempDir()
+	file := filepath.Join(dir, "tracked.txt")
+	write := sessionInput("Write", "s1", map[string]string{"path": file})
+	policy := &CompletionGatePolicy{Conditions: []string{"checks"}}
+
+	RecordCheckResult(stateDir, "lint-changed", write, dir, Deny("eslint found 1 issue"), 2)
+	result, _ := CompletionGateWithPolicy(gateStop(false), dir, stateDir, policy)
+	if result.Decision != "block" || !strings.Contains(result.Reason, "lint-changed still failing for tracked.txt") {
+		t.Errorf("expected open lint failure to block, got %+v", result)
+	}
+
+	RecordCheckResult(stateDir, "lint-changed", write, dir, Allow(), 0)
+	if result, _ := CompletionGateWithPolicy(gateStop(false), dir, stateDir, policy); result.Decision != "" {
+		t.Errorf("fixed failure should not block, got %q", result.Reason)
+	}
+}
+
+func TestCompletionGate_AddedTodos(t *testing.T) {
+	dir := completionRepo(t)
+	stateDir := t.TempDir()
+	policy := &CompletionGatePolicy{Conditions: []string{"todos"}}
+
+	recordWrite(t, stateDir, dir, "tracked.txt", "v1\n// TODO: handle errors\n")
+	recordWrite(t, stateDir, dir, "new.go", "package x\n// FIXME: remove\n")
+	result, _ := CompletionGateWithPolicy(gateStop(false), dir, stateDir, policy)
+	if result.Decision != "block" || !strings.Contains(result.Reason, "tracked.txt: TODO: handle errors") || !strings.Contains(result.Reason, "new.go: FIXME: remove") {
+		t.Errorf("expected added TODOs to block, got %+v", result)
+	}
+
+	// TODOs that were already committed don't count
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", "todos"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = checkpointEnv("")
+		cmd.Run()
+	}
+	if result, _ := CompletionGateWithPolicy(gateStop(false), dir, stateDir, policy); result.Decision != "" {
+		t.Errorf("committed TODOs should not block, got %q", result.Reason)
+	}
+}
+
+func TestCompletionGate_ProtectedBranch(t *testing.T) {
+	dir := completionRepo(t)
+	stateDir := t.TempDir()
+	policy := &CompletionGatePolicy{Conditions: []string{"protected-branch"}, ProtectedBranches: []string{"feat*"}}
+
+	// The user's own uncommitted work is not the session's to commit
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("wip\n"), 0644)
+	if result, _ := CompletionGateWithPolicy(gateStop(false), dir, stateDir, policy); result.Decision != "" {
+		t.Errorf("changes the session did not make should not block, got %q", result.Reason)
+	}
+
+	recordWrite(t, stateDir, dir, "tracked.txt", "v2\n")
+	result, _ := CompletionGateWithPolicy(gateStop(false), dir, stateDir, policy)
+	if result.Decision != "block" || !strings.Contains(result.Reason, "protected branch feature") || !strings.Contains(result.Reason, "(tracked.txt)") {
+		t.Errorf("expected uncommitted changes on protected branch to block, got %+v", result)
+	}
+	if strings.Contains(result.Reason, "notes.txt") {
+		t.Errorf("only the session's files should be listed, got %q", result.Reason)
+	}
+
+	policy.ProtectedBranches = nil
+	if result, _ := CompletionGateWithPolicy(gateStop(false), dir, stateDir, policy); result.Decision != "" {
+		t.Errorf("changes on a feature branch should not block, got %q", result.Reason)
+	}
+}
+
+func TestIsVerifyCommand(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want bool
+	}{
+		{"cd api && go test ./...", true},
+		{"sudo -u ci make check", true},
+		{"npx vitest run", true},
+		{"./gradlew build", true},
+		{".venv/bin/pytest -q", true},
+		{"uv run python -m pytest", true},
+		{"echo go test ./...", false},
+		{"grep -rn pytest docs/", false},
+		{"git commit -m 'make tests pass'", false},
+		{"cat Makefile", false},
+	}
+	for _, tt := range tests {
+		if got := isVerifyCommand(verifyCommandRes, tt.cmd); got != tt.want {
+			t.Errorf("isVerifyCommand(%q) = %v, want %v", tt.cmd, got, tt.want)
+		}
+	}
+}
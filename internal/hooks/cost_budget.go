@@ -0,0 +1,28 @@
+package hooks
+
+import (
+	"fmt"
+	"time"
+)
+
+// CostBudget is a preToolUse hook that blocks tool calls once the session or
+// today's cost, as recorded by cost-estimator in logDir, has reached its
+// budget in policy. Without budgets it allows everything.
+func CostBudget(input HookInput, logDir string, policy *CostPolicy) (HookResult, int) {
+	if policy == nil || (policy.SessionBudget <= 0 && policy.DailyBudget <= 0) {
+		return Allow(), 0
+	}
+	totals := loadCostTotals(logDir)
+
+	session := input.SessionID()
+	if session == "" {
+		session = "default"
+	}
+	if s := totals.Sessions[session]; s != nil && policy.SessionBudget > 0 && s.CostUSD >= policy.SessionBudget {
+		return Deny(fmt.Sprintf("Blocked: this session has cost $%.2f, over its $%.2f budget (policies.cost.sessionBudget). Wrap up or raise the budget.", s.CostUSD, policy.SessionBudget)), 2
+	}
+	if d := totals.Days[time.Now().Format("2006-01-02")]; d != nil && policy.DailyBudget > 0 && d.CostUSD >= policy.DailyBudget {
+		return Deny(fmt.Sprintf("Blocked: today's sessions have cost $%.2f, over the $%.2f daily budget (policies.cost.dailyBudget).", d.CostUSD, policy.DailyBudget)), 2
+	}
+	return Allow(), 0
+}
@@ -0,0 +1,38 @@
+package hooks
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCostBudget(t *testing.T) {
+	dir := t.TempDir()
+	tr := filepath.Join(t.TempDir(), "t.jsonl")
+	appendLines(t, tr, usageLine("m1", "claude-sonnet-4", 0, 100000, 0, 0)) // $1.50
+	CostEstimator(costInput("Stop", tr, "s1"), dir)
+
+	tests := []struct {
+		name    string
+		session string
+		policy  *CostPolicy
+		want    string
+	}{
+		{"no budgets", "s1", nil, "allow"},
+		{"under session budget", "s1", &CostPolicy{SessionBudget: 2}, "allow"},
+		{"over session budget", "s1", &CostPolicy{SessionBudget: 1}, "deny"},
+		{"other session", "s2", &CostPolicy{SessionBudget: 1}, "allow"},
+		{"over daily budget", "s2", &CostPolicy{DailyBudget: 1.5}, "deny"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := CostBudget(costInput("Shell", "", tt.session), dir, tt.policy)
+			if result.Decision != tt.want {
+				t.Fatalf("decision = %q, want %q (%s)", result.Decision, tt.want, result.Reason)
+			}
+			if tt.want == "deny" && (code != 2 || !strings.HasPrefix(result.Reason, "Blocked:")) {
+				t.Errorf("unexpected deny %+v code=%d", result, code)
+			}
+		})
+	}
+}
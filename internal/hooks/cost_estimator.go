@@ -1,37 +1,356 @@
 package hooks
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"hooks/internal/transcript"
+)
+
+// CostPolicy configures cost-estimator and cost-budget (policies.cost in
+// config.yaml).
+type CostPolicy struct {
+	// Prices adds or overrides model prices, keyed by model name or prefix
+	// (the longest matching key wins).
+	Prices map[string]ModelPrice `yaml:"prices,omitempty" json:"prices,omitempty"`
+	// SessionBudget and DailyBudget are limits in USD; 0 means no limit.
+	SessionBudget float64 `yaml:"sessionBudget,omitempty" json:"sessionBudget,omitempty"`
+	DailyBudget   float64 `yaml:"dailyBudget,omitempty" json:"dailyBudget,omitempty"`
+	// WarnAt is the fraction of a budget at which cost-estimator warns
+	// (default 0.8).
+	WarnAt float64 `yaml:"warnAt,omitempty" json:"warnAt,omitempty"`
+}
+
+// ModelPrice is a model's price in USD per million tokens. Cache prices
+// default to 1.25x (write) and 0.1x (read) the input price.
+type ModelPrice struct {
+	Input      float64 `yaml:"input" json:"input"`
+	Output     float64 `yaml:"output" json:"output"`
+	CacheWrite float64 `yaml:"cacheWrite,omitempty" json:"cacheWrite,omitempty"`
+	CacheRead  float64 `yaml:"cacheRead,omitempty" json:"cacheRead,omitempty"`
+}
+
+// defaultModelPrices are list prices keyed by model prefix.
+var defaultModelPrices = map[string]ModelPrice{
+	"claude-opus-4-5":   {Input: 5, Output: 25},
+	"claude-opus-4":     {Input: 15, Output: 75},
+	"claude-3-opus":     {Input: 15, Output: 75},
+	"claude-sonnet-4":   {Input: 3, Output: 15},
+	"claude-3-7-sonnet": {Input: 3, Output: 15},
+	"claude-3-5-sonnet": {Input: 3, Output: 15},
+	"claude-haiku-4-5":  {Input: 1, Output: 5},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25},
+}
+
+const (
+	costLogFile    = "cost.log"
+	costTotalsFile = "totals.json"
+	costSessionTTL = 30 * 24 * time.Hour
+	defaultWarnAt  = 0.8
 )
 
-// estimateTokens gives a rough token count for the hook input.
-// Approximation: ~4 chars per token.
-func estimateTokens(input HookInput) int {
-	raw, _ := json.Marshal(input)
-	return len(raw) / 4
+// costTotal is token usage and its cost.
+type costTotal struct {
+	Usage   transcript.Usage `json:"usage"`
+	CostUSD float64          `json:"cost_usd"`
+	// Warned and Exceeded record which budget messages were given, so the
+	// warning and the over-budget notice each come once.
+	Warned   bool `json:"warned,omitempty"`
+	Exceeded bool `json:"exceeded,omitempty"`
 }
 
-// CostEstimator is a postToolUse hook that tracks estimated token usage.
+// sessionCost is a session's running total and how far its transcript has
+// been read.
+type sessionCost struct {
+	costTotal
+	Repo          string                      `json:"repo,omitempty"`
+	Transcript    string                      `json:"transcript,omitempty"`
+	Offset        int64                       `json:"offset"`
+	LastMessageID string                      `json:"last_message_id,omitempty"`
+	LastUsage     transcript.Usage            `json:"last_usage"`
+	Models        map[string]transcript.Usage `json:"models,omitempty"`
+	Unpriced      []string                    `json:"unpriced,omitempty"`
+	Updated       time.Time                   `json:"updated"`
+}
+
+// costTotals is everything cost-estimator has counted, per session, per repo
+// and per day (local date).
+type costTotals struct {
+	Sessions map[string]*sessionCost `json:"sessions"`
+	Repos    map[string]*costTotal   `json:"repos"`
+	Days     map[string]*costTotal   `json:"days"`
+}
+
+// CostEstimator records the session's token usage and cost.
 func CostEstimator(input HookInput, logDir string) (HookResult, int) {
+	return CostEstimatorWithPolicy(input, logDir, nil)
+}
+
+// CostEstimatorWithPolicy reads the usage the model reported (input, output
+// and cache tokens per model) from the session transcript, prices it and adds
+// it to the session, repo and daily totals in logDir. It runs on postToolUse,
+// stop and sessionEnd; each run reads only what the transcript gained since
+// the last one. Each increment is logged to cost.log. When a budget crosses
+// policy.WarnAt it says so once; blocking is left to cost-budget.
+func CostEstimatorWithPolicy(input HookInput, logDir string, policy *CostPolicy) (HookResult, int) {
+	lifecycle := input.ToolName == "" || input.ToolName == "Stop" || input.ToolName == "SessionEnd"
+	reply := func(msg string) (HookResult, int) {
+		switch {
+		case lifecycle && msg == "":
+			return NoOp(), 0
+		case lifecycle:
+			return NoOpMsg(msg), 0
+		case msg == "":
+			return Allow(), 0
+		}
+		return AllowMsg(msg), 0
+	}
+	if policy == nil {
+		policy = &CostPolicy{}
+	}
+
+	path := input.TranscriptPath()
+	if path == "" {
+		return reply("")
+	}
 	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return Allow(), 0
+		return reply("")
+	}
+	session := input.SessionID()
+	if session == "" {
+		session = "default"
+	}
+
+	// Hooks for parallel tool calls run at once; without the lock they would
+	// each add their increments to the same old totals and lose some.
+	unlock, ok := lockStateFile(filepath.Join(logDir, costTotalsFile))
+	if !ok {
+		return reply("") // the transcript offset is unchanged, so the next run counts it
+	}
+	defer unlock()
+
+	totals := loadCostTotals(logDir)
+	s := totals.Sessions[session]
+	if s == nil {
+		s = &sessionCost{Repo: repoNameFromCwd(input.Cwd()), Models: map[string]transcript.Usage{}}
+		totals.Sessions[session] = s
+	}
+	if s.Transcript != path {
+		s.Transcript, s.Offset, s.LastMessageID, s.LastUsage = path, 0, "", transcript.Usage{}
+	}
+
+	increments, err := s.readTranscript(path)
+	if err != nil {
+		return reply("")
+	}
+	if len(increments) == 0 {
+		saveCostTotals(logDir, totals)
+		return reply("")
+	}
+
+	f, _ := os.OpenFile(filepath.Join(logDir, costLogFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	for _, inc := range increments {
+		price, ok := modelPrice(inc.model, policy.Prices)
+		if !ok && !hasString(s.Unpriced, inc.model) {
+			s.Unpriced = append(s.Unpriced, inc.model)
+		}
+		cost := price.cost(inc.usage)
+		day := inc.time.Local().Format("2006-01-02")
+		for _, t := range []*costTotal{&s.costTotal, totals.repo(s.Repo), totals.day(day)} {
+			t.Usage = t.Usage.Add(inc.usage)
+			t.CostUSD += cost
+		}
+		s.Models[inc.model] = s.Models[inc.model].Add(inc.usage)
+		if f != nil {
+			fmt.Fprintf(f, "[%s] session=%s repo=%s model=%s tokens=%d cost=%.4f\n",
+				inc.time.Local().Format("2006-01-02 15:04:05"), session, s.Repo, inc.model, inc.usage.Total(), cost)
+		}
+	}
+	if f != nil {
+		f.Close()
+	}
+	s.Updated = time.Now()
+
+	var warnings []string
+	warnAt := policy.WarnAt
+	if warnAt <= 0 {
+		warnAt = defaultWarnAt
+	}
+	if w := budgetWarning("Session", &s.costTotal, policy.SessionBudget, warnAt); w != "" {
+		warnings = append(warnings, w)
+	}
+	if w := budgetWarning("Today's", totals.day(time.Now().Format("2006-01-02")), policy.DailyBudget, warnAt); w != "" {
+		warnings = append(warnings, w)
 	}
+	saveCostTotals(logDir, totals)
 
-	tokens := estimateTokens(input)
-	logFile := filepath.Join(logDir, "cost.log")
+	if len(warnings) == 0 {
+		return reply("")
+	}
+	return reply("[Cost] " + strings.Join(warnings, " "))
+}
 
-	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// costIncrement is usage reported by one response.
+type costIncrement struct {
+	model string
+	usage transcript.Usage
+	time  time.Time
+}
+
+// readTranscript returns the usage added to the transcript since the last
+// read and advances the offset past the last complete line. Claude Code
+// repeats a response's usage on each of its lines, so a response split
+// across two reads is counted once.
+func (s *sessionCost) readTranscript(path string) ([]costIncrement, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return Allow(), 0
+		return nil, err
 	}
 	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < s.Offset {
+		// Rewritten under us; counting it again would double the totals.
+		s.Offset = info.Size()
+		return nil, nil
+	}
+	if _, err := f.Seek(s.Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return nil, nil
+	}
+	data = data[:end+1]
+	s.Offset += int64(len(data))
+
+	t, _ := transcript.Parse(bytes.NewReader(data))
+	var out []costIncrement
+	for _, m := range t.Messages {
+		if m.Role != "assistant" || m.Usage.Total() == 0 {
+			continue
+		}
+		u := m.Usage
+		if m.ID != "" && m.ID == s.LastMessageID {
+			u = s.LastUsage.Max(m.Usage)
+			u, s.LastUsage = u.Sub(s.LastUsage), u
+		} else {
+			s.LastMessageID, s.LastUsage = m.ID, m.Usage
+		}
+		if u.Total() == 0 {
+			continue
+		}
+		at := m.Time
+		if at.IsZero() {
+			at = time.Now()
+		}
+		model := m.Model
+		if model == "" {
+			model = "unknown"
+		}
+		out = append(out, costIncrement{model: model, usage: u, time: at})
+	}
+	return out, nil
+}
+
+// modelPrice finds the price for model: the longest matching key in prices,
+// then in the built-in table.
+func modelPrice(model string, prices map[string]ModelPrice) (ModelPrice, bool) {
+	for _, table := range []map[string]ModelPrice{prices, defaultModelPrices} {
+		best := ""
+		for key := range table {
+			if strings.HasPrefix(model, key) && len(key) > len(best) {
+				best = key
+			}
+		}
+		if best != "" {
+			return table[best], true
+		}
+	}
+	return ModelPrice{}, false
+}
+
+func (p ModelPrice) cost(u transcript.Usage) float64 {
+	cacheWrite, cacheRead := p.CacheWrite, p.CacheRead
+	if cacheWrite == 0 {
+		cacheWrite = p.Input * 1.25
+	}
+	if cacheRead == 0 {
+		cacheRead = p.Input * 0.1
+	}
+	return (float64(u.InputTokens)*p.Input +
+		float64(u.OutputTokens)*p.Output +
+		float64(u.CacheCreationInputTokens)*cacheWrite +
+		float64(u.CacheReadInputTokens)*cacheRead) / 1e6
+}
+
+// budgetWarning returns a warning the first time t reaches warnAt of budget,
+// and a notice the first time it exceeds budget.
+func budgetWarning(label string, t *costTotal, budget, warnAt float64) string {
+	switch {
+	case budget <= 0:
+		return ""
+	case t.CostUSD >= budget && !t.Exceeded:
+		t.Exceeded, t.Warned = true, true
+		return fmt.Sprintf("%s cost $%.2f has exceeded its $%.2f budget; further tool calls will be blocked.", label, t.CostUSD, budget)
+	case t.CostUSD >= budget*warnAt && !t.Warned:
+		t.Warned = true
+		return fmt.Sprintf("%s cost is $%.2f, %.0f%% of its $%.2f budget.", label, t.CostUSD, 100*t.CostUSD/budget, budget)
+	}
+	return ""
+}
+
+func (t *costTotals) repo(name string) *costTotal {
+	if t.Repos[name] == nil {
+		t.Repos[name] = &costTotal{}
+	}
+	return t.Repos[name]
+}
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	fmt.Fprintf(f, "[%s] tool=%s tokens=%d\n", timestamp, input.ToolName, tokens)
+func (t *costTotals) day(date string) *costTotal {
+	if t.Days[date] == nil {
+		t.Days[date] = &costTotal{}
+	}
+	return t.Days[date]
+}
 
-	return Allow(), 0
+func loadCostTotals(dir string) *costTotals {
+	t := &costTotals{}
+	if data, err := os.ReadFile(filepath.Join(dir, costTotalsFile)); err == nil {
+		json.Unmarshal(data, t)
+	}
+	if t.Sessions == nil {
+		t.Sessions = map[string]*sessionCost{}
+	}
+	if t.Repos == nil {
+		t.Repos = map[string]*costTotal{}
+	}
+	if t.Days == nil {
+		t.Days = map[string]*costTotal{}
+	}
+	return t
+}
+
+// saveCostTotals writes the totals, dropping sessions idle for a month.
+func saveCostTotals(dir string, t *costTotals) {
+	for id, s := range t.Sessions {
+		if time.Since(s.Updated) > costSessionTTL {
+			delete(t.Sessions, id)
+		}
+	}
+	data, _ := json.MarshalIndent(t, "", "  ")
+	writeFileAtomic(filepath.Join(dir, costTotalsFile), data)
 }
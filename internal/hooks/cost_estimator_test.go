@@ -1,50 +1,196 @@
 package hooks
 
 import (
+	"encoding/json"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
-func TestCostEstimator_TracksTokens(t *testing.T) {
-	dir := t.TempDir()
+// usageLine is an assistant transcript line reporting token usage.
+func usageLine(id, model string, in, out, cacheWrite, cacheRead int) string {
+	return `{"type":"assistant","timestamp":"` + time.Now().UTC().Format(time.RFC3339) + `","message":{"id":"` + id + `","role":"assistant","model":"` + model +
+		`","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":` + itoa(in) + `,"output_tokens":` + itoa(out) +
+		`,"cache_creation_input_tokens":` + itoa(cacheWrite) + `,"cache_read_input_tokens":` + itoa(cacheRead) + `}}}`
+}
+
+func itoa(n int) string {
+	b, _ := json.Marshal(n)
+	return string(b)
+}
 
-	CostEstimator(shellInput("ls -la"), dir)
-	CostEstimator(writeInput("main.go", "package main\nfunc main() {}"), dir)
+func costInput(tool, transcriptPath, session string) HookInput {
+	ti, _ := json.Marshal(map[string]string{"transcript_path": transcriptPath, "session_id": session, "cwd": "/work/myrepo"})
+	return HookInput{ToolName: tool, ToolInput: ti}
+}
 
-	logFile := filepath.Join(dir, "cost.log")
-	data, err := os.ReadFile(logFile)
+func appendLines(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		t.Fatalf("expected cost.log: %v", err)
+		t.Fatal(err)
 	}
+	f.WriteString(strings.Join(lines, "\n") + "\n")
+	f.Close()
+}
 
-	log := string(data)
-	if !strings.Contains(log, "tokens") {
-		t.Errorf("log missing token info, got: %s", log)
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestCostEstimator_NoTranscript(t *testing.T) {
+	dir := t.TempDir()
+	result, code := CostEstimator(shellInput("ls"), dir)
+	if code != 0 || result.Decision != "allow" {
+		t.Errorf("expected allow, got %+v code=%d", result, code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, costTotalsFile)); err == nil {
+		t.Error("nothing should be recorded without a transcript")
 	}
 }
 
-func TestCostEstimator_AlwaysAllows(t *testing.T) {
+func TestCostEstimator_PricesUsage(t *testing.T) {
 	dir := t.TempDir()
-	result, code := CostEstimator(shellInput("ls"), dir)
-	if code != 0 {
-		t.Errorf("expected exit 0, got %d", code)
+	tr := filepath.Join(t.TempDir(), "t.jsonl")
+	// One response streamed over two lines with the same id counts once.
+	appendLines(t, tr,
+		usageLine("m1", "claude-sonnet-4-20250514", 1000, 100, 2000, 10000),
+		usageLine("m1", "claude-sonnet-4-20250514", 1000, 100, 2000, 10000),
+		usageLine("m2", "claude-opus-4-1-20250805", 10, 1000, 0, 0),
+	)
+
+	result, code := CostEstimator(costInput("Stop", tr, "s1"), dir)
+	if code != 0 || result.Decision != "" {
+		t.Fatalf("expected NoOp on stop, got %+v", result)
+	}
+	totals := loadCostTotals(dir)
+	s := totals.Sessions["s1"]
+	if s == nil {
+		t.Fatal("session not recorded")
+	}
+	// sonnet: 1000*3 + 100*15 + 2000*3.75 + 10000*0.3 = 15000; opus: 10*15 + 1000*75 = 75150
+	if want := (15000.0 + 75150.0) / 1e6; !approx(s.CostUSD, want) {
+		t.Errorf("session cost = %v, want %v", s.CostUSD, want)
+	}
+	if s.Usage.InputTokens != 1010 || s.Usage.CacheReadInputTokens != 10000 {
+		t.Errorf("usage = %+v", s.Usage)
+	}
+	if s.Repo != "myrepo" || totals.Repos["myrepo"] == nil || !approx(totals.Repos["myrepo"].CostUSD, s.CostUSD) {
+		t.Errorf("repo totals = %+v", totals.Repos)
+	}
+	if d := totals.Days[time.Now().Format("2006-01-02")]; d == nil || !approx(d.CostUSD, s.CostUSD) {
+		t.Errorf("day totals = %+v", totals.Days)
 	}
-	if result.Decision != "allow" {
-		t.Errorf("expected allow, got %q", result.Decision)
+	log, _ := os.ReadFile(filepath.Join(dir, costLogFile))
+	if !strings.Contains(string(log), "model=claude-opus-4-1-20250805 tokens=1010") {
+		t.Errorf("cost.log = %s", log)
 	}
 }
 
-func TestCostEstimator_AccumulatesAcrossCalls(t *testing.T) {
+func TestCostEstimator_Incremental(t *testing.T) {
 	dir := t.TempDir()
+	tr := filepath.Join(t.TempDir(), "t.jsonl")
+	appendLines(t, tr, usageLine("m1", "claude-haiku-4-5", 100, 10, 0, 0))
+	CostEstimator(costInput("Shell", tr, "s1"), dir)
+	// The same response continues on a later line with more output tokens.
+	appendLines(t, tr, usageLine("m1", "claude-haiku-4-5", 100, 50, 0, 0), usageLine("m2", "claude-haiku-4-5", 200, 20, 0, 0))
+	CostEstimator(costInput("Shell", tr, "s1"), dir)
+	CostEstimator(costInput("Stop", tr, "s1"), dir)
 
-	CostEstimator(shellInput("go test ./..."), dir)
-	CostEstimator(shellInput("go build ./..."), dir)
+	s := loadCostTotals(dir).Sessions["s1"]
+	if s.Usage.InputTokens != 300 || s.Usage.OutputTokens != 70 {
+		t.Errorf("usage = %+v, want 300 in / 70 out", s.Usage)
+	}
+}
 
-	data, _ := os.ReadFile(filepath.Join(dir, "cost.log"))
-	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
-	if len(lines) < 2 {
-		t.Errorf("expected at least 2 log entries, got %d", len(lines))
+func TestCostEstimator_PricesFromPolicy(t *testing.T) {
+	dir := t.TempDir()
+	tr := filepath.Join(t.TempDir(), "t.jsonl")
+	appendLines(t, tr, usageLine("m1", "my-local-model", 1000000, 0, 0, 0), usageLine("m2", "mystery", 5, 5, 0, 0))
+	CostEstimatorWithPolicy(costInput("Stop", tr, "s1"), dir, &CostPolicy{Prices: map[string]ModelPrice{"my-local": {Input: 2}}})
+
+	s := loadCostTotals(dir).Sessions["s1"]
+	if !approx(s.CostUSD, 2) {
+		t.Errorf("cost = %v, want 2", s.CostUSD)
+	}
+	if len(s.Unpriced) != 1 || s.Unpriced[0] != "mystery" {
+		t.Errorf("unpriced = %v", s.Unpriced)
+	}
+}
+
+func TestCostEstimator_BudgetWarning(t *testing.T) {
+	dir := t.TempDir()
+	tr := filepath.Join(t.TempDir(), "t.jsonl")
+	policy := &CostPolicy{SessionBudget: 1}
+	appendLines(t, tr, usageLine("m1", "claude-sonnet-4", 0, 60000, 0, 0)) // $0.90
+	result, _ := CostEstimatorWithPolicy(costInput("Shell", tr, "s1"), dir, policy)
+	if result.Decision != "allow" || !strings.Contains(result.Message, "90% of its $1.00 budget") {
+		t.Errorf("expected warning, got %+v", result)
+	}
+
+	appendLines(t, tr, usageLine("m2", "claude-sonnet-4", 0, 1000, 0, 0))
+	result, _ = CostEstimatorWithPolicy(costInput("Shell", tr, "s1"), dir, policy)
+	if result.Message != "" {
+		t.Errorf("warning should be given once, got %q", result.Message)
+	}
+
+	// Going over the budget is reported even though the warning was given
+	appendLines(t, tr, usageLine("m3", "claude-sonnet-4", 0, 10000, 0, 0))
+	result, _ = CostEstimatorWithPolicy(costInput("Shell", tr, "s1"), dir, policy)
+	if !strings.Contains(result.Message, "has exceeded its $1.00 budget") {
+		t.Errorf("expected the over-budget notice, got %+v", result)
+	}
+	appendLines(t, tr, usageLine("m4", "claude-sonnet-4", 0, 1000, 0, 0))
+	result, _ = CostEstimatorWithPolicy(costInput("Shell", tr, "s1"), dir, policy)
+	if result.Message != "" {
+		t.Errorf("over-budget notice should be given once, got %q", result.Message)
+	}
+}
+
+func TestCostEstimator_ConcurrentRuns(t *testing.T) {
+	dir := t.TempDir()
+	const sessions = 8
+	var wg sync.WaitGroup
+	for i := 0; i < sessions; i++ {
+		tr := filepath.Join(t.TempDir(), "t.jsonl")
+		appendLines(t, tr, usageLine("m1", "claude-haiku-4-5", 100, 10, 0, 0))
+		wg.Add(1)
+		go func(session string) {
+			defer wg.Done()
+			CostEstimator(costInput("Shell", tr, session), dir)
+		}("s" + itoa(i))
+	}
+	wg.Wait()
+
+	totals := loadCostTotals(dir)
+	if len(totals.Sessions) != sessions {
+		t.Errorf("recorded %d sessions, want %d", len(totals.Sessions), sessions)
+	}
+	if d := totals.Days[time.Now().Format("2006-01-02")]; d == nil || d.Usage.InputTokens != 100*sessions {
+		t.Errorf("day totals = %+v, want %d input tokens", d, 100*sessions)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(matches) > 0 || exists(filepath.Join(dir, costTotalsFile+".lock")) {
+		t.Errorf("temp or lock files left behind: %v", matches)
+	}
+}
+
+func TestModelPrice(t *testing.T) {
+	tests := []struct {
+		model string
+		input float64
+		ok    bool
+	}{
+		{"claude-opus-4-5-20251101", 5, true},
+		{"claude-opus-4-1-20250805", 15, true},
+		{"claude-3-5-haiku-20241022", 0.8, true},
+		{"gpt-5", 0, false},
+	}
+	for _, tt := range tests {
+		p, ok := modelPrice(tt.model, nil)
+		if ok != tt.ok || p.Input != tt.input {
+			t.Errorf("modelPrice(%q) = %+v, %v", tt.model, p, ok)
+		}
 	}
 }
@@ -0,0 +1,377 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic is one finding from a linter or type checker, normalized across
+// tools. Hooks attach them to HookResult.Diagnostics for other tools to read.
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Rule     string `json:"rule,omitempty"`
+	Severity string `json:"severity"` // error, warning or info
+	Message  string `json:"message"`
+	Source   string `json:"source"` // the tool that reported it
+	Fixable  bool   `json:"fixable,omitempty"`
+}
+
+// Output formats parseDiagnostics understands.
+const (
+	formatLines      = ""                 // file:line[:col]: message, one per line
+	formatESLint     = "eslint-json"      // eslint -f json
+	formatRuff       = "ruff-json"        // ruff check --output-format=json
+	formatShellcheck = "shellcheck-json1" // shellcheck -f json1
+	formatGolangci   = "golangci-json"    // golangci-lint --output.json.path=stdout
+	formatTsc        = "tsc"              // tsc --pretty false
+	formatPyright    = "pyright-json"     // pyright --outputjson
+)
+
+// maxDiagnosticsShown caps the list in a deny reason.
+const maxDiagnosticsShown = 20
+
+// parseDiagnostics turns tool output in the given format into diagnostics.
+// Relative file names are resolved against dir. ok is false when the output
+// could not be parsed, so callers can fall back to showing it raw.
+func parseDiagnostics(format, output, dir, source string) ([]Diagnostic, bool) {
+	var diags []Diagnostic
+	var ok bool
+	switch format {
+	case formatESLint:
+		diags, ok = parseESLintJSON(output)
+	case formatRuff:
+		diags, ok = parseRuffJSON(output)
+	case formatShellcheck:
+		diags, ok = parseShellcheckJSON(output)
+	case formatGolangci:
+		diags, ok = parseGolangciJSON(output)
+	case formatPyright:
+		diags, ok = parsePyrightJSON(output)
+	case formatTsc:
+		diags = parseTscDiagnostics(output)
+		ok = len(diags) > 0
+	default:
+		diags = parseDiagnosticLines(output)
+		ok = len(diags) > 0
+	}
+	for i := range diags {
+		if diags[i].File != "" && !filepath.IsAbs(diags[i].File) && dir != "" {
+			diags[i].File = filepath.Join(dir, diags[i].File)
+		}
+		if diags[i].Severity == "" {
+			diags[i].Severity = "error"
+		}
+		if diags[i].Source == "" {
+			diags[i].Source = source
+		}
+	}
+	return diags, ok
+}
+
+func parseESLintJSON(output string) ([]Diagnostic, bool) {
+	var files []struct {
+		FilePath string `json:"filePath"`
+		Messages []struct {
+			RuleID   string          `json:"ruleId"`
+			Severity int             `json:"severity"`
+			Message  string          `json:"message"`
+			Line     int             `json:"line"`
+			Column   int             `json:"column"`
+			Fix      json.RawMessage `json:"fix"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal([]byte(jsonPayload(output)), &files); err != nil {
+		return nil, false
+	}
+	var diags []Diagnostic
+	for _, f := range files {
+		for _, m := range f.Messages {
+			sev := "warning"
+			if m.Severity >= 2 {
+				sev = "error"
+			}
+			diags = append(diags, Diagnostic{File: f.FilePath, Line: m.Line, Column: m.Column, Rule: m.RuleID, Severity: sev, Message: m.Message, Fixable: len(m.Fix) > 0 && string(m.Fix) != "null"})
+		}
+	}
+	return diags, true
+}
+
+func parseRuffJSON(output string) ([]Diagnostic, bool) {
+	var items []struct {
+		Code     string          `json:"code"`
+		Message  string          `json:"message"`
+		Filename string          `json:"filename"`
+		Fix      json.RawMessage `json:"fix"`
+		Location struct {
+			Row    int `json:"row"`
+			Column int `json:"column"`
+		} `json:"location"`
+	}
+	if err := json.Unmarshal([]byte(jsonPayload(output)), &items); err != nil {
+		return nil, false
+	}
+	var diags []Diagnostic
+	for _, it := range items {
+		diags = append(diags, Diagnostic{File: it.Filename, Line: it.Location.Row, Column: it.Location.Column, Rule: it.Code, Severity: "error", Message: it.Message, Fixable: len(it.Fix) > 0 && string(it.Fix) != "null"})
+	}
+	return diags, true
+}
+
+func parseShellcheckJSON(output string) ([]Diagnostic, bool) {
+	var report struct {
+		Comments []struct {
+			File    string          `json:"file"`
+			Line    int             `json:"line"`
+			Column  int             `json:"column"`
+			Level   string          `json:"level"`
+			Code    int             `json:"code"`
+			Message string          `json:"message"`
+			Fix     json.RawMessage `json:"fix"`
+		} `json:"comments"`
+	}
+	if err := json.Unmarshal([]byte(jsonPayload(output)), &report); err != nil {
+		return nil, false
+	}
+	var diags []Diagnostic
+	for _, c := range report.Comments {
+		sev := c.Level
+		if sev == "style" {
+			sev = "info"
+		}
+		diags = append(diags, Diagnostic{File: c.File, Line: c.Line, Column: c.Column, Rule: fmt.Sprintf("SC%d", c.Code), Severity: sev, Message: c.Message, Fixable: len(c.Fix) > 0 && string(c.Fix) != "null"})
+	}
+	return diags, true
+}
+
+func parseGolangciJSON(output string) ([]Diagnostic, bool) {
+	var report struct {
+		Issues []struct {
+			FromLinter  string          `json:"FromLinter"`
+			Text        string          `json:"Text"`
+			Severity    string          `json:"Severity"`
+			Replacement json.RawMessage `json:"Replacement"`
+			Pos         struct {
+				Filename string `json:"Filename"`
+				Line     int    `json:"Line"`
+				Column   int    `json:"Column"`
+			} `json:"Pos"`
+		} `json:"Issues"`
+	}
+	if err := json.Unmarshal([]byte(jsonPayload(output)), &report); err != nil {
+		return nil, false
+	}
+	var diags []Diagnostic
+	for _, is := range report.Issues {
+		diags = append(diags, Diagnostic{File: is.Pos.Filename, Line: is.Pos.Line, Column: is.Pos.Column, Rule: is.FromLinter, Severity: strings.ToLower(is.Severity), Message: is.Text, Fixable: len(is.Replacement) > 0 && string(is.Replacement) != "null"})
+	}
+	return diags, true
+}
+
+func parsePyrightJSON(output string) ([]Diagnostic, bool) {
+	var report struct {
+		GeneralDiagnostics []struct {
+			File     string `json:"file"`
+			Severity string `json:"severity"`
+			Message  string `json:"message"`
+			Rule     string `json:"rule"`
+			Range    struct {
+				Start struct {
+					Line      int `json:"line"`
+					Character int `json:"character"`
+				} `json:"start"`
+			} `json:"range"`
+		} `json:"generalDiagnostics"`
+	}
+	if err := json.Unmarshal([]byte(jsonPayload(output)), &report); err != nil {
+		return nil, false
+	}
+	var diags []Diagnostic
+	for _, d := range report.GeneralDiagnostics {
+		sev := d.Severity
+		if sev == "information" {
+			sev = "info"
+		}
+		// pyright positions are zero-based
+		diags = append(diags, Diagnostic{File: d.File, Line: d.Range.Start.Line + 1, Column: d.Range.Start.Character + 1, Rule: d.Rule, Severity: sev, Message: d.Message})
+	}
+	return diags, true
+}
+
+// tscLineRe matches tsc --pretty false output: file(line,col): error TS2322: message
+var tscLineRe = regexp.MustCompile(`^(.+?)\((\d+),(\d+)\): (error|warning|message) (TS\d+): (.*)$`)
+
+func parseTscDiagnostics(output string) []Diagnostic {
+	var diags []Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		m := tscLineRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		ln, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		sev := m[4]
+		if sev == "message" {
+			sev = "info"
+		}
+		diags = append(diags, Diagnostic{File: m[1], Line: ln, Column: col, Rule: m[5], Severity: sev, Message: m[6]})
+	}
+	return diags
+}
+
+var (
+	// diagLineRe matches the common file:line[:col][:] message shape (go vet,
+	// staticcheck, yamllint -f parsable, markdownlint, hadolint, clippy short).
+	diagLineRe = regexp.MustCompile(`^([^\s:][^:]*):(\d+)(?::(\d+))?:?\s+(.+)$`)
+	// diagSeverityRe picks a leading severity: "error: ", "[warning] ",
+	// "warning - ", or rustc's "error[E0308]: " with its code.
+	diagSeverityRe = regexp.MustCompile(`^\[?(error|warning|warn|info|note|style)\]?(?:\[([\w-]+)\])?:?\s*-?\s*`)
+	// diagLeadingRuleRe picks a leading rule code such as DL3006 or MD013/line-length.
+	diagLeadingRuleRe = regexp.MustCompile(`^([A-Z]{1,4}\d{2,5}(?:/[\w-]+)*)\s+`)
+	// diagTrailingRuleRe picks a trailing rule such as (SA4006), (line-length)
+	// or mypy's [assignment].
+	diagTrailingRuleRe = regexp.MustCompile(`\s+(?:\(([\w-]+)\)|\[([\w-]+)\])$`)
+)
+
+func parseDiagnosticLines(output string) []Diagnostic {
+	var diags []Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		m := diagLineRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		d := Diagnostic{File: m[1], Message: m[4]}
+		d.Line, _ = strconv.Atoi(m[2])
+		d.Column, _ = strconv.Atoi(m[3])
+		if r := diagLeadingRuleRe.FindStringSubmatch(d.Message); r != nil {
+			d.Rule = r[1]
+			d.Message = d.Message[len(r[0]):]
+		}
+		if s := diagSeverityRe.FindStringSubmatch(d.Message); s != nil {
+			d.Severity = normalizeSeverity(s[1])
+			d.Message = d.Message[len(s[0]):]
+			if s[2] != "" && d.Rule == "" {
+				d.Rule = s[2]
+			}
+		}
+		if r := diagTrailingRuleRe.FindStringSubmatch(d.Message); r != nil && d.Rule == "" {
+			d.Rule = r[1] + r[2]
+			d.Message = strings.TrimSpace(d.Message[:len(d.Message)-len(r[0])])
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+func normalizeSeverity(s string) string {
+	switch s {
+	case "warn":
+		return "warning"
+	case "note", "style":
+		return "info"
+	}
+	return s
+}
+
+// jsonPayload drops anything a tool prints before its JSON document (npx
+// notices, progress lines).
+func jsonPayload(output string) string {
+	output = strings.TrimSpace(output)
+	if i := strings.IndexAny(output, "[{"); i > 0 {
+		return output[i:]
+	}
+	return output
+}
+
+// diagnosticsFor keeps the diagnostics reported for file.
+func diagnosticsFor(diags []Diagnostic, file string) []Diagnostic {
+	var out []Diagnostic
+	for _, d := range diags {
+		if d.File == file || sameFile(d.File, file) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func sameFile(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	ra, errA := filepath.EvalSymlinks(a)
+	rb, errB := filepath.EvalSymlinks(b)
+	return errA == nil && errB == nil && ra == rb
+}
+
+// dedupeDiagnostics drops repeats and sorts by file and position.
+func dedupeDiagnostics(diags []Diagnostic) []Diagnostic {
+	seen := map[string]bool{}
+	var out []Diagnostic
+	for _, d := range diags {
+		key := fmt.Sprintf("%s:%d:%d:%s:%s", d.File, d.Line, d.Column, d.Rule, d.Message)
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, d)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].File != out[j].File {
+			return out[i].File < out[j].File
+		}
+		if out[i].Line != out[j].Line {
+			return out[i].Line < out[j].Line
+		}
+		return out[i].Column < out[j].Column
+	})
+	return out
+}
+
+// formatDiagnostics renders diags as indented "file:line:col severity rule: message"
+// lines, paths relative to dir, at most maxDiagnosticsShown of them.
+func formatDiagnostics(diags []Diagnostic, dir string) string {
+	var b strings.Builder
+	for i, d := range diags {
+		if i == maxDiagnosticsShown {
+			fmt.Fprintf(&b, "\n    ... %d more", len(diags)-i)
+			break
+		}
+		file := d.File
+		if rel, err := filepath.Rel(dir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+		pos := file
+		if d.Line > 0 {
+			pos += ":" + strconv.Itoa(d.Line)
+			if d.Column > 0 {
+				pos += ":" + strconv.Itoa(d.Column)
+			}
+		}
+		rule := ""
+		if d.Rule != "" {
+			rule = " " + d.Rule
+		}
+		fix := ""
+		if d.Fixable {
+			fix = " (fixable)"
+		}
+		fmt.Fprintf(&b, "\n    %s %s%s: %s%s", pos, d.Severity, rule, d.Message, fix)
+	}
+	return b.String()
+}
+
+// countFixable returns how many diags a tool can fix automatically.
+func countFixable(diags []Diagnostic) int {
+	n := 0
+	for _, d := range diags {
+		if d.Fixable {
+			n++
+		}
+	}
+	return n
+}
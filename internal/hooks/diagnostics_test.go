@@ -0,0 +1,137 @@
+package hooks
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		output string
+		want   []Diagnostic
+	}{
+		{
+			"eslint",
+			formatESLint,
+			`[{"filePath":"/p/a.ts","messages":[{"ruleId":"no-unused-vars","severity":2,"message":"'x' is unused","line":3,"column":7},{"ruleId":"semi","severity":1,"message":"Missing semicolon","line":4,"column":2,"fix":{"range":[1,2],"text":";"}}]}]`,
+			[]Diagnostic{
+				{File: "/p/a.ts", Line: 3, Column: 7, Rule: "no-unused-vars", Severity: "error", Message: "'x' is unused", Source: "tool"},
+				{File: "/p/a.ts", Line: 4, Column: 2, Rule: "semi", Severity: "warning", Message: "Missing semicolon", Source: "tool", Fixable: true},
+			},
+		},
+		{
+			"ruff",
+			formatRuff,
+			`[{"code":"F401","message":"os imported but unused","filename":"/p/a.py","location":{"row":1,"column":8},"fix":{"applicability":"safe"}}]`,
+			[]Diagnostic{{File: "/p/a.py", Line: 1, Column: 8, Rule: "F401", Severity: "error", Message: "os imported but unused", Source: "tool", Fixable: true}},
+		},
+		{
+			"shellcheck",
+			formatShellcheck,
+			`{"comments":[{"file":"run.sh","line":2,"column":6,"level":"warning","code":2086,"message":"Double quote to prevent globbing","fix":null}]}`,
+			[]Diagnostic{{File: "/p/run.sh", Line: 2, Column: 6, Rule: "SC2086", Severity: "warning", Message: "Double quote to prevent globbing", Source: "tool"}},
+		},
+		{
+			"golangci",
+			formatGolangci,
+			`{"Issues":[{"FromLinter":"errcheck","Text":"Error return value is not checked","Severity":"","Pos":{"Filename":"pkg/a.go","Line":10,"Column":4}}]}`,
+			[]Diagnostic{{File: "/p/pkg/a.go", Line: 10, Column: 4, Rule: "errcheck", Severity: "error", Message: "Error return value is not checked", Source: "tool"}},
+		},
+		{
+			"tsc",
+			formatTsc,
+			"src/a.ts(3,5): error TS2322: Type 'string' is not assignable to type 'number'.\n",
+			[]Diagnostic{{File: "/p/src/a.ts", Line: 3, Column: 5, Rule: "TS2322", Severity: "error", Message: "Type 'string' is not assignable to type 'number'.", Source: "tool"}},
+		},
+		{
+			"go vet",
+			formatLines,
+			"# example.com/x\n./a.go:5:2: fmt.Printf format %d has arg s of wrong type string\n",
+			[]Diagnostic{{File: "/p/a.go", Line: 5, Column: 2, Severity: "error", Message: "fmt.Printf format %d has arg s of wrong type string", Source: "tool"}},
+		},
+		{
+			"staticcheck",
+			formatLines,
+			"a.go:7:3: this value of err is never used (SA4006)",
+			[]Diagnostic{{File: "/p/a.go", Line: 7, Column: 3, Rule: "SA4006", Severity: "error", Message: "this value of err is never used", Source: "tool"}},
+		},
+		{
+			"yamllint",
+			formatLines,
+			"ci.yml:4:81: [warning] line too long (82 > 80 characters) (line-length)",
+			[]Diagnostic{{File: "/p/ci.yml", Line: 4, Column: 81, Rule: "line-length", Severity: "warning", Message: "line too long (82 > 80 characters)", Source: "tool"}},
+		},
+		{
+			"hadolint",
+			formatLines,
+			"Dockerfile:3 DL3006 warning: Always tag the version of an image explicitly",
+			[]Diagnostic{{File: "/p/Dockerfile", Line: 3, Rule: "DL3006", Severity: "warning", Message: "Always tag the version of an image explicitly", Source: "tool"}},
+		},
+		{
+			"markdownlint",
+			formatLines,
+			"README.md:1 MD041/first-line-heading First line in a file should be a top-level heading",
+			[]Diagnostic{{File: "/p/README.md", Line: 1, Rule: "MD041/first-line-heading", Severity: "error", Message: "First line in a file should be a top-level heading", Source: "tool"}},
+		},
+		{
+			"mypy",
+			formatLines,
+			"app/a.py:3:5: error: Incompatible types in assignment  [assignment]\napp/a.py:3:5: note: See docs",
+			[]Diagnostic{
+				{File: "/p/app/a.py", Line: 3, Column: 5, Rule: "assignment", Severity: "error", Message: "Incompatible types in assignment", Source: "tool"},
+				{File: "/p/app/a.py", Line: 3, Column: 5, Severity: "info", Message: "See docs", Source: "tool"},
+			},
+		},
+		{
+			"cargo check",
+			formatLines,
+			"src/main.rs:2:13: error[E0308]: mismatched types",
+			[]Diagnostic{{File: "/p/src/main.rs", Line: 2, Column: 13, Rule: "E0308", Severity: "error", Message: "mismatched types", Source: "tool"}},
+		},
+		{
+			"pyright",
+			formatPyright,
+			`{"generalDiagnostics":[{"file":"/p/a.py","severity":"error","message":"Cannot access attribute","rule":"reportAttributeAccessIssue","range":{"start":{"line":4,"character":2}}}]}`,
+			[]Diagnostic{{File: "/p/a.py", Line: 5, Column: 3, Rule: "reportAttributeAccessIssue", Severity: "error", Message: "Cannot access attribute", Source: "tool"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseDiagnostics(tt.format, tt.output, "/p", "tool")
+			if !ok {
+				t.Fatalf("output not parsed")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := parseDiagnostics(formatESLint, "Oops! Something went wrong!", "/p", "eslint"); ok {
+		t.Error("non-JSON eslint output should not parse")
+	}
+}
+
+func TestFormatDiagnostics(t *testing.T) {
+	diags := dedupeDiagnostics([]Diagnostic{
+		{File: "/p/a.go", Line: 9, Severity: "error", Message: "b"},
+		{File: "/p/a.go", Line: 2, Column: 4, Rule: "R1", Severity: "warning", Message: "a", Fixable: true},
+		{File: "/p/a.go", Line: 9, Severity: "error", Message: "b"},
+	})
+	got := formatDiagnostics(diags, "/p")
+	want := "\n    a.go:2:4 warning R1: a (fixable)\n    a.go:9 error: b"
+	if got != want {
+		t.Errorf("formatDiagnostics = %q, want %q", got, want)
+	}
+
+	var many []Diagnostic
+	for i := 0; i < maxDiagnosticsShown+5; i++ {
+		many = append(many, Diagnostic{File: "/p/a.go", Line: i + 1, Severity: "error", Message: "x"})
+	}
+	if out := formatDiagnostics(many, "/p"); !strings.HasSuffix(out, "... 5 more") {
+		t.Errorf("expected the list to be capped, got %q", out)
+	}
+}
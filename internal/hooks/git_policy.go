@@ -0,0 +1,409 @@
+package hooks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GitPolicyConfig configures GitPolicy (policies.gitPolicy in config.yaml).
+type GitPolicyConfig struct {
+	// Rules maps a rule id to "deny", "ask" or "off"; unlisted rules keep their default.
+	Rules map[string]string `yaml:"rules,omitempty" json:"rules,omitempty"`
+	// SharedBranches are names or globs that must not be rewritten with an
+	// interactive rebase (default main, master, develop, release/*).
+	SharedBranches []string `yaml:"sharedBranches,omitempty" json:"sharedBranches,omitempty"`
+}
+
+var defaultSharedBranches = []string{"main", "master", "develop", "release/*"}
+
+// gitCall is one git invocation found in a Shell command.
+type gitCall struct {
+	Dir     string // directory git runs in, after cd and git -C
+	Sub     string
+	Args    []string
+	Flags   map[string]bool
+	Ops     []string
+	Command string // the rendered sub-command, for messages
+}
+
+// gitRule is one destructive git operation GitPolicy knows about.
+type gitRule struct {
+	ID       string
+	Severity string // default severity: deny or ask
+	// Match describes the operation when the rule applies to gc, or returns "".
+	Match func(gc gitCall, shared []string) string
+	// Loss describes what would be lost, or "" when nothing can be measured.
+	Loss func(gc gitCall) string
+}
+
+var gitRules = []gitRule{
+	{ID: "force-push", Severity: "deny", Match: matchForcePush},
+	{ID: "reset-hard", Severity: "deny", Match: matchResetHard, Loss: uncommittedLoss},
+	{ID: "clean", Severity: "deny", Match: matchClean, Loss: cleanLoss},
+	{ID: "discard-changes", Severity: "deny", Match: matchDiscardChanges, Loss: discardLoss},
+	{ID: "stash-drop", Severity: "ask", Match: matchStashDrop, Loss: stashLoss},
+	{ID: "branch-force-delete", Severity: "ask", Match: matchBranchForceDelete, Loss: branchLoss},
+	{ID: "history-rewrite", Severity: "deny", Match: matchHistoryRewrite, Loss: historyLoss},
+	{ID: "rebase-shared", Severity: "deny", Match: matchRebaseShared},
+	{ID: "no-verify", Severity: "deny", Match: matchNoVerify},
+	{ID: "amend-pushed", Severity: "deny", Match: matchAmendPushed, Loss: amendLoss},
+	{ID: "tag-delete", Severity: "ask", Match: matchTagDelete},
+}
+
+// GitPolicy is a preToolUse hook that blocks (or asks before) destructive git
+// operations: force pushes, reset --hard, clean -f, discarding changes, dropping
+// stashes, force-deleting branches, history rewrites and more.
+func GitPolicy(input HookInput, workDir string) (HookResult, int) {
+	return GitPolicyWithConfig(input, workDir, nil)
+}
+
+// GitPolicyWithConfig runs GitPolicy with per-rule severities from cfg. When a
+// command matches several rules, deny wins over ask.
+func GitPolicyWithConfig(input HookInput, workDir string, cfg *GitPolicyConfig) (HookResult, int) {
+	if input.ToolName != "Shell" {
+		return Allow(), 0
+	}
+
+	cmd := input.Command()
+	if cmd == "" || !strings.Contains(cmd, "git") {
+		return Allow(), 0
+	}
+	if cfg == nil {
+		cfg = &GitPolicyConfig{}
+	}
+	shared := cfg.SharedBranches
+	if len(shared) == 0 {
+		shared = defaultSharedBranches
+	}
+
+	var ask string
+	for _, gc := range gitCalls(cmd, workDir) {
+		for _, rule := range gitRules {
+			severity := rule.Severity
+			if v, ok := cfg.Rules[rule.ID]; ok {
+				severity = strings.ToLower(strings.TrimSpace(v))
+			}
+			if severity == "off" {
+				continue
+			}
+			desc := rule.Match(gc, shared)
+			if desc == "" {
+				continue
+			}
+			loss := ""
+			if rule.Loss != nil {
+				loss = rule.Loss(gc)
+			}
+			if severity == "ask" {
+				if ask == "" {
+					ask = gitPolicyReason("Confirm: ", desc, gc.Command, loss, rule.ID, "deny or off")
+				}
+				continue
+			}
+			return Deny(gitPolicyReason("Blocked: ", desc, gc.Command, loss, rule.ID, "ask or off")), 2
+		}
+	}
+
+	if ask != "" {
+		return Ask(ask), 0
+	}
+	return Allow(), 0
+}
+
+func gitPolicyReason(prefix, desc, command, loss, ruleID, alternatives string) string {
+	var reason strings.Builder
+	reason.WriteString(prefix + desc)
+	reason.WriteString("\n  Command: " + command)
+	if loss != "" {
+		reason.WriteString("\n  Would lose: " + loss)
+	}
+	reason.WriteString("\n  Rule: " + ruleID + " (set policies.gitPolicy.rules." + ruleID + " to " + alternatives + " in config.yaml)")
+	return reason.String()
+}
+
+// gitCalls returns every git invocation in cmd with the directory it runs in.
+func gitCalls(cmd, workDir string) []gitCall {
+	var out []gitCall
+	dir := workDir
+	for _, c := range shellCommands(cmd) {
+		args := commandArgs(c.Args)
+		switch commandName(args) {
+		case "cd":
+			if len(args) > 1 {
+				dir = joinDir(dir, args[1])
+			}
+		case "git":
+			gitDir, sub, subArgs := gitInvocation(dir, args[1:])
+			flags, ops := splitGitArgs(subArgs, gitValueFlags[sub])
+			out = append(out, gitCall{Dir: gitDir, Sub: sub, Args: subArgs, Flags: flags, Ops: ops, Command: c.String()})
+		case "git-filter-repo":
+			out = append(out, gitCall{Dir: dir, Sub: "filter-repo", Args: args[1:], Flags: map[string]bool{}, Command: c.String()})
+		}
+	}
+	return out
+}
+
+func matchForcePush(gc gitCall, _ []string) string {
+	if gc.Sub != "push" {
+		return ""
+	}
+	if gc.Flags["-f"] || gc.Flags["--force"] {
+		return "force push rewrites remote history (use --force-with-lease)"
+	}
+	for _, op := range gc.Ops {
+		if strings.HasPrefix(op, "+") {
+			return "force push via +refspec rewrites remote history (use --force-with-lease)"
+		}
+	}
+	return ""
+}
+
+func matchResetHard(gc gitCall, _ []string) string {
+	if gc.Sub == "reset" && gc.Flags["--hard"] {
+		return "git reset --hard discards uncommitted changes"
+	}
+	return ""
+}
+
+func matchClean(gc gitCall, _ []string) string {
+	if gc.Sub != "clean" || !(gc.Flags["-f"] || gc.Flags["--force"]) || gc.Flags["-n"] || gc.Flags["--dry-run"] {
+		return ""
+	}
+	if gc.Flags["-x"] || gc.Flags["-X"] {
+		return "git clean deletes untracked files, including ignored ones"
+	}
+	return "git clean deletes untracked files"
+}
+
+func matchDiscardChanges(gc gitCall, _ []string) string {
+	switch gc.Sub {
+	case "checkout":
+		if gc.Flags["-f"] || gc.Flags["--force"] {
+			return "git checkout --force discards uncommitted changes"
+		}
+		if hasArg(gc.Args, "--") || hasArg(gc.Ops, ".") {
+			return "git checkout of paths overwrites uncommitted changes"
+		}
+	case "restore":
+		staged := gc.Flags["--staged"] || gc.Flags["-S"]
+		worktree := gc.Flags["--worktree"] || gc.Flags["-W"]
+		if staged && !worktree {
+			return "" // only unstages
+		}
+		if gc.Flags["--source"] || gc.Flags["-s"] {
+			return "git restore --source overwrites files with another revision"
+		}
+		if len(gitRestorePaths(gc)) > 0 {
+			return "git restore discards uncommitted changes"
+		}
+	}
+	return ""
+}
+
+func matchStashDrop(gc gitCall, _ []string) string {
+	if gc.Sub != "stash" || len(gc.Ops) == 0 {
+		return ""
+	}
+	switch gc.Ops[0] {
+	case "drop":
+		return "git stash drop deletes a stash entry"
+	case "clear":
+		return "git stash clear deletes every stash entry"
+	}
+	return ""
+}
+
+func matchBranchForceDelete(gc gitCall, _ []string) string {
+	if gc.Sub != "branch" {
+		return ""
+	}
+	del := gc.Flags["-d"] || gc.Flags["--delete"]
+	if gc.Flags["-D"] || (del && (gc.Flags["-f"] || gc.Flags["--force"])) {
+		return "git branch -D deletes branches even if they are not merged"
+	}
+	return ""
+}
+
+func matchHistoryRewrite(gc gitCall, _ []string) string {
+	if gc.Sub == "filter-branch" || gc.Sub == "filter-repo" {
+		return "git " + gc.Sub + " rewrites the repository history"
+	}
+	return ""
+}
+
+func matchRebaseShared(gc gitCall, shared []string) string {
+	if gc.Sub != "rebase" || !(gc.Flags["-i"] || gc.Flags["--interactive"]) {
+		return ""
+	}
+	branch := gitOutput(gc.Dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if (branchGuardCheck{protected: shared}).isProtected(branch) {
+		return "interactive rebase rewrites shared branch '" + branch + "'"
+	}
+	return ""
+}
+
+func matchNoVerify(gc gitCall, _ []string) string {
+	switch gc.Sub {
+	case "commit", "push", "merge", "am", "rebase", "cherry-pick":
+		if gc.Flags["--no-verify"] || (gc.Sub == "commit" && gc.Flags["-n"]) {
+			return "--no-verify skips the repository's git hooks"
+		}
+	}
+	return ""
+}
+
+func matchAmendPushed(gc gitCall, _ []string) string {
+	if gc.Sub != "commit" || !gc.Flags["--amend"] {
+		return ""
+	}
+	if remotes := gitOutput(gc.Dir, "branch", "-r", "--contains", "HEAD"); remotes != "" {
+		return "amending a commit that is already pushed to " + strings.Join(strings.Fields(remotes), ", ")
+	}
+	return ""
+}
+
+func matchTagDelete(gc gitCall, _ []string) string {
+	switch gc.Sub {
+	case "tag":
+		if gc.Flags["-d"] || gc.Flags["--delete"] {
+			return "git tag -d deletes tags"
+		}
+	case "push":
+		deleting := gc.Flags["-d"] || gc.Flags["--delete"]
+		for i, op := range gc.Ops {
+			if i == 0 {
+				continue // remote
+			}
+			if strings.HasPrefix(op, ":refs/tags/") || (deleting && (op == "tag" || strings.HasPrefix(op, "refs/tags/"))) {
+				return "pushing a tag deletion removes it from the remote"
+			}
+		}
+	}
+	return ""
+}
+
+// uncommittedLoss reports tracked files with uncommitted changes.
+func uncommittedLoss(gc gitCall) string {
+	return describeFiles(gitLines(gc.Dir, "status", "--porcelain", "--untracked-files=no"), "file with uncommitted changes", "files with uncommitted changes")
+}
+
+// discardLoss reports the uncommitted changes in the paths being restored.
+func discardLoss(gc gitCall) string {
+	args := []string{"status", "--porcelain", "--untracked-files=no"}
+	if paths := gitRestorePaths(gc); len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	return describeFiles(gitLines(gc.Dir, args...), "file with uncommitted changes", "files with uncommitted changes")
+}
+
+// cleanLoss runs the same git clean as a dry run to list what it would delete.
+func cleanLoss(gc gitCall) string {
+	args := []string{"clean", "-n"}
+	for _, a := range gc.Args {
+		switch {
+		case a == "--force":
+		case strings.HasPrefix(a, "-") && !strings.HasPrefix(a, "--"):
+			// Drop -f but keep -d/-x/-X from combined flags such as -fdx
+			if rest := strings.ReplaceAll(a[1:], "f", ""); rest != "" {
+				args = append(args, "-"+rest)
+			}
+		default:
+			args = append(args, a)
+		}
+	}
+	var files []string
+	for _, line := range gitLines(gc.Dir, args...) {
+		files = append(files, strings.TrimPrefix(line, "Would remove "))
+	}
+	return describeFiles(files, "untracked path", "untracked paths")
+}
+
+func stashLoss(gc gitCall) string {
+	entries := gitLines(gc.Dir, "stash", "list")
+	if len(entries) == 0 {
+		return ""
+	}
+	if gc.Ops[0] == "clear" {
+		return describeFiles(entries, "stash entry", "stash entries")
+	}
+	ref := "stash@{0}"
+	if len(gc.Ops) > 1 {
+		ref = gc.Ops[1]
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e, ref+":") {
+			return e
+		}
+	}
+	return ""
+}
+
+func branchLoss(gc gitCall) string {
+	var parts []string
+	for _, b := range gc.Ops {
+		n := gitOutput(gc.Dir, "rev-list", "--count", "HEAD.."+b)
+		if n != "" && n != "0" {
+			parts = append(parts, n+" unmerged commit(s) on "+b)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func historyLoss(gc gitCall) string {
+	if n := gitOutput(gc.Dir, "rev-list", "--count", "--all"); n != "" {
+		return "original IDs of " + n + " commit(s) (every clone must be re-synced)"
+	}
+	return ""
+}
+
+func amendLoss(gc gitCall) string {
+	return gitOutput(gc.Dir, "log", "-1", "--format=%h %s", "HEAD") + " (already on the remote)"
+}
+
+// gitRestorePaths returns the pathspecs of a checkout/restore call.
+func gitRestorePaths(gc gitCall) []string {
+	if i := indexOf(gc.Args, "--"); i >= 0 {
+		return gc.Args[i+1:]
+	}
+	if gc.Sub == "restore" {
+		return gc.Ops
+	}
+	if hasArg(gc.Ops, ".") {
+		return []string{"."}
+	}
+	return nil
+}
+
+// gitLines runs git in dir and returns its non-empty output lines.
+func gitLines(dir string, args ...string) []string {
+	var lines []string
+	for _, line := range strings.Split(gitOutput(dir, args...), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// describeFiles summarizes a list as "N things (a, b, c, ...)".
+func describeFiles(files []string, singular, plural string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	noun := plural
+	if len(files) == 1 {
+		noun = singular
+	}
+	shown := files
+	if len(shown) > 5 {
+		shown = shown[:5]
+	}
+	for i, f := range shown {
+		shown[i] = strings.TrimSpace(f)
+	}
+	list := strings.Join(shown, ", ")
+	if len(files) > 5 {
+		list += ", ..."
+	}
+	return fmt.Sprintf("%d %s (%s)", len(files), noun, list)
+}
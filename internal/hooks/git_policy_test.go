@@ -0,0 +1,208 @@
+package hooks
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitPolicy_Rules(t *testing.T) {
+	dir := initGitRepo(t)
+
+	tests := []struct {
+		name     string
+		cmd      string
+		decision string
+		rule     string
+	}{
+		{"force push", "git push --force origin feature", "deny", "force-push"},
+		{"force push refspec", "git push origin +feature:feature", "deny", "force-push"},
+		{"force with lease", "git push --force-with-lease origin feature", "allow", ""},
+		{"reset hard", "git reset --hard HEAD~1", "deny", "reset-hard"},
+		{"reset soft", "git reset --soft HEAD~1", "allow", ""},
+		{"clean fdx", "git clean -fdx", "deny", "clean"},
+		{"clean dry run", "git clean -n -d", "allow", ""},
+		{"checkout dot", "git checkout .", "deny", "discard-changes"},
+		{"checkout paths", "git checkout -- src/", "deny", "discard-changes"},
+		{"checkout branch", "git checkout feature", "allow", ""},
+		{"restore source", "git restore --source HEAD~3 main.go", "deny", "discard-changes"},
+		{"restore file", "git restore main.go", "deny", "discard-changes"},
+		{"restore staged", "git restore --staged main.go", "allow", ""},
+		{"stash drop", "git stash drop", "ask", "stash-drop"},
+		{"stash clear", "git stash clear", "ask", "stash-drop"},
+		{"stash push", "git stash push -m wip", "allow", ""},
+		{"branch -D", "git branch -D old", "ask", "branch-force-delete"},
+		{"branch -d", "git branch -d merged", "allow", ""},
+		{"filter-branch", "git filter-branch --tree-filter 'rm x' HEAD", "deny", "history-rewrite"},
+		{"filter-repo", "git filter-repo --path secrets --invert-paths", "deny", "history-rewrite"},
+		{"no-verify commit", "git commit --no-verify -m x", "deny", "no-verify"},
+		{"commit -n", "git commit -n -m x", "deny", "no-verify"},
+		{"no-verify push", "git push --no-verify", "deny", "no-verify"},
+		{"tag delete", "git tag -d v1.0", "ask", "tag-delete"},
+		{"push tag delete", "git push origin :refs/tags/v1.0", "ask", "tag-delete"},
+		{"push --delete tag", "git push --delete origin tag v1.0", "ask", "tag-delete"},
+		{"deny beats ask", "git stash drop && git reset --hard", "deny", "reset-hard"},
+		{"status", "git status && git log", "allow", ""},
+		{"force push after heredoc message", "git commit -m \"$(cat <<'EOF'\nfix: it's ok\nEOF\n)\" && git push --force origin main", "deny", "force-push"},
+		{"not git", "echo git reset --hard", "allow", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := GitPolicy(shellInput(tt.cmd), dir)
+			if result.Decision != tt.decision {
+				t.Fatalf("expected %s for %q, got %s (%s)", tt.decision, tt.cmd, result.Decision, result.Reason)
+			}
+			wantCode := 0
+			if tt.decision == "deny" {
+				wantCode = 2
+			}
+			if code != wantCode {
+				t.Errorf("expected exit %d, got %d", wantCode, code)
+			}
+			if tt.rule != "" && !strings.Contains(result.Reason, "Rule: "+tt.rule) {
+				t.Errorf("expected rule %s in reason, got %q", tt.rule, result.Reason)
+			}
+		})
+	}
+}
+
+func TestGitPolicy_ConfigurableSeverity(t *testing.T) {
+	dir := initGitRepo(t)
+	cfg := &GitPolicyConfig{Rules: map[string]string{
+		"reset-hard": "ask",
+		"no-verify":  "off",
+		"stash-drop": "deny",
+	}}
+
+	tests := []struct {
+		cmd      string
+		decision string
+	}{
+		{"git reset --hard", "ask"},
+		{"git commit --no-verify -m x", "allow"},
+		{"git stash clear", "deny"},
+	}
+	for _, tt := range tests {
+		result, _ := GitPolicyWithConfig(shellInput(tt.cmd), dir, cfg)
+		if result.Decision != tt.decision {
+			t.Errorf("%q: expected %s, got %s", tt.cmd, tt.decision, result.Decision)
+		}
+	}
+}
+
+func TestGitPolicy_ReportsLoss(t *testing.T) {
+	dir := initGitRepo(t)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@test.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@test.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, contents string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.go", "package a\n")
+	write("b.go", "package b\n")
+	git("add", ".")
+	git("commit", "-m", "add files")
+	write("a.go", "package a // changed\n")
+	write("b.go", "package b // changed\n")
+	write("scratch.txt", "notes\n")
+	write(".gitignore", "*.log\n")
+	write("debug.log", "x\n")
+	git("checkout", "-b", "side")
+	git("commit", "--allow-empty", "-m", "side work")
+	git("checkout", "-")
+
+	tests := []struct {
+		name string
+		cmd  string
+		want string
+	}{
+		{"reset hard", "git reset --hard", "Would lose: 2 files with uncommitted changes"},
+		{"checkout one file", "git checkout -- a.go", "Would lose: 1 file with uncommitted changes (M a.go)"},
+		{"clean -fd", "git clean -fd", "scratch.txt"},
+		{"clean -fdx includes ignored", "git clean -fdx", "debug.log"},
+		{"branch -D", "git branch -D side", "1 unmerged commit(s) on side"},
+		{"-C into repo", "git -C " + dir + " reset --hard", "2 files with uncommitted changes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _ := GitPolicy(shellInput(tt.cmd), dir)
+			if !strings.Contains(result.Reason, tt.want) {
+				t.Errorf("expected reason to contain %q, got %q", tt.want, result.Reason)
+			}
+		})
+	}
+
+	t.Run("clean without -x keeps ignored", func(t *testing.T) {
+		result, _ := GitPolicy(shellInput("git clean -fd"), dir)
+		if strings.Contains(result.Reason, "debug.log") {
+			t.Errorf("git clean -fd does not remove ignored files, got %q", result.Reason)
+		}
+	})
+}
+
+func TestGitPolicy_RebaseShared(t *testing.T) {
+	dir := initGitRepo(t)
+	cmd := exec.Command("git", "branch", "-M", "main")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git branch -M failed: %v\n%s", err, out)
+	}
+
+	if result, code := GitPolicy(shellInput("git rebase -i HEAD~3"), dir); code != 2 {
+		t.Errorf("expected deny for interactive rebase on main, got %q", result.Decision)
+	}
+	cfg := &GitPolicyConfig{SharedBranches: []string{"develop"}}
+	if result, code := GitPolicyWithConfig(shellInput("git rebase -i HEAD~3"), dir, cfg); code != 0 {
+		t.Errorf("expected allow when main is not shared, got %q", result.Reason)
+	}
+}
+
+func TestGitPolicy_AmendPushed(t *testing.T) {
+	dir := initGitRepo(t)
+	remote := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--bare", remote},
+		{"-C", dir, "remote", "add", "origin", remote},
+		{"-C", dir, "push", "-q", "origin", "HEAD:refs/heads/feature"},
+		{"-C", dir, "fetch", "-q", "origin"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	result, code := GitPolicy(shellInput("git commit --amend --no-edit"), dir)
+	if code != 2 || !strings.Contains(result.Reason, "origin/feature") {
+		t.Errorf("expected deny naming origin/feature, got code=%d reason=%q", code, result.Reason)
+	}
+
+	commit := exec.Command("git", "-C", dir, "commit", "--allow-empty", "-m", "local")
+	commit.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@test.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@test.com")
+	if out, err := commit.CombinedOutput(); err != nil {
+		t.Fatalf("git commit failed: %v\n%s", err, out)
+	}
+	if result, code := GitPolicy(shellInput("git commit --amend --no-edit"), dir); code != 0 {
+		t.Errorf("expected allow when HEAD is not pushed, got %q", result.Reason)
+	}
+}
+
+func TestGitPolicy_PassthroughNonShell(t *testing.T) {
+	result, code := GitPolicy(writeInput("main.go", "git reset --hard"), "")
+	if code != 0 || result.Decision != "allow" {
+		t.Error("should passthrough non-Shell tools")
+	}
+}
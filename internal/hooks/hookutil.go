@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -64,10 +65,25 @@ func (h *HookInput) Pattern() string {
 
 // HookResult is the JSON output from a hook.
 type HookResult struct {
-	Decision    string `json:"decision,omitempty"`
-	Reason      string `json:"reason,omitempty"`
-	Message     string `json:"message,omitempty"`
-	LintCommand string `json:"lint_command,omitempty"`
+	Decision    string       `json:"decision,omitempty"`
+	Reason      string       `json:"reason,omitempty"`
+	Message     string       `json:"message,omitempty"`
+	LintCommand string       `json:"lint_command,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+
+	// Permission, UserMessage and AgentMessage are Cursor's permission
+	// fields; HookSpecificOutput is Claude Code's. Only Ask sets them.
+	Permission         string              `json:"permission,omitempty"`
+	UserMessage        string              `json:"userMessage,omitempty"`
+	AgentMessage       string              `json:"agentMessage,omitempty"`
+	HookSpecificOutput *HookSpecificOutput `json:"hookSpecificOutput,omitempty"`
+}
+
+// HookSpecificOutput is Claude Code's PreToolUse permission decision.
+type HookSpecificOutput struct {
+	HookEventName            string `json:"hookEventName"`
+	PermissionDecision       string `json:"permissionDecision"`
+	PermissionDecisionReason string `json:"permissionDecisionReason,omitempty"`
 }
 
 func Allow() HookResult {
@@ -93,6 +109,31 @@ func Deny(reason string) HookResult {
 	return HookResult{Decision: "deny", Reason: reason}
 }
 
+// Block returns a result that keeps the agent from ending its turn (Stop
+// hooks); reason tells it what is left to do. Hooks return it with exit code 0.
+func Block(reason string) HookResult {
+	return HookResult{Decision: "block", Reason: reason}
+}
+
+// Ask returns a result that has the agent ask the user before the action
+// runs: Cursor reads permission, Claude Code hookSpecificOutput, and the
+// OpenCode adapter, which cannot ask, treats decision "ask" as deny. Hooks
+// return it with exit code 0.
+func Ask(reason string) HookResult {
+	return HookResult{
+		Decision:     "ask",
+		Reason:       reason,
+		Permission:   "ask",
+		UserMessage:  reason,
+		AgentMessage: reason,
+		HookSpecificOutput: &HookSpecificOutput{
+			HookEventName:            "PreToolUse",
+			PermissionDecision:       "ask",
+			PermissionDecisionReason: reason,
+		},
+	}
+}
+
 // Prompt extracts the "prompt" field from tool_input (beforeSubmitPrompt).
 func (h *HookInput) Prompt() string {
 	var m map[string]interface{}
@@ -129,6 +170,15 @@ func (h *HookInput) FilePath() string {
 	return ""
 }
 
+// WritePath returns the file a Write, Edit or MultiEdit call changes: Write
+// names it "path", Edit and MultiEdit "file_path".
+func (h *HookInput) WritePath() string {
+	if p := h.Path(); p != "" {
+		return p
+	}
+	return h.FilePath()
+}
+
 // StopHookActive checks if "stop_hook_active" field is true in tool_input (Stop event).
 func (h *HookInput) StopHookActive() bool {
 	var m map[string]interface{}
@@ -178,6 +228,21 @@ func ReadInput(r io.Reader) (HookInput, error) {
 	return input, nil
 }
 
+// HookDir returns the directory named by env, or ~/.config/hooks/<name>.
+func HookDir(env, name string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "hooks", name)
+}
+
+// StateDir returns HOOK_STATE_DIR (default ~/.config/hooks/state), where
+// hooks keep per-session state.
+func StateDir() string {
+	return HookDir("HOOK_STATE_DIR", "state")
+}
+
 // IsHookDisabled returns true if name is listed in HOOK_DISABLED (comma-separated, trimmed).
 func IsHookDisabled(name string) bool {
 	v := os.Getenv("HOOK_DISABLED")
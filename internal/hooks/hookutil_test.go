@@ -1,7 +1,9 @@
 package hooks
 
 import (
+	"encoding/json"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -47,3 +49,48 @@ func TestIsHookDisabled_Empty(t *testing.T) {
 		t.Error("expected false when HOOK_DISABLED empty")
 	}
 }
+
+func TestAsk_JSON(t *testing.T) {
+	out, _ := json.Marshal(Ask("Delete branch feature?"))
+	want := `{"decision":"ask","reason":"Delete branch feature?","permission":"ask","userMessage":"Delete branch feature?","agentMessage":"Delete branch feature?",` +
+		`"hookSpecificOutput":{"hookEventName":"PreToolUse","permissionDecision":"ask","permissionDecisionReason":"Delete branch feature?"}}`
+	if string(out) != want {
+		t.Errorf("Ask JSON\n got %s\nwant %s", out, want)
+	}
+}
+
+func TestDeny_JSONHasNoPermissionFields(t *testing.T) {
+	out, _ := json.Marshal(Deny("Blocked: x"))
+	if string(out) != `{"decision":"deny","reason":"Blocked: x"}` {
+		t.Errorf("unexpected Deny JSON %s", out)
+	}
+}
+
+func TestWritePath(t *testing.T) {
+	tests := []struct {
+		input HookInput
+		want  string
+	}{
+		{writeInput("a.go", "package a"), "a.go"},
+		{toolInput("Edit", map[string]string{"file_path": "/repo/b.go"}), "/repo/b.go"},
+		{toolInput("MultiEdit", map[string]string{"file_path": "c.go"}), "c.go"},
+		{shellInput("ls"), ""},
+	}
+	for _, tt := range tests {
+		if got := tt.input.WritePath(); got != tt.want {
+			t.Errorf("%s WritePath() = %q, want %q", tt.input.ToolName, got, tt.want)
+		}
+	}
+}
+
+func TestStateDir(t *testing.T) {
+	t.Setenv("HOOK_STATE_DIR", "/tmp/hook-state")
+	if got := StateDir(); got != "/tmp/hook-state" {
+		t.Errorf("StateDir() = %q, want HOOK_STATE_DIR", got)
+	}
+	t.Setenv("HOOK_STATE_DIR", "")
+	home, _ := os.UserHomeDir()
+	if got, want := StateDir(), filepath.Join(home, ".config", "hooks", "state"); got != want {
+		t.Errorf("StateDir() = %q, want %q", got, want)
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"hooks/internal/transcript"
 )
 
 const (
@@ -38,7 +40,9 @@ type knowledgeUpdate struct {
 }
 
 // KnowledgeUpdate is a stop hook that extracts knowledge entities from session transcript
-// using OpenAI API and saves them to a knowledge graph.
+// using OpenAI API and saves them to a knowledge graph. The model sees a digest
+// of the conversation and tool calls rather than the raw transcript, and the
+// files created are taken from the transcript's Write calls.
 func KnowledgeUpdate(input HookInput, workDir string) (HookResult, int) {
 	transcriptPath := input.TranscriptPath()
 	if transcriptPath == "" {
@@ -68,17 +72,19 @@ func KnowledgeUpdate(input HookInput, workDir string) (HookResult, int) {
 		cwd = "."
 	}
 
-	// Read transcript
-	transcript, err := os.ReadFile(transcriptPath)
+	t, err := transcript.ParseFile(transcriptPath)
 	if err != nil {
 		return NoOp(), 0 // Fail silently
 	}
 
 	// Call OpenAI to extract entities
-	entities, err := extractEntitiesWithLLM(string(transcript), apiKey)
+	entities, err := extractEntitiesWithLLM(transcriptDigest(t), apiKey)
 	if err != nil {
 		return NoOp(), 0 // Fail silently
 	}
+	if t.Format != transcript.FormatText {
+		entities.FilesCreated = filesCreated(t)
+	}
 
 	// Skip if nothing interesting found
 	if len(entities.FilesCreated) == 0 &&
@@ -133,12 +139,12 @@ func KnowledgeUpdate(input HookInput, workDir string) (HookResult, int) {
 	return NoOpMsg(msg.String()), 0
 }
 
-func extractEntitiesWithLLM(transcript string, apiKey string) (knowledgeEntities, error) {
+func extractEntitiesWithLLM(digest string, apiKey string) (knowledgeEntities, error) {
 	var entities knowledgeEntities
 
-	// Truncate transcript if too long (keep last 8000 chars for context)
-	if len(transcript) > 8000 {
-		transcript = "..." + transcript[len(transcript)-8000:]
+	// Truncate digest if too long (keep last 8000 chars for context)
+	if len(digest) > 8000 {
+		digest = "..." + digest[len(digest)-8000:]
 	}
 
 	prompt := `Analyze the following development session transcript and extract knowledge entities in JSON format.
@@ -154,7 +160,7 @@ Return a JSON object with these fields:
 Only include entities that are actually mentioned or created in the transcript. Return only valid JSON, no markdown formatting.
 
 Transcript:
-` + transcript
+` + digest
 
 	// Get model from env or use default
 	model := os.Getenv("HOOK_KNOWLEDGE_UPDATE_MODEL")
@@ -268,6 +274,61 @@ Transcript:
 	return entities, nil
 }
 
+// transcriptDigest renders a transcript as the conversation plus one line per
+// tool call, which is what the model needs and far shorter than the JSONL.
+func transcriptDigest(t *transcript.Transcript) string {
+	if t.Format == transcript.FormatText {
+		return t.AssistantText()
+	}
+	calls := map[string]transcript.Call{}
+	for _, c := range t.Calls() {
+		calls[c.ID] = c
+	}
+	clip := func(s string) string {
+		s = strings.TrimSpace(s)
+		if len(s) > 500 {
+			s = s[:500] + "..."
+		}
+		return s
+	}
+
+	var b strings.Builder
+	for _, m := range t.Messages {
+		if m.Text != "" {
+			role := "User"
+			if m.Role == "assistant" {
+				role = "Assistant"
+			}
+			b.WriteString(role + ": " + clip(m.Text) + "\n")
+		}
+		for _, tc := range m.ToolCalls {
+			c := calls[tc.ID]
+			detail := c.Path()
+			if c.IsShell() {
+				detail = shortCommand(c.Command())
+			}
+			b.WriteString("Tool " + c.Name + ": " + detail)
+			if c.Failed() {
+				b.WriteString(" (failed)")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// filesCreated returns the files the session wrote whole (Write rather than
+// Edit), in order.
+func filesCreated(t *transcript.Transcript) []string {
+	var files []string
+	for _, c := range t.Calls() {
+		if (c.Name == "Write" || c.Name == "write") && c.Path() != "" && !hasString(files, c.Path()) {
+			files = append(files, c.Path())
+		}
+	}
+	return files
+}
+
 // repoNameFromCwd returns a directory-safe name for the repo (git top-level base or cwd base).
 func repoNameFromCwd(cwd string) string {
 	dir := cwd
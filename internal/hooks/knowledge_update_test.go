@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"hooks/internal/transcript"
 )
 
 func TestKnowledgeUpdate_NoTranscriptPath(t *testing.T) {
@@ -244,3 +246,29 @@ func TestKnowledgeUpdate_SummaryCounts(t *testing.T) {
 		t.Errorf("expected 1 component, got %d", update.Summary["components_added"])
 	}
 }
+
+func TestTranscriptDigest(t *testing.T) {
+	path := writeTranscript(t,
+		`{"type":"user","message":{"role":"user","content":"add a cache"}}`,
+		`{"type":"assistant","message":{"id":"m1","role":"assistant","content":[{"type":"text","text":"Adding it."},{"type":"tool_use","id":"t1","name":"Write","input":{"file_path":"cache.go","content":"package x"}}]}}`,
+		`{"type":"assistant","message":{"id":"m2","role":"assistant","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"go get golang.org/x/sync"}}]}}`,
+		`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t2","content":"no network","is_error":true}]}}`,
+		`{"type":"assistant","message":{"id":"m3","role":"assistant","content":[{"type":"tool_use","id":"t3","name":"Edit","input":{"file_path":"main.go"}}]}}`,
+	)
+	tr, err := transcript.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := transcriptDigest(tr)
+	for _, want := range []string{"User: add a cache", "Assistant: Adding it.", "Tool Write: cache.go", "Tool Bash: go get golang.org/x/sync (failed)"} {
+		if !strings.Contains(digest, want) {
+			t.Errorf("digest missing %q:\n%s", want, digest)
+		}
+	}
+	if strings.Contains(digest, "package x") {
+		t.Error("digest should not include file contents")
+	}
+	if files := filesCreated(tr); len(files) != 1 || files[0] != "cache.go" {
+		t.Errorf("filesCreated = %q", files)
+	}
+}
@@ -12,8 +12,15 @@ import (
 )
 
 // LintChanged is a postToolUse hook that runs the appropriate linter on changed files.
-// It detects biome, eslint, or ruff based on config files and runs them.
+// The linter is picked per file from linterRegistry (or user linters) based
+// on the file's extension and the config files found in the project.
 func LintChanged(input HookInput, workDir string) (HookResult, int) {
+	return LintChangedWithPolicy(input, workDir, nil)
+}
+
+// LintChangedWithPolicy runs LintChanged with user-defined and disabled
+// linters from policy; nil means built-ins only.
+func LintChangedWithPolicy(input HookInput, workDir string, policy *LintPolicy) (HookResult, int) {
 	if input.ToolName != "Write" {
 		return Allow(), 0
 	}
@@ -39,98 +46,100 @@ func LintChanged(input HookInput, workDir string) (HookResult, int) {
 		projectRoot = cwd
 	}
 
-	linter := detectLinter(projectRoot)
-	if linter == "" {
-		return Allow(), 0
+	var fixed []string
+	if policy != nil && policy.Autofix {
+		fixed = applyFixesOnce(projectRoot, absPath, policy)
+	}
+
+	linter, runDir, ok := linterFor(projectRoot, absPath, policy)
+	if !ok {
+		return autofixResult(path, fixed), 0
 	}
 
 	// Run linter with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	var cmd *exec.Cmd
-	switch linter {
-	case "biome":
-		cmd = exec.CommandContext(ctx, "biome", "check", absPath)
-	case "eslint":
-		cmd = exec.CommandContext(ctx, "eslint", absPath)
-	case "ruff":
-		cmd = exec.CommandContext(ctx, "ruff", "check", "--fix", "--exit-zero", absPath)
-	default:
-		return Deny("Unknown linter: " + linter), 2
-	}
-
-	cmd.Dir = projectRoot
+	args := linter.args(absPath, runDir)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = runDir
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
-	exitCode := cmd.ProcessState.ExitCode()
 
 	if err != nil {
 		// Check if it's a timeout
 		if ctx.Err() == context.DeadlineExceeded {
-			return Deny(fmt.Sprintf("%s timed out after 30 seconds", linter)), 2
+			return Deny(fmt.Sprintf("%s timed out after 30 seconds", linter.Name)), 2
 		}
 		// Check if command not found
 		if strings.Contains(err.Error(), "executable file not found") {
 			return Allow(), 0 // Fail open if linter not installed
 		}
 	}
+	if cmd.ProcessState == nil {
+		return Allow(), 0 // could not start; fail open
+	}
+	exitCode := cmd.ProcessState.ExitCode()
 
-	if exitCode != 0 {
-		fileName := filepath.Base(path)
-		var reason strings.Builder
-		reason.WriteString(fmt.Sprintf("%s found issues in %s (exit code %d)", linter, fileName, exitCode))
-		reason.WriteString("\n  Hints:")
-		reason.WriteString(fmt.Sprintf("\n    - Run '%s --fix %s' to auto-fix issues", linter, fileName))
-		reason.WriteString("\n    - Review the linter output below for details")
+	if linter.succeeded(exitCode) {
+		return autofixResult(path, fixed), 0
+	}
 
-		if stdout.Len() > 0 {
-			reason.WriteString("\n\n" + stdout.String())
+	fileName := filepath.Base(path)
+	output := stdout.String()
+	if linter.Format == formatLines {
+		output += "\n" + stderr.String()
+	}
+	if diags, ok := parseDiagnostics(linter.Format, output, runDir, linter.Name); ok {
+		// Report only what concerns the changed file; package-wide tools
+		// such as go vet also report its neighbours.
+		diags = dedupeDiagnostics(diagnosticsFor(diags, absPath))
+		if len(diags) == 0 {
+			return autofixResult(path, fixed), 0
 		}
-		if stderr.Len() > 0 {
-			reason.WriteString("\n" + stderr.String())
+		var reason strings.Builder
+		reason.WriteString(fmt.Sprintf("%s found %d issue(s) in %s", linter.Name, len(diags), fileName))
+		reason.WriteString(formatDiagnostics(diags, runDir))
+		reason.WriteString("\n  Hints:")
+		if len(fixed) > 0 {
+			reason.WriteString("\n    - " + autofixNote(fileName, fixed) + " The issues above could not be fixed automatically")
+		} else if n := countFixable(diags); n > 0 {
+			reason.WriteString(fmt.Sprintf("\n    - %d can be fixed automatically by %s (or set policies.lintChanged.autofix)", n, linter.Name))
 		}
-
-		return Deny(reason.String()), 2
+		reason.WriteString("\n    - Fix the issues listed above and save the file again")
+		result := Deny(reason.String())
+		result.Diagnostics = diags
+		return result, 2
 	}
 
-	return Allow(), 0
-}
-
-func detectLinter(projectRoot string) string {
-	// Check for biome
-	if exists(filepath.Join(projectRoot, "biome.json")) ||
-		exists(filepath.Join(projectRoot, "biome.jsonc")) {
-		if commandExists("biome") {
-			return "biome"
-		}
+	var reason strings.Builder
+	reason.WriteString(fmt.Sprintf("%s found issues in %s (exit code %d)", linter.Name, fileName, exitCode))
+	reason.WriteString("\n  Hints:")
+	reason.WriteString(fmt.Sprintf("\n    - Run '%s' to see the issues", strings.Join(args, " ")))
+	reason.WriteString("\n    - Review the linter output below for details")
+	if len(fixed) > 0 {
+		reason.WriteString("\n    - " + autofixNote(fileName, fixed))
 	}
 
-	// Check for eslint
-	if exists(filepath.Join(projectRoot, ".eslintrc.js")) ||
-		exists(filepath.Join(projectRoot, ".eslintrc.json")) ||
-		exists(filepath.Join(projectRoot, ".eslintrc.cjs")) ||
-		exists(filepath.Join(projectRoot, ".eslintrc.yaml")) ||
-		exists(filepath.Join(projectRoot, ".eslintrc.yml")) ||
-		exists(filepath.Join(projectRoot, "eslint.config.js")) ||
-		exists(filepath.Join(projectRoot, "eslint.config.mjs")) {
-		if commandExists("eslint") {
-			return "eslint"
-		}
+	if stdout.Len() > 0 {
+		reason.WriteString("\n\n" + stdout.String())
 	}
-
-	// Check for ruff
-	if exists(filepath.Join(projectRoot, "ruff.toml")) ||
-		exists(filepath.Join(projectRoot, "pyproject.toml")) {
-		if commandExists("ruff") {
-			return "ruff"
-		}
+	if stderr.Len() > 0 {
+		reason.WriteString("\n" + stderr.String())
 	}
 
-	return ""
+	return Deny(reason.String()), 2
+}
+
+// autofixResult allows the write, telling the agent when fixers changed the file.
+func autofixResult(path string, fixed []string) HookResult {
+	if len(fixed) == 0 {
+		return Allow()
+	}
+	return AllowMsg(autofixNote(filepath.Base(path), fixed))
 }
 
 func exists(path string) bool {
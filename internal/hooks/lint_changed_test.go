@@ -1,6 +1,7 @@
 package hooks
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -34,64 +35,136 @@ func TestLintChanged_NonexistentFile(t *testing.T) {
 	}
 }
 
-func TestDetectLinter_Biome(t *testing.T) {
+func TestLinterFor_Biome(t *testing.T) {
 	dir := t.TempDir()
 	biomeConfig := filepath.Join(dir, "biome.json")
 	os.WriteFile(biomeConfig, []byte("{}"), 0644)
 
-	linter := detectLinter(dir)
-	if linter != "biome" && commandExists("biome") {
-		t.Errorf("expected biome when biome.json exists and command available, got %q", linter)
+	linter, _, _ := linterFor(dir, filepath.Join(dir, "a.ts"), nil)
+	if linter.Name != "biome" && commandExists("biome") {
+		t.Errorf("expected biome when biome.json exists and command available, got %q", linter.Name)
 	}
 }
 
-func TestDetectLinter_ESLint(t *testing.T) {
+func TestLinterFor_ESLint(t *testing.T) {
 	dir := t.TempDir()
 	eslintConfig := filepath.Join(dir, ".eslintrc.json")
 	os.WriteFile(eslintConfig, []byte("{}"), 0644)
 
-	linter := detectLinter(dir)
-	if linter != "eslint" && commandExists("eslint") {
-		t.Errorf("expected eslint when .eslintrc.json exists and command available, got %q", linter)
+	linter, _, _ := linterFor(dir, filepath.Join(dir, "a.ts"), nil)
+	if linter.Name != "eslint" && commandExists("eslint") {
+		t.Errorf("expected eslint when .eslintrc.json exists and command available, got %q", linter.Name)
 	}
 }
 
-func TestDetectLinter_Ruff(t *testing.T) {
+func TestLinterFor_Ruff(t *testing.T) {
 	dir := t.TempDir()
 	ruffConfig := filepath.Join(dir, "ruff.toml")
 	os.WriteFile(ruffConfig, []byte(""), 0644)
 
-	linter := detectLinter(dir)
-	if linter != "ruff" && commandExists("ruff") {
-		t.Errorf("expected ruff when ruff.toml exists and command available, got %q", linter)
+	linter, _, _ := linterFor(dir, filepath.Join(dir, "a.py"), nil)
+	if linter.Name != "ruff" && commandExists("ruff") {
+		t.Errorf("expected ruff when ruff.toml exists and command available, got %q", linter.Name)
 	}
 }
 
-func TestDetectLinter_NoConfig(t *testing.T) {
+func TestLinterFor_NoConfig(t *testing.T) {
 	dir := t.TempDir()
-	linter := detectLinter(dir)
-	if linter != "" {
-		t.Errorf("expected empty linter when no config exists, got %q", linter)
+	for _, f := range []string{"a.ts", "a.py", "a.go", "a.rs", "a.tf"} {
+		if linter, _, ok := linterFor(dir, filepath.Join(dir, f), nil); ok {
+			t.Errorf("expected no linter for %s without config, got %q", f, linter.Name)
+		}
+	}
+}
+
+func TestLinterFor_PerExtension(t *testing.T) {
+	// A Python config must not make Go files go through ruff
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "pyproject.toml"), []byte(""), 0644)
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "pkg", "sub"), 0755)
+
+	linter, runDir, ok := linterFor(dir, filepath.Join(dir, "pkg", "sub", "a.go"), nil)
+	if !ok || linter.Name != "go vet" {
+		t.Fatalf("expected go vet for a .go file, got %q", linter.Name)
+	}
+	if got := strings.Join(linter.args(filepath.Join(dir, "pkg", "sub", "a.go"), runDir), " "); got != "go vet ./pkg/sub" {
+		t.Errorf("go vet args = %q", got)
 	}
 }
 
-func TestDetectLinter_Priority(t *testing.T) {
+func TestLinterFor_NearestConfig(t *testing.T) {
+	// Monorepo: the linter runs where its config lives
 	dir := t.TempDir()
-	// Create multiple configs - biome should take priority
-	os.WriteFile(filepath.Join(dir, "biome.json"), []byte("{}"), 0644)
-	os.WriteFile(filepath.Join(dir, ".eslintrc.json"), []byte("{}"), 0644)
-	os.WriteFile(filepath.Join(dir, "ruff.toml"), []byte(""), 0644)
-
-	linter := detectLinter(dir)
-	// Biome should be detected first (checked first)
-	if linter != "biome" && commandExists("biome") {
-		// If biome not available, check others
-		if linter == "" {
-			t.Log("No linters available, skipping priority test")
+	fakeLinter(t, "yamllint", 0, "")
+	os.MkdirAll(filepath.Join(dir, "deploy"), 0755)
+	os.WriteFile(filepath.Join(dir, "deploy", ".yamllint"), []byte(""), 0644)
+
+	_, runDir, ok := linterFor(dir, filepath.Join(dir, "deploy", "app.yaml"), nil)
+	if !ok || runDir != filepath.Join(dir, "deploy") {
+		t.Errorf("expected yamllint to run in deploy/, got ok=%v dir=%q", ok, runDir)
+	}
+	if _, _, ok := linterFor(dir, filepath.Join(dir, "other.yaml"), nil); ok {
+		t.Error("config in deploy/ should not apply to files outside it")
+	}
+}
+
+func TestLinterFor_Dockerfile(t *testing.T) {
+	fakeLinter(t, "hadolint", 0, "")
+	dir := t.TempDir()
+	for _, f := range []string{"Dockerfile", "Dockerfile.dev", "api.dockerfile"} {
+		if linter, _, ok := linterFor(dir, filepath.Join(dir, f), nil); !ok || linter.Name != "hadolint" {
+			t.Errorf("expected hadolint for %s, got %q", f, linter.Name)
 		}
 	}
 }
 
+func TestLintChanged_UserLinter(t *testing.T) {
+	fakeLinter(t, "protolint", 1, "api.proto:3: missing comment")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "api.proto")
+	os.WriteFile(file, []byte("syntax = \"proto3\";"), 0644)
+
+	policy := &LintPolicy{Linters: []LinterConfig{{Name: "protolint", Extensions: []string{".proto"}, Command: "protolint lint {file}"}}}
+	result, code := LintChangedWithPolicy(writeInput(file, ""), dir, policy)
+	if code != 2 || !strings.Contains(result.Reason, "protolint found 1 issue") || !strings.Contains(result.Reason, "api.proto:3 error: missing comment") {
+		t.Errorf("expected protolint deny, got code=%d %q", code, result.Reason)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].File != file || result.Diagnostics[0].Line != 3 {
+		t.Errorf("expected one diagnostic for %s line 3, got %+v", file, result.Diagnostics)
+	}
+
+	// Issues in other files (package-wide tools) do not block this write
+	fakeLinter(t, "protolint", 1, "other.proto:3: missing comment")
+	if _, code := LintChangedWithPolicy(writeInput(file, ""), dir, policy); code != 0 {
+		t.Error("issues in other files should not block")
+	}
+
+	// Unparseable output falls back to the raw text
+	fakeLinter(t, "protolint", 1, "protolint: config error")
+	if result, code := LintChangedWithPolicy(writeInput(file, ""), dir, policy); code != 2 || !strings.Contains(result.Reason, "config error") {
+		t.Errorf("expected raw output fallback, got code=%d %q", code, result.Reason)
+	}
+
+	policy.Linters[0].SuccessCodes = []int{0, 1}
+	if _, code := LintChangedWithPolicy(writeInput(file, ""), dir, policy); code != 0 {
+		t.Error("exit code listed in successCodes should allow")
+	}
+
+	policy.Disable = []string{"protolint"}
+	policy.Linters[0].SuccessCodes = nil
+	if _, code := LintChangedWithPolicy(writeInput(file, ""), dir, policy); code != 0 {
+		t.Error("disabled linter should not run")
+	}
+}
+
+// fakeLinter puts an executable named name on PATH that prints output and
+// exits with code.
+func fakeLinter(t *testing.T, name string, code int, output string) {
+	t.Helper()
+	fakeTool(t, name, fmt.Sprintf("echo '%s'\nexit %d", output, code))
+}
+
 func TestLintChanged_NoLinterDetected(t *testing.T) {
 	dir := t.TempDir()
 	testFile := filepath.Join(dir, "test.ts")
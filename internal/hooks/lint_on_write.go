@@ -2,6 +2,7 @@ package hooks
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 )
 
@@ -21,6 +22,13 @@ var lintCommands = map[string]string{
 // LintOnWrite is a postToolUse hook that suggests a lint command after file writes.
 // Always exits 0 (informational only).
 func LintOnWrite(input HookInput) (HookResult, int) {
+	return LintOnWriteWithPolicy(input, "", nil)
+}
+
+// LintOnWriteWithPolicy is LintOnWrite with policies.lintChanged. With
+// autofix on it runs the file's formatters and fixers instead of suggesting a
+// command, and tells the agent when the file changed on disk.
+func LintOnWriteWithPolicy(input HookInput, workDir string, policy *LintPolicy) (HookResult, int) {
 	if input.ToolName != "Write" {
 		return Allow(), 0
 	}
@@ -30,6 +38,25 @@ func LintOnWrite(input HookInput) (HookResult, int) {
 		return Allow(), 0
 	}
 
+	if policy != nil && policy.Autofix {
+		absPath := path
+		if !filepath.IsAbs(path) && workDir != "" {
+			absPath = filepath.Join(workDir, path)
+		}
+		if _, err := os.Stat(absPath); err == nil {
+			root := workDir
+			if root == "" {
+				root = filepath.Dir(absPath)
+			}
+			if fixers, _ := fixersFor(root, absPath, policy); len(fixers) > 0 {
+				if fixed := applyFixesOnce(root, absPath, policy); len(fixed) > 0 {
+					return AllowMsg(autofixNote(filepath.Base(path), fixed)), 0
+				}
+				return Allow(), 0
+			}
+		}
+	}
+
 	ext := filepath.Ext(path)
 	if tmpl, ok := lintCommands[ext]; ok {
 		result := Allow()
@@ -0,0 +1,215 @@
+package hooks
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LinterConfig is a user-defined linter (policies.lintChanged.linters in config.yaml).
+type LinterConfig struct {
+	Name string `yaml:"name" json:"name"`
+	// Extensions are the file extensions it lints, with the dot (".proto").
+	Extensions []string `yaml:"extensions,omitempty" json:"extensions,omitempty"`
+	// Files are base-name globs for files without a telling extension ("Jenkinsfile").
+	Files []string `yaml:"files,omitempty" json:"files,omitempty"`
+	// ConfigFiles enable the linter when one exists between the file and the
+	// project root; empty means it runs whenever the command is installed.
+	ConfigFiles []string `yaml:"configFiles,omitempty" json:"configFiles,omitempty"`
+	// Command is a template; see linterSpec.Command for the placeholders.
+	Command string `yaml:"command" json:"command"`
+	// SuccessCodes are the exit codes that mean "no issues" (default [0]).
+	SuccessCodes []int `yaml:"successCodes,omitempty" json:"successCodes,omitempty"`
+	// Fix is an optional command template that fixes the file in place,
+	// run first when autofix is on.
+	Fix string `yaml:"fix,omitempty" json:"fix,omitempty"`
+	// Format is the output format: eslint-json, ruff-json, shellcheck-json1,
+	// golangci-json, tsc, or empty for file:line:col: message lines.
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+}
+
+// LintPolicy configures lint-changed (policies.lintChanged in config.yaml).
+type LintPolicy struct {
+	// Linters are tried before the built-in ones.
+	Linters []LinterConfig `yaml:"linters,omitempty" json:"linters,omitempty"`
+	// Disable turns built-in or user linters and fixers off by name.
+	Disable []string `yaml:"disable,omitempty" json:"disable,omitempty"`
+	// Autofix runs the formatters and fixers for a written file (gofmt,
+	// ruff --fix, eslint --fix, rustfmt, shfmt, terraform fmt, ...) before
+	// checking it, in lint-changed and lint-on-write.
+	Autofix bool `yaml:"autofix,omitempty" json:"autofix,omitempty"`
+}
+
+// linterSpec describes how to find and run one linter.
+type linterSpec struct {
+	Name  string
+	Exts  []string
+	Files []string // base-name globs (Dockerfile, *.dockerfile)
+	// Configs are looked up from the file's directory up to the project root;
+	// the directory holding the first one found is where the linter runs.
+	// Without Configs the linter runs from the project root when installed.
+	Configs []string
+	// Command is split on spaces and each word expanded: {file} absolute path,
+	// {dir} its directory, {root} the directory the linter runs in, {relfile}
+	// and {reldir} (./pkg, for Go-style package patterns) relative to it.
+	Command      string
+	SuccessCodes []int
+	Format       string // see parseDiagnostics
+}
+
+// linterRegistry lists the built-in linters. For each file the first match
+// whose config is present and whose command is installed wins, so more
+// specific linters come before general ones (golangci-lint before go vet,
+// eslint before prettier).
+var linterRegistry = []linterSpec{
+	{Name: "golangci-lint", Exts: []string{".go"}, Configs: []string{".golangci.yml", ".golangci.yaml", ".golangci.toml", ".golangci.json"}, Command: "golangci-lint run --output.json.path=stdout --show-stats=false {reldir}", Format: formatGolangci},
+	{Name: "staticcheck", Exts: []string{".go"}, Configs: []string{"staticcheck.conf"}, Command: "staticcheck {reldir}"},
+	{Name: "go vet", Exts: []string{".go"}, Configs: []string{"go.mod"}, Command: "go vet {reldir}"},
+	{Name: "clippy", Exts: []string{".rs"}, Configs: []string{"Cargo.toml"}, Command: "cargo clippy --quiet --message-format=short -- -D warnings"},
+	{Name: "biome", Exts: []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".json", ".jsonc"}, Configs: []string{"biome.json", "biome.jsonc"}, Command: "biome check {file}"},
+	{Name: "eslint", Exts: []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".vue"}, Configs: []string{".eslintrc.js", ".eslintrc.json", ".eslintrc.cjs", ".eslintrc.yaml", ".eslintrc.yml", "eslint.config.js", "eslint.config.mjs", "eslint.config.cjs", "eslint.config.ts"}, Command: "eslint -f json {file}", Format: formatESLint},
+	{Name: "ruff", Exts: []string{".py", ".pyi"}, Configs: []string{"ruff.toml", ".ruff.toml", "pyproject.toml"}, Command: "ruff check --output-format=json {file}", Format: formatRuff},
+	{Name: "shellcheck", Exts: []string{".sh", ".bash"}, Command: "shellcheck --severity=warning --exclude=SC1090,SC1091 -f json1 {file}", Format: formatShellcheck},
+	{Name: "yamllint", Exts: []string{".yml", ".yaml"}, Configs: []string{".yamllint", ".yamllint.yml", ".yamllint.yaml"}, Command: "yamllint -f parsable {file}"},
+	{Name: "hadolint", Files: []string{"Dockerfile", "Dockerfile.*", "*.dockerfile", "Containerfile"}, Command: "hadolint {file}"},
+	{Name: "tflint", Exts: []string{".tf"}, Configs: []string{".tflint.hcl"}, Command: "tflint --chdir={dir}"},
+	{Name: "markdownlint", Exts: []string{".md", ".markdown"}, Configs: []string{".markdownlint.json", ".markdownlint.jsonc", ".markdownlint.yaml", ".markdownlint.yml", ".markdownlintrc"}, Command: "markdownlint {file}"},
+	{Name: "prettier", Exts: []string{".js", ".jsx", ".ts", ".tsx", ".css", ".scss", ".less", ".html", ".vue", ".json", ".md", ".yml", ".yaml", ".graphql"}, Configs: []string{".prettierrc", ".prettierrc.json", ".prettierrc.yaml", ".prettierrc.yml", ".prettierrc.js", ".prettierrc.cjs", ".prettierrc.mjs", ".prettierrc.toml", "prettier.config.js", "prettier.config.cjs", "prettier.config.mjs"}, Command: "prettier --check {file}"},
+}
+
+// linterSpecs returns the user linters followed by the built-in ones, minus
+// the disabled names.
+func linterSpecs(policy *LintPolicy) []linterSpec {
+	var specs []linterSpec
+	disabled := map[string]bool{}
+	if policy != nil {
+		for _, d := range policy.Disable {
+			disabled[d] = true
+		}
+		for _, l := range policy.Linters {
+			if l.Command == "" {
+				continue
+			}
+			name := l.Name
+			if name == "" {
+				name = strings.Fields(l.Command)[0]
+			}
+			specs = append(specs, linterSpec{Name: name, Exts: l.Extensions, Files: l.Files, Configs: l.ConfigFiles, Command: l.Command, SuccessCodes: l.SuccessCodes, Format: l.Format})
+		}
+	}
+	specs = append(specs, linterRegistry...)
+	out := specs[:0]
+	for _, s := range specs {
+		if !disabled[s.Name] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// matches reports whether the linter handles file.
+func (s linterSpec) matches(file string) bool {
+	ext := filepath.Ext(file)
+	for _, e := range s.Exts {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	base := filepath.Base(file)
+	for _, g := range s.Files {
+		if ok, _ := path.Match(g, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// configDir returns the directory the linter should run in for file, or ""
+// when none of its config files exist between the file and root.
+func (s linterSpec) configDir(root, file string) string {
+	if len(s.Configs) == 0 {
+		return root
+	}
+	for _, dir := range dirsUpTo(filepath.Dir(file), root) {
+		for _, c := range s.Configs {
+			if exists(filepath.Join(dir, c)) {
+				return dir
+			}
+		}
+	}
+	return ""
+}
+
+// args expands the command template for file, run from runDir.
+func (s linterSpec) args(file, runDir string) []string {
+	dir := filepath.Dir(file)
+	relFile, err := filepath.Rel(runDir, file)
+	if err != nil {
+		relFile = file
+	}
+	relDir := "./" + filepath.ToSlash(filepath.Dir(relFile))
+	if relDir == "./." {
+		relDir = "."
+	}
+	r := strings.NewReplacer("{file}", file, "{dir}", dir, "{root}", runDir, "{relfile}", relFile, "{reldir}", relDir)
+	var args []string
+	for _, w := range strings.Fields(s.Command) {
+		args = append(args, r.Replace(w))
+	}
+	return args
+}
+
+// succeeded reports whether exit code means the linter found nothing.
+func (s linterSpec) succeeded(code int) bool {
+	if len(s.SuccessCodes) == 0 {
+		return code == 0
+	}
+	for _, c := range s.SuccessCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// linterFor picks the linter for file: the first spec that handles it, has a
+// config file in reach and is installed. It returns the spec and the
+// directory to run it in.
+func linterFor(root, file string, policy *LintPolicy) (linterSpec, string, bool) {
+	for _, s := range linterSpecs(policy) {
+		if !s.matches(file) {
+			continue
+		}
+		dir := s.configDir(root, file)
+		if dir == "" {
+			continue
+		}
+		if args := s.args(file, dir); len(args) == 0 || !commandExists(args[0]) {
+			continue
+		}
+		return s, dir, true
+	}
+	return linterSpec{}, "", false
+}
+
+// dirsUpTo lists dir and its parents up to and including root. A dir outside
+// root yields just dir and root.
+func dirsUpTo(dir, root string) []string {
+	dir = filepath.Clean(dir)
+	root = filepath.Clean(root)
+	if !isWithin(dir, root) {
+		return []string{dir, root}
+	}
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+		if dir == root {
+			return dirs
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dirs
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,327 @@
+package hooks
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"hooks/internal/transcript"
+)
+
+// LoopDetectorPolicy configures loop-detector (policies.loopDetector in
+// config.yaml).
+type LoopDetectorPolicy struct {
+	// WarnAfter is the attempt at which the agent is told to change approach
+	// (default 3).
+	WarnAfter int `yaml:"warnAfter,omitempty" json:"warnAfter,omitempty"`
+	// BlockAfter is the attempt that is denied (default 5; -1 never blocks).
+	BlockAfter int `yaml:"blockAfter,omitempty" json:"blockAfter,omitempty"`
+	// Window is how many recent tool calls are considered (default 40).
+	Window int `yaml:"window,omitempty" json:"window,omitempty"`
+}
+
+const (
+	loopLogFile           = "loop.jsonl"
+	loopTranscriptTail    = 512 * 1024
+	defaultLoopWarnAfter  = 3
+	defaultLoopBlockAfter = 5
+	defaultLoopWindow     = 40
+)
+
+// loopCall is the fingerprint of one tool call and, when known, its result.
+type loopCall struct {
+	Tool   string    `json:"tool"`
+	Input  string    `json:"input"`
+	Path   string    `json:"path,omitempty"`
+	Before string    `json:"before,omitempty"` // Edit: hash of the replaced text
+	After  string    `json:"after,omitempty"`  // Write: hash of the contents; Edit: of the new text
+	Result string    `json:"result,omitempty"`
+	Failed bool      `json:"failed,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// loopIgnoredFields don't change what a call does.
+var loopIgnoredFields = []string{"description", "timeout", "run_in_background", "explanation", "session_id", "cwd", "transcript_path"}
+
+// volatileOutputRe matches parts of tool output that change between otherwise
+// identical runs: numbers (durations, line counts, pids) and hex ids.
+var volatileOutputRe = regexp.MustCompile(`0x[0-9a-fA-F]+|[0-9a-f]{12,}|\d+(\.\d+)?`)
+
+// LoopDetector is a preToolUse hook that spots an agent going in circles:
+// the same call failing the same way again and again, the same call with the
+// same result repeated back to back, or a file edited back and forth between
+// versions. Calls and results come from the tail of the session transcript
+// (which also records denied calls); without a transcript it falls back to
+// the calls it has seen, kept per session under stateDir. At policy.WarnAfter
+// attempts it tells the agent to change approach; at policy.BlockAfter the
+// call is denied.
+func LoopDetector(input HookInput, stateDir string, policy *LoopDetectorPolicy) (HookResult, int) {
+	if input.ToolName == "" || stateDir == "" {
+		return Allow(), 0
+	}
+	p := LoopDetectorPolicy{WarnAfter: defaultLoopWarnAfter, BlockAfter: defaultLoopBlockAfter, Window: defaultLoopWindow}
+	if policy != nil {
+		if policy.WarnAfter > 0 {
+			p.WarnAfter = policy.WarnAfter
+		}
+		if policy.BlockAfter != 0 {
+			p.BlockAfter = policy.BlockAfter
+		}
+		if policy.Window > 0 {
+			p.Window = policy.Window
+		}
+	}
+	dir := SessionStateDir(stateDir, input.SessionID())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Allow(), 0
+	}
+
+	next := newLoopCall(input.ToolName, input.ToolInput)
+	history := loopLog(dir, p.Window)
+	appendLoopLog(dir, next, p.Window)
+	if calls := transcriptLoopCalls(input.TranscriptPath(), p.Window); calls != nil {
+		history = calls
+	}
+
+	attempt, what := detectLoop(history, next)
+	switch {
+	case p.BlockAfter > 0 && attempt >= p.BlockAfter:
+		return Deny(fmt.Sprintf("Blocked: %s (attempt %d). Repeating it will not help. Stop, work out why it keeps happening, and take a different approach or ask the user.", what, attempt)), 2
+	case attempt >= p.WarnAfter:
+		return AllowMsg(fmt.Sprintf("[Loop detector] %s (attempt %d). This looks like a loop: read the result carefully and change approach instead of retrying.", what, attempt)), 0
+	}
+	return Allow(), 0
+}
+
+// detectLoop returns which attempt next is in the longest pattern it
+// continues, and a description of the pattern.
+func detectLoop(history []loopCall, next loopCall) (int, string) {
+	attempt, what := 0, ""
+	label := loopLabel(next)
+
+	// The same call, whose last run failed, failing the same way each time.
+	var same []loopCall
+	for _, c := range history {
+		if c.Input == next.Input {
+			same = append(same, c)
+		}
+	}
+	if n := len(same); n > 0 && same[n-1].Failed && same[n-1].Result != "" {
+		failures := 0
+		for _, c := range same {
+			if c.Failed && c.Result == same[n-1].Result {
+				failures++
+			}
+		}
+		if failures+1 > attempt {
+			attempt, what = failures+1, fmt.Sprintf("%s has failed with the same error %d times", label, failures)
+		}
+	}
+
+	// The same call back to back with the same result (or unknown results).
+	run := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		c := history[i]
+		if c.Input != next.Input || c.Result != history[len(history)-1].Result {
+			break
+		}
+		run++
+	}
+	if run+1 > attempt {
+		attempt, what = run+1, fmt.Sprintf("%s has been repeated %d times in a row with the same result", label, run)
+	}
+
+	// A file going back to a version it had before.
+	if next.Path != "" && next.After != "" {
+		var writes []loopCall
+		for _, c := range history {
+			if c.Path == next.Path && c.After != "" {
+				writes = append(writes, c)
+			}
+		}
+		if reverts(writes, next) {
+			n := 1
+			for i := range writes {
+				if reverts(writes[:i], writes[i]) {
+					n++
+				}
+			}
+			if n+1 > attempt {
+				attempt, what = n+1, fmt.Sprintf("%s would go back to an earlier version again (%d reverts)", next.Path, n)
+			}
+		}
+	}
+	return attempt, what
+}
+
+// reverts reports whether write c restores a version of the file that earlier
+// writes replaced: a Write with contents seen before (other than the current
+// ones), or an Edit that undoes an earlier edit.
+func reverts(earlier []loopCall, c loopCall) bool {
+	current := ""
+	for _, e := range earlier {
+		if e.Before == "" {
+			current = e.After
+		}
+	}
+	for _, e := range earlier {
+		if c.Before == "" && e.Before == "" && e.After == c.After && c.After != current {
+			return true
+		}
+		if c.Before != "" && e.Before == c.After && e.After == c.Before {
+			return true
+		}
+	}
+	return false
+}
+
+// newLoopCall fingerprints a call. Tool names and input fields are normalized
+// so Cursor and Claude Code calls compare equal.
+func newLoopCall(tool string, raw json.RawMessage) loopCall {
+	var in map[string]interface{}
+	json.Unmarshal(raw, &in)
+	if in == nil {
+		in = map[string]interface{}{}
+	}
+	str := func(keys ...string) string {
+		for _, k := range keys {
+			if s, ok := in[k].(string); ok && s != "" {
+				return s
+			}
+		}
+		return ""
+	}
+
+	c := loopCall{Time: time.Now()}
+	switch tool {
+	case "Shell", "Bash", "run_terminal_cmd":
+		c.Tool = "Shell"
+		c.Input = "Shell\x00" + strings.Join(strings.Fields(str("command")), " ")
+		return c
+	case "Write", "write":
+		c.Tool = "Write"
+		c.Path = str("path", "file_path")
+		c.After = shortHash(str("contents", "content"))
+	case "Edit", "search_replace":
+		c.Tool = "Edit"
+		c.Path = str("path", "file_path")
+		c.Before, c.After = shortHash(str("old_string")), shortHash(str("new_string"))
+	default:
+		c.Tool = tool
+		c.Path = str("path", "file_path", "target_file")
+	}
+	for _, k := range loopIgnoredFields {
+		delete(in, k)
+	}
+	if p, ok := in["file_path"]; ok {
+		in["path"] = p
+		delete(in, "file_path")
+	}
+	if s, ok := in["content"]; ok {
+		in["contents"] = s
+		delete(in, "content")
+	}
+	data, _ := json.Marshal(in) // map keys are sorted
+	c.Input = c.Tool + "\x00" + string(data)
+	return c
+}
+
+func loopLabel(c loopCall) string {
+	if c.Tool == "Shell" {
+		return "`" + shortCommand(strings.TrimPrefix(c.Input, "Shell\x00")) + "`"
+	}
+	if c.Path != "" {
+		return c.Tool + " " + c.Path
+	}
+	return "This " + c.Tool + " call"
+}
+
+// transcriptLoopCalls returns the completed calls near the end of the
+// transcript, or nil when there is none to read.
+func transcriptLoopCalls(path string, window int) []loopCall {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() > loopTranscriptTail {
+		f.Seek(-loopTranscriptTail, io.SeekEnd)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil
+	}
+	t, _ := transcript.Parse(bytes.NewReader(data))
+	if t.Format == transcript.FormatText {
+		return nil
+	}
+	calls := []loopCall{}
+	for _, c := range t.Calls() {
+		if c.Result == nil {
+			continue
+		}
+		lc := newLoopCall(c.Name, c.Input)
+		lc.Result = shortHash(volatileOutputRe.ReplaceAllString(strings.Join(strings.Fields(c.Result.Content), " "), "N"))
+		lc.Failed = c.Result.IsError
+		lc.Time = c.Time
+		calls = append(calls, lc)
+	}
+	if len(calls) > window {
+		calls = calls[len(calls)-window:]
+	}
+	return calls
+}
+
+// loopLog returns the last window calls loop-detector has seen this session.
+func loopLog(dir string, window int) []loopCall {
+	f, err := os.Open(filepath.Join(dir, loopLogFile))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var calls []loopCall
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var c loopCall
+		if json.Unmarshal(scanner.Bytes(), &c) == nil {
+			calls = append(calls, c)
+		}
+	}
+	if len(calls) > window {
+		calls = calls[len(calls)-window:]
+	}
+	return calls
+}
+
+// appendLoopLog records c, trimming the log to the window now and then.
+func appendLoopLog(dir string, c loopCall, window int) {
+	path := filepath.Join(dir, loopLogFile)
+	data, _ := json.Marshal(c)
+	if info, err := os.Stat(path); err == nil && info.Size() > int64(window)*4096 {
+		calls := append(loopLog(dir, window), c)
+		var buf bytes.Buffer
+		for _, lc := range calls {
+			line, _ := json.Marshal(lc)
+			buf.Write(append(line, '\n'))
+		}
+		os.WriteFile(path, buf.Bytes(), 0644)
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(data, '\n'))
+}
@@ -0,0 +1,124 @@
+package hooks
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func loopInput(tool string, fields map[string]string) HookInput {
+	m := map[string]string{"session_id": "s1"}
+	for k, v := range fields {
+		m[k] = v
+	}
+	ti, _ := json.Marshal(m)
+	return HookInput{ToolName: tool, ToolInput: ti}
+}
+
+// bashCall returns transcript lines for a Bash call and its result.
+func bashCall(id, command, output string, failed bool) []string {
+	call, _ := json.Marshal(map[string]interface{}{"type": "assistant", "message": map[string]interface{}{
+		"id": "m" + id, "role": "assistant",
+		"content": []interface{}{map[string]interface{}{"type": "tool_use", "id": id, "name": "Bash", "input": map[string]string{"command": command, "description": "run " + id}}},
+	}})
+	result, _ := json.Marshal(map[string]interface{}{"type": "user", "message": map[string]interface{}{
+		"role":    "user",
+		"content": []interface{}{map[string]interface{}{"type": "tool_result", "tool_use_id": id, "content": output, "is_error": failed}},
+	}})
+	return []string{string(call), string(result)}
+}
+
+func TestLoopDetector_RepeatedFailure(t *testing.T) {
+	var lines []string
+	for i, out := range []string{"FAIL TestX (0.12s)", "ok", "FAIL TestX (0.31s)"} {
+		id := string(rune('a' + i))
+		lines = append(lines, bashCall(id, "go  test ./...", out, out != "ok")...)
+		lines = append(lines, bashCall(id+"r", "cat x.go", "package x", false)...)
+	}
+	tr := writeTranscript(t, lines...)
+	in := loopInput("Shell", map[string]string{"command": "go test ./...", "transcript_path": tr})
+
+	result, code := LoopDetector(in, t.TempDir(), nil)
+	if code != 0 || !strings.Contains(result.Message, "has failed with the same error 2 times (attempt 3)") {
+		t.Errorf("expected warning, got %+v", result)
+	}
+
+	result, code = LoopDetector(in, t.TempDir(), &LoopDetectorPolicy{BlockAfter: 3})
+	if code != 2 || result.Decision != "deny" || !strings.HasPrefix(result.Reason, "Blocked:") {
+		t.Errorf("expected deny, got %+v code=%d", result, code)
+	}
+}
+
+func TestLoopDetector_DifferentErrorsAreProgress(t *testing.T) {
+	var lines []string
+	for i, out := range []string{"undefined: foo", "undefined: bar", "missing return"} {
+		lines = append(lines, bashCall(string(rune('a'+i)), "go build ./...", out, true)...)
+	}
+	in := loopInput("Shell", map[string]string{"command": "go build ./...", "transcript_path": writeTranscript(t, lines...)})
+	if result, _ := LoopDetector(in, t.TempDir(), nil); result.Message != "" {
+		t.Errorf("expected no warning, got %q", result.Message)
+	}
+}
+
+func TestLoopDetector_RepeatedInARowWithoutTranscript(t *testing.T) {
+	state := t.TempDir()
+	in := loopInput("Read", map[string]string{"path": "a.go"})
+	var result HookResult
+	var code int
+	for i := 0; i < 5; i++ {
+		result, code = LoopDetector(in, state, nil)
+		if i == 2 && !strings.Contains(result.Message, "repeated 2 times in a row") {
+			t.Errorf("expected warning on attempt 3, got %+v", result)
+		}
+	}
+	if code != 2 || result.Decision != "deny" {
+		t.Errorf("expected deny on attempt 5, got %+v", result)
+	}
+
+	// Anything in between breaks the run.
+	LoopDetector(loopInput("Read", map[string]string{"path": "b.go"}), state, nil)
+	if result, _ := LoopDetector(in, state, nil); result.Decision != "allow" || result.Message != "" {
+		t.Errorf("expected plain allow, got %+v", result)
+	}
+}
+
+func TestLoopDetector_Oscillation(t *testing.T) {
+	state := t.TempDir()
+	write := func(contents string) HookResult {
+		r, _ := LoopDetector(loopInput("Write", map[string]string{"path": "a.go", "contents": contents}), state, nil)
+		return r
+	}
+	for _, c := range []string{"A", "B", "A"} {
+		if r := write(c); r.Message != "" {
+			t.Fatalf("unexpected warning writing %s: %q", c, r.Message)
+		}
+	}
+	if r := write("B"); !strings.Contains(r.Message, "a.go would go back to an earlier version again (2 reverts)") {
+		t.Errorf("expected oscillation warning, got %+v", r)
+	}
+}
+
+func TestLoopDetector_EditUndo(t *testing.T) {
+	state := t.TempDir()
+	edit := func(before, after string) HookResult {
+		r, _ := LoopDetector(loopInput("Edit", map[string]string{"file_path": "a.go", "old_string": before, "new_string": after}), state, &LoopDetectorPolicy{WarnAfter: 2})
+		return r
+	}
+	edit("x := 1", "x := 2")
+	if r := edit("x := 2", "x := 1"); !strings.Contains(r.Message, "earlier version") {
+		t.Errorf("expected undo to be flagged, got %+v", r)
+	}
+}
+
+func TestNewLoopCall_Normalizes(t *testing.T) {
+	a := newLoopCall("Bash", json.RawMessage(`{"command":"go   test ./...","description":"run tests"}`))
+	b := newLoopCall("Shell", json.RawMessage(`{"command":"go test ./..."}`))
+	if a.Input != b.Input {
+		t.Errorf("shell fingerprints differ: %q vs %q", a.Input, b.Input)
+	}
+	w1 := newLoopCall("Write", json.RawMessage(`{"file_path":"a.go","content":"x"}`))
+	w2 := newLoopCall("Write", json.RawMessage(`{"path":"a.go","contents":"x"}`))
+	if w1.Input != w2.Input || w1.After != w2.After {
+		t.Errorf("write fingerprints differ: %+v vs %+v", w1, w2)
+	}
+}
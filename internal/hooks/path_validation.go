@@ -1,8 +1,8 @@
 package hooks
 
 import (
-	"encoding/json"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 )
@@ -22,28 +22,30 @@ var blockedPaths = []string{
 	"/ProgramData",
 }
 
+// PathPolicy configures PathValidation (policies.pathValidation in config.yaml).
+type PathPolicy struct {
+	// WorkspaceRoots are extra directories writes are allowed under. Relative
+	// entries are resolved against the repo root; ~ is expanded.
+	WorkspaceRoots []string `yaml:"workspaceRoots,omitempty" json:"workspaceRoots,omitempty"`
+	// GitWorktrees adds every worktree of the current repository as a root.
+	GitWorktrees bool `yaml:"gitWorktrees,omitempty" json:"gitWorktrees,omitempty"`
+	// DenyHomeOutsideProject blocks writes under $HOME that are not inside a workspace root.
+	DenyHomeOutsideProject bool `yaml:"denyHomeOutsideProject,omitempty" json:"denyHomeOutsideProject,omitempty"`
+	// AllowedPaths are always writable, in addition to /tmp, ~/.claude and HOOK_PATH_VALIDATION_ALLOWED.
+	AllowedPaths []string `yaml:"allowedPaths,omitempty" json:"allowedPaths,omitempty"`
+	// BlockedPaths are denied in addition to the built-in system directories.
+	BlockedPaths []string `yaml:"blockedPaths,omitempty" json:"blockedPaths,omitempty"`
+}
+
 // PathValidation is a preToolUse hook that blocks writes outside allowed project directories.
 // It prevents accidental modifications to system files or other projects.
 func PathValidation(input HookInput, workDir string) (HookResult, int) {
-	if input.ToolName != "Write" && input.ToolName != "Edit" && input.ToolName != "MultiEdit" {
-		return Allow(), 0
-	}
-
-	// Get path - Edit/MultiEdit use "file_path", Write uses "path"
-	path := input.Path()
-	if path == "" {
-		// Try file_path for Edit/MultiEdit
-		var m map[string]interface{}
-		if err := json.Unmarshal(input.ToolInput, &m); err == nil {
-			if v, ok := m["file_path"].(string); ok {
-				path = v
-			}
-		}
-		if path == "" {
-			return Allow(), 0
-		}
-	}
+	return PathValidationWithPolicy(input, workDir, nil)
+}
 
+// PathValidationWithPolicy runs PathValidation for Write/Edit tools and for
+// Shell commands that write files (redirects, tee, cp, mv, sed -i, touch).
+func PathValidationWithPolicy(input HookInput, workDir string, policy *PathPolicy) (HookResult, int) {
 	cwd := workDir
 	if cwd == "" {
 		cwd, _ = os.Getwd()
@@ -52,134 +54,238 @@ func PathValidation(input HookInput, workDir string) (HookResult, int) {
 		cwd = "."
 	}
 
-	allowed, reason := isPathAllowed(path, cwd)
-	if !allowed {
-		var msg strings.Builder
-		msg.WriteString("Path validation failed: " + reason)
-		msg.WriteString("\n  Attempted path: " + path)
-		msg.WriteString("\n  Current directory: " + cwd)
-		msg.WriteString("\n\nHint: Only write to project directories or ~/")
-		msg.WriteString("\n  - Use relative paths within your project")
-		msg.WriteString("\n  - System directories are protected")
-		msg.WriteString("\n  - Paths outside project require explicit permission")
-
-		return Deny(msg.String()), 2
+	switch input.ToolName {
+	case "Write", "Edit", "MultiEdit":
+		path := input.WritePath()
+		if path == "" {
+			return Allow(), 0
+		}
+		pc := newPathChecker(cwd, policy)
+		if allowed, reason := pc.check(path); !allowed {
+			return Deny(pathValidationReason(reason, path, "", cwd)), 2
+		}
+	case "Shell":
+		targets := shellWriteTargets(input.Command(), cwd)
+		if len(targets) == 0 {
+			return Allow(), 0
+		}
+		pc := newPathChecker(cwd, policy)
+		for _, t := range targets {
+			if allowed, reason := pc.check(t.Path); !allowed {
+				return Deny(pathValidationReason(reason, t.Path, t.Command, cwd)), 2
+			}
+		}
 	}
 
 	return Allow(), 0
 }
 
+func pathValidationReason(reason, path, command, cwd string) string {
+	var msg strings.Builder
+	msg.WriteString("Path validation failed: " + reason)
+	msg.WriteString("\n  Attempted path: " + path)
+	if command != "" {
+		msg.WriteString("\n  Command: " + command)
+	}
+	msg.WriteString("\n  Current directory: " + cwd)
+	msg.WriteString("\n\nHint: Only write inside the project workspace")
+	msg.WriteString("\n  - Use relative paths within your project")
+	msg.WriteString("\n  - System directories are protected")
+	msg.WriteString("\n  - Add extra directories under policies.pathValidation.workspaceRoots")
+	return msg.String()
+}
+
 func isPathAllowed(filePath string, cwd string) (bool, string) {
-	// Expand user home directory
-	expandedPath := filePath
-	if strings.HasPrefix(filePath, "~") {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			expandedPath = strings.Replace(filePath, "~", home, 1)
-		}
+	return newPathChecker(cwd, nil).check(filePath)
+}
+
+// pathChecker holds the resolved roots for one hook invocation.
+type pathChecker struct {
+	cwd      string
+	roots    []string
+	allowed  []string
+	blocked  []string
+	home     string
+	denyHome bool
+}
+
+func newPathChecker(cwd string, policy *PathPolicy) *pathChecker {
+	if policy == nil {
+		policy = &PathPolicy{}
+	}
+	pc := &pathChecker{
+		cwd:      absFrom(".", expandUserHome(cwd)),
+		blocked:  append(append([]string{}, blockedPaths...), policy.BlockedPaths...),
+		denyHome: policy.DenyHomeOutsideProject,
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		pc.home = filepath.Clean(home)
 	}
 
-	// Resolve absolute path
-	resolved, err := filepath.Abs(expandedPath)
-	if err != nil {
-		return false, "Invalid path: " + err.Error()
-	}
-
-	// Normalize separators for comparison
-	resolved = filepath.Clean(resolved)
-	resolvedLower := strings.ToLower(resolved)
-
-	// Check blocked system paths (case-insensitive for Windows)
-	// But allow /var/folders (macOS temp) and /var/tmp
-	for _, blocked := range blockedPaths {
-		blockedLower := strings.ToLower(blocked)
-		// Skip /var check if it's a temp directory
-		if blockedLower == "/var" {
-			if strings.HasPrefix(resolvedLower, "/var/folders/") ||
-				strings.HasPrefix(resolvedLower, "/var/tmp/") ||
-				resolvedLower == "/var/tmp" {
-				continue
+	pc.roots = append(pc.roots, pc.cwd)
+	repoRoot := gitOutput(pc.cwd, "rev-parse", "--show-toplevel")
+	if repoRoot != "" {
+		pc.roots = append(pc.roots, repoRoot)
+	}
+	if policy.GitWorktrees {
+		for _, line := range strings.Split(gitOutput(pc.cwd, "worktree", "list", "--porcelain"), "\n") {
+			if wt, ok := strings.CutPrefix(line, "worktree "); ok {
+				pc.roots = append(pc.roots, wt)
 			}
 		}
-		if strings.HasPrefix(resolvedLower, blockedLower) ||
-			strings.HasPrefix(resolvedLower, strings.ToLower(filepath.VolumeName(resolved))+blockedLower) {
-			// Ensure it's actually the directory, not a subdirectory we want to allow
-			if len(resolved) > len(blocked) {
-				nextChar := resolved[len(blocked)]
-				if nextChar != filepath.Separator && nextChar != '/' && nextChar != '\\' {
-					// Not actually a subdirectory, might be a prefix match
-					continue
-				}
+	}
+	base := repoRoot
+	if base == "" {
+		base = pc.cwd
+	}
+	for _, r := range policy.WorkspaceRoots {
+		pc.roots = append(pc.roots, absFrom(base, expandUserHome(r)))
+	}
+
+	pc.allowed = getAllowedPaths()
+	for _, p := range policy.AllowedPaths {
+		pc.allowed = append(pc.allowed, absFrom(base, expandUserHome(p)))
+	}
+	return pc
+}
+
+// check reports whether filePath may be written and why.
+func (pc *pathChecker) check(filePath string) (bool, string) {
+	if strings.ContainsRune(filePath, 0) {
+		return false, "Invalid path: contains NUL byte"
+	}
+
+	lexical := absFrom(pc.cwd, expandUserHome(filePath))
+	// Follow symlinks so a link inside the repo cannot point writes at /etc.
+	resolved := resolveExistingAncestor(lexical)
+
+	for _, p := range []string{lexical, resolved} {
+		if blocked := pc.blockedBy(p); blocked != "" {
+			if p == resolved && resolved != lexical {
+				return false, "System path blocked: " + blocked + " (via symlink to " + resolved + ")"
 			}
 			return false, "System path blocked: " + blocked
 		}
 	}
 
-	// Check always-allowed paths
-	allowedPaths := getAllowedPaths()
-	for _, allowed := range allowedPaths {
-		allowedLower := strings.ToLower(allowed)
-		if strings.HasPrefix(resolvedLower, allowedLower) {
-			return true, "Allowed path"
+	inRoot := pc.underAny(resolved, pc.roots)
+
+	// Path traversal (../) is only acceptable when it stays inside the workspace
+	if containsPathTraversal(filePath) && !inRoot {
+		return false, "Path traversal detected"
+	}
+
+	if pc.underAny(resolved, pc.allowed) || pc.underAny(lexical, pc.allowed) {
+		return true, "Allowed path"
+	}
+
+	if inRoot {
+		return true, "Under workspace root"
+	}
+
+	if pc.home != "" && isWithin(resolved, pc.home) {
+		if pc.denyHome {
+			return false, "Home directory write outside the project workspace"
+		}
+		return true, "Under home (outside project)"
+	}
+
+	if resolved != lexical && pc.underAny(lexical, pc.roots) {
+		return false, "Symlink escapes workspace: resolves to " + resolved
+	}
+	return false, "Path outside allowed directories"
+}
+
+func (pc *pathChecker) blockedBy(p string) string {
+	for _, blocked := range pc.blocked {
+		// macOS temp dirs live under /var
+		if blocked == "/var" && (isWithin(p, "/var/folders") || isWithin(p, "/var/tmp")) {
+			continue
+		}
+		if isWithin(p, blocked) || isWithin(p, filepath.VolumeName(p)+blocked) {
+			return blocked
 		}
 	}
+	return ""
+}
 
-	// Check for path traversal attacks (e.g., ../../../etc/passwd)
-	if containsPathTraversal(filePath) {
-		// Still check if it resolves to something safe
-		cwdResolved, err := filepath.Abs(cwd)
-		if err == nil {
-			relPath, err := filepath.Rel(cwdResolved, resolved)
-			if err == nil && !strings.HasPrefix(relPath, "..") {
-				// Path resolves to within cwd, allow it
-				return true, "Under cwd"
-			}
+func (pc *pathChecker) underAny(p string, dirs []string) bool {
+	for _, d := range dirs {
+		if isWithin(p, d) || isWithin(p, resolveExistingAncestor(d)) {
+			return true
 		}
-		// Path traversal detected and doesn't resolve to safe location
-		return false, "Path traversal detected"
 	}
+	return false
+}
 
-	// Allow paths under current working directory
-	cwdResolved, err := filepath.Abs(cwd)
-	if err == nil {
-		cwdResolved = filepath.Clean(cwdResolved)
-		// Normalize both paths for comparison
-		cwdNormalized := filepath.ToSlash(cwdResolved)
-		resolvedNormalized := filepath.ToSlash(resolved)
-
-		// Use case-insensitive comparison for Windows
-		cwdLower := strings.ToLower(cwdNormalized)
-		resolvedLower := strings.ToLower(resolvedNormalized)
-
-		if strings.HasPrefix(resolvedLower, cwdLower) {
-			// Ensure it's actually a subdirectory, not just a prefix match
-			if len(resolvedLower) == len(cwdLower) {
-				return true, "Under cwd"
-			}
-			// Check if next character is path separator
-			nextChar := resolvedLower[len(cwdLower)]
-			if nextChar == '/' || nextChar == '\\' {
-				return true, "Under cwd"
-			}
+// isWithin reports whether p is dir or inside it (case-insensitive, as
+// Windows and default macOS filesystems are).
+func isWithin(p, dir string) bool {
+	p = strings.ToLower(filepath.ToSlash(filepath.Clean(p)))
+	dir = strings.ToLower(filepath.ToSlash(filepath.Clean(dir)))
+	if p == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	return strings.HasPrefix(p, dir)
+}
+
+// resolveExistingAncestor resolves symlinks in the longest existing prefix of
+// p and re-appends the part that does not exist yet.
+func resolveExistingAncestor(p string) string {
+	var rest []string
+	cur := filepath.Clean(p)
+	for {
+		if resolved, err := filepath.EvalSymlinks(cur); err == nil {
+			parts := append([]string{resolved}, rest...)
+			return filepath.Join(parts...)
 		}
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return filepath.Clean(p)
+		}
+		rest = append([]string{filepath.Base(cur)}, rest...)
+		cur = parent
+	}
+}
+
+// absFrom makes p absolute relative to base.
+func absFrom(base, p string) string {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(base, p)
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
 	}
+	return filepath.Clean(p)
+}
 
-	// Allow paths under home directory
+// expandUserHome replaces a leading ~ or $HOME with the user's home directory.
+func expandUserHome(p string) string {
 	home, err := os.UserHomeDir()
-	if err == nil {
-		home = filepath.Clean(home)
-		if strings.HasPrefix(resolved, home) {
-			// Warn if path is far from cwd
-			if cwdResolved != "" && !strings.HasPrefix(cwdResolved, home) {
-				// Path is in home but cwd is not - might be accidental
-				// But allow it anyway, just log
-				return true, "Under home (outside project)"
-			}
-			return true, "Under home"
+	if err != nil {
+		return p
+	}
+	for _, prefix := range []string{"~", "$HOME", "${HOME}"} {
+		if p == prefix {
+			return home
+		}
+		if rest, ok := strings.CutPrefix(p, prefix+"/"); ok {
+			return filepath.Join(home, rest)
 		}
 	}
+	return p
+}
 
-	return false, "Path outside allowed directories"
+func gitOutput(dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
 }
 
 func getAllowedPaths() []string {
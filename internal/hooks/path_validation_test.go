@@ -247,3 +247,119 @@ func TestContainsPathTraversal(t *testing.T) {
 		})
 	}
 }
+
+func TestPathValidation_SymlinkToSystemDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Symlink("/etc", filepath.Join(dir, "conf")); err != nil {
+		t.Skip("symlinks not supported")
+	}
+
+	result, code := PathValidation(writeInput("conf/new.conf", "x"), dir)
+	if code != 2 || result.Decision != "deny" {
+		t.Fatalf("expected deny for write through symlink to /etc, got decision=%s code=%d", result.Decision, code)
+	}
+	if !strings.Contains(result.Reason, "via symlink") {
+		t.Errorf("expected reason to mention the symlink, got %q", result.Reason)
+	}
+}
+
+func TestPathValidation_SymlinkEscapesWorkspace(t *testing.T) {
+	policy := &PathPolicy{DenyHomeOutsideProject: true}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("cannot get home directory")
+	}
+	outside, err := os.MkdirTemp(home, "pv-outside-")
+	if err != nil {
+		t.Skip("cannot create dir under home")
+	}
+	defer os.RemoveAll(outside)
+	dir, err := os.MkdirTemp(home, "pv-project-")
+	if err != nil {
+		t.Skip("cannot create dir under home")
+	}
+	defer os.RemoveAll(dir)
+	os.Symlink(outside, filepath.Join(dir, "link"))
+
+	result, code := PathValidationWithPolicy(writeInput("link/file.txt", "x"), dir, policy)
+	if code != 2 || result.Decision != "deny" {
+		t.Errorf("expected deny for symlink leaving the project, got decision=%s code=%d", result.Decision, code)
+	}
+	result, code = PathValidationWithPolicy(writeInput("src/file.txt", "x"), dir, policy)
+	if code != 0 || result.Decision != "allow" {
+		t.Errorf("expected allow inside project, got decision=%s reason=%s", result.Decision, result.Reason)
+	}
+}
+
+func TestPathValidation_DenyHomeOutsideProject(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("cannot get home directory")
+	}
+	dir := t.TempDir()
+	policy := &PathPolicy{DenyHomeOutsideProject: true}
+
+	result, code := PathValidationWithPolicy(writeInput(filepath.Join(home, "notes.txt"), "x"), dir, policy)
+	if code != 2 || result.Decision != "deny" {
+		t.Errorf("expected deny for home write outside project, got decision=%s code=%d", result.Decision, code)
+	}
+
+	// ~/.claude stays writable
+	result, code = PathValidationWithPolicy(writeInput("~/.claude/settings.json", "{}"), dir, policy)
+	if code != 0 || result.Decision != "allow" {
+		t.Errorf("expected allow for ~/.claude, got decision=%s reason=%s", result.Decision, result.Reason)
+	}
+}
+
+func TestPathValidation_WorkspaceRoots(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("cannot get home directory")
+	}
+	project := t.TempDir()
+	shared := filepath.Join(home, "shared-libs")
+	policy := &PathPolicy{DenyHomeOutsideProject: true, WorkspaceRoots: []string{"~/shared-libs"}}
+
+	result, code := PathValidationWithPolicy(writeInput(filepath.Join(shared, "lib.go"), "x"), project, policy)
+	if code != 0 || result.Decision != "allow" {
+		t.Errorf("expected allow under configured workspace root, got decision=%s reason=%s", result.Decision, result.Reason)
+	}
+}
+
+func TestPathValidation_ShellWrites(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		cmd      string
+		wantDeny bool
+	}{
+		{"redirect to etc", "echo x > /etc/hosts", true},
+		{"append to usr", "echo x >> /usr/local/bin/tool", true},
+		{"tee", "echo x | sudo tee /etc/profile.d/x.sh", true},
+		{"cp into usr", "cp ./bin/tool /usr/local/bin/", true},
+		{"mv target dir", "mv -t /usr/local/bin tool", true},
+		{"mv into usr local", "mv tool /usr/local/", true},
+		{"sed -i", "sed -i 's/a/b/' /etc/hosts", true},
+		{"touch", "touch /var/log/app.log", true},
+		{"redirect in project", "echo x > out.txt", false},
+		{"stderr to null", "make 2>/dev/null", false},
+		{"fd dup", "go test ./... 2>&1 | tee test.log", false},
+		{"cp from system into project", "cp /etc/hosts ./hosts.bak", false},
+		{"sed without -i", "sed 's/a/b/' /etc/hosts", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := PathValidation(shellInput(tt.cmd), dir)
+			if tt.wantDeny {
+				if code != 2 || result.Decision != "deny" {
+					t.Errorf("expected deny for %q, got decision=%s code=%d", tt.cmd, result.Decision, code)
+				}
+				if !strings.Contains(result.Reason, "Command: ") {
+					t.Errorf("expected reason to name the sub-command, got %q", result.Reason)
+				}
+			} else if code != 0 || result.Decision != "allow" {
+				t.Errorf("expected allow for %q, got decision=%s reason=%s", tt.cmd, result.Decision, result.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Policies holds per-hook settings from the top-level policies: section of
+// config.yaml. gen-config writes it to .cursor/hooks-policies.json and hook
+// binaries read it with LoadPolicies. A nil section means built-in defaults.
+type Policies struct {
+	PathValidation   *PathPolicy           `yaml:"pathValidation,omitempty" json:"pathValidation,omitempty"`
+	ReadonlyGuard    *ReadonlyPolicy       `yaml:"readonlyGuard,omitempty" json:"readonlyGuard,omitempty"`
+	GitPolicy        *GitPolicyConfig      `yaml:"gitPolicy,omitempty" json:"gitPolicy,omitempty"`
+	Checkpoint       *CheckpointPolicy     `yaml:"checkpoint,omitempty" json:"checkpoint,omitempty"`
+	CommitMsgLint    *CommitPolicy         `yaml:"commitMsgLint,omitempty" json:"commitMsgLint,omitempty"`
+	CommitGate       *CommitGatePolicy     `yaml:"commitGate,omitempty" json:"commitGate,omitempty"`
+	LintChanged      *LintPolicy           `yaml:"lintChanged,omitempty" json:"lintChanged,omitempty"`
+	TypecheckChanged *TypecheckPolicy      `yaml:"typecheckChanged,omitempty" json:"typecheckChanged,omitempty"`
+	BackgroundChecks *BackgroundPolicy     `yaml:"backgroundChecks,omitempty" json:"backgroundChecks,omitempty"`
+	TestRunner       *TestRunnerPolicy     `yaml:"testRunner,omitempty" json:"testRunner,omitempty"`
+	TestBuddy        *TestBuddyPolicy      `yaml:"testBuddy,omitempty" json:"testBuddy,omitempty"`
+	CompletionGate   *CompletionGatePolicy `yaml:"completionGate,omitempty" json:"completionGate,omitempty"`
+	Cost             *CostPolicy           `yaml:"cost,omitempty" json:"cost,omitempty"`
+	SessionBudget    *SessionBudgetPolicy  `yaml:"sessionBudget,omitempty" json:"sessionBudget,omitempty"`
+	LoopDetector     *LoopDetectorPolicy   `yaml:"loopDetector,omitempty" json:"loopDetector,omitempty"`
+	TimeTracker      *TimeTrackerPolicy    `yaml:"timeTracker,omitempty" json:"timeTracker,omitempty"`
+	SessionDiary     *SessionDiaryPolicy   `yaml:"sessionDiary,omitempty" json:"sessionDiary,omitempty"`
+}
+
+// LoadPolicies reads HOOK_POLICIES_PATH, or the .cursor/hooks-policies.json
+// nearest to workDir: hooks may run from a subdirectory, so the search walks
+// up to the repository root. A missing file yields empty policies; so does an
+// invalid one, which is reported on stderr so hooks fail open to defaults
+// without hiding the broken file.
+func LoadPolicies(workDir string) Policies {
+	path := os.Getenv("HOOK_POLICIES_PATH")
+	if path == "" {
+		path = findPoliciesFile(workDir)
+	}
+	if path == "" {
+		return Policies{}
+	}
+	p, err := readPolicies(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hooks: ignoring %s: %v\n", path, err)
+		return Policies{}
+	}
+	return p
+}
+
+// findPoliciesFile returns the first .cursor/hooks-policies.json from workDir
+// up to the repository root (the first directory with a .git entry), or "".
+func findPoliciesFile(workDir string) string {
+	dir, err := filepath.Abs(workDir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if p := filepath.Join(dir, ".cursor", "hooks-policies.json"); exists(p) {
+			return p
+		}
+		parent := filepath.Dir(dir)
+		if exists(filepath.Join(dir, ".git")) || parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readPolicies parses a policies file; a missing file is not an error.
+func readPolicies(path string) (Policies, error) {
+	var p Policies
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Policies{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,47 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPolicies_FindsRepoPolicies(t *testing.T) {
+	t.Setenv("HOOK_POLICIES_PATH", "")
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".git/HEAD":                   "ref: refs/heads/main\n",
+		".cursor/hooks-policies.json": `{"testRunner":{"onStop":true}}`,
+		"pkg/sub/a.go":                "package sub\n",
+	})
+
+	for _, dir := range []string{root, filepath.Join(root, "pkg", "sub")} {
+		if p := LoadPolicies(dir); p.TestRunner == nil || !p.TestRunner.OnStop {
+			t.Errorf("LoadPolicies(%s) did not find the repository policies: %+v", dir, p)
+		}
+	}
+
+	// The search stops at the repository root
+	nested := filepath.Join(root, "vendor", "lib")
+	writeFiles(t, nested, map[string]string{".git/HEAD": "ref: refs/heads/main\n"})
+	if p := LoadPolicies(nested); p.TestRunner != nil {
+		t.Errorf("policies leaked into a nested repository: %+v", p)
+	}
+}
+
+func TestReadPolicies_InvalidJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hooks-policies.json")
+	if _, err := readPolicies(path); err != nil {
+		t.Errorf("a missing file should not be an error, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte(`{"testRunner": {"onStop": true,}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPolicies(path); err == nil {
+		t.Error("expected invalid JSON to be reported")
+	}
+	t.Setenv("HOOK_POLICIES_PATH", path)
+	if p := LoadPolicies(t.TempDir()); p.TestRunner != nil {
+		t.Errorf("invalid policies should fall back to defaults, got %+v", p)
+	}
+}
@@ -0,0 +1,160 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// shellReaders are commands that print or copy the contents of their file arguments.
+var shellReaders = map[string]bool{
+	"cat": true, "less": true, "more": true, "head": true, "tail": true, "tac": true, "nl": true,
+	"bat": true, "grep": true, "egrep": true, "fgrep": true, "rg": true, "ag": true,
+	"sed": true, "awk": true, "gawk": true, "cp": true, "strings": true, "xxd": true,
+	"od": true, "hexdump": true, "base64": true, "diff": true, "jq": true, "yq": true,
+}
+
+// recursiveSearchers walk directory arguments; pointing them at $HOME reads every
+// credential file under it (grep -r AWS_SECRET ~).
+var recursiveSearchers = map[string]bool{
+	"grep": true, "egrep": true, "fgrep": true, "rg": true, "ag": true,
+}
+
+// patternFirstReaders take a pattern or script as their first operand, which
+// must not be mistaken for a file (grep id_rsa README.md).
+var patternFirstReaders = map[string]bool{
+	"grep": true, "egrep": true, "fgrep": true, "rg": true, "ag": true,
+	"sed": true, "awk": true, "gawk": true, "jq": true, "yq": true,
+}
+
+// ReadGuard is a preToolUse hook that blocks agents from reading secrets files
+// through Read/Grep/Glob tools or shell commands such as cat, grep and cp.
+func ReadGuard(input HookInput) (HookResult, int) {
+	return ReadGuardWithExemptions(input, nil)
+}
+
+// ReadGuardWithExemptions runs the read guard; paths matching any exempt glob are allowed.
+func ReadGuardWithExemptions(input HookInput, exempt []string) (HookResult, int) {
+	switch input.ToolName {
+	case "Read", "Grep", "Glob":
+		return readGuardTool(input, exempt)
+	case "Shell":
+		return readGuardShell(input.Command(), exempt)
+	}
+	return Allow(), 0
+}
+
+func readGuardTool(input HookInput, exempt []string) (HookResult, int) {
+	path := input.Path()
+	if path == "" {
+		path = input.FilePath()
+	}
+
+	candidates := []string{path}
+	if input.ToolName == "Glob" {
+		candidates = append(candidates, input.Pattern())
+	}
+	for _, p := range candidates {
+		if kind, ok := sensitiveReadKind(p); ok && !isReadExempt(p, exempt) {
+			return denyRead(kind, p, input.ToolName+" tool"), 2
+		}
+	}
+
+	if input.ToolName == "Grep" && isHomeDir(path) {
+		return denyRead("home directory (contains credential files)", path, "Grep tool"), 2
+	}
+
+	return Allow(), 0
+}
+
+func readGuardShell(cmd string, exempt []string) (HookResult, int) {
+	if cmd == "" {
+		return Allow(), 0
+	}
+
+	for _, c := range shellCommands(cmd) {
+		for _, r := range c.Redirects {
+			if r.Op != "<" && r.Op != "<>" {
+				continue
+			}
+			if kind, ok := sensitiveReadKind(r.Target); ok && !isReadExempt(r.Target, exempt) {
+				return denyRead(kind, r.Target, c.String()), 2
+			}
+		}
+
+		args := commandArgs(c.Args)
+		name := commandName(c.Args)
+		if !shellReaders[name] {
+			continue
+		}
+		recursive := name == "rg" || name == "ag"
+		patternFirst := patternFirstReaders[name]
+		for i := 1; i < len(args); i++ {
+			arg := args[i]
+			if strings.HasPrefix(arg, "-") {
+				switch {
+				case patternFirst && (arg == "-e" || arg == "--regexp" || arg == "--expression"):
+					i++ // the pattern is the flag value
+					patternFirst = false
+				case patternFirst && (arg == "-f" || arg == "--file"):
+					patternFirst = false // pattern/script comes from a file, which is checked below
+				case name != "cp" && (arg == "--recursive" || (!strings.HasPrefix(arg, "--") && strings.ContainsAny(arg, "rR"))):
+					recursive = true
+				}
+				continue
+			}
+			if patternFirst {
+				patternFirst = false
+				continue
+			}
+			if kind, ok := sensitiveReadKind(arg); ok && !isReadExempt(arg, exempt) {
+				return denyRead(kind, arg, c.String()), 2
+			}
+			if recursive && recursiveSearchers[name] && isHomeDir(arg) {
+				return denyRead("home directory (contains credential files)", arg, c.String()), 2
+			}
+		}
+	}
+
+	return Allow(), 0
+}
+
+func denyRead(kind, path, via string) HookResult {
+	var reason strings.Builder
+	reason.WriteString("Blocked: read of " + kind)
+	reason.WriteString("\n  Path: " + path)
+	reason.WriteString("\n  Via: " + via)
+	reason.WriteString("\n\nHint: secrets must not enter the conversation.")
+	reason.WriteString("\n  - Ask the user for the specific non-secret value you need")
+	reason.WriteString("\n  - Exempt a harmless file with allowlists.readGuard.exemptPaths in config.yaml")
+	return Deny(reason.String())
+}
+
+// isReadExempt reports whether path, or any trailing part of it, matches one
+// of the exempt globs ("fixtures/*.pem" matches "testdata/fixtures/a.pem").
+func isReadExempt(path string, exempt []string) bool {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for _, pattern := range exempt {
+		for i := range parts {
+			if ok, _ := filepath.Match(pattern, strings.Join(parts[i:], "/")); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isHomeDir reports whether path names the user's home directory itself.
+func isHomeDir(path string) bool {
+	switch strings.TrimSuffix(path, "/") {
+	case "~", "$HOME", "${HOME}":
+		return true
+	case "":
+		return false
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false
+	}
+	return filepath.Clean(path) == filepath.Clean(home)
+}
@@ -0,0 +1,108 @@
+package hooks
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func toolInput(tool string, fields map[string]string) HookInput {
+	ti, _ := json.Marshal(fields)
+	return HookInput{ToolName: tool, ToolInput: ti}
+}
+
+func TestReadGuard_BlocksTools(t *testing.T) {
+	tests := []struct {
+		name  string
+		input HookInput
+	}{
+		{"Read .env", toolInput("Read", map[string]string{"path": ".env"})},
+		{"Read file_path key", toolInput("Read", map[string]string{"file_path": "/home/u/.ssh/id_ed25519"})},
+		{"Read kubeconfig", toolInput("Read", map[string]string{"path": "~/.kube/config"})},
+		{"Grep ssh dir", toolInput("Grep", map[string]string{"pattern": "BEGIN", "path": "~/.ssh"})},
+		{"Grep home", toolInput("Grep", map[string]string{"pattern": "AWS_SECRET", "path": "~"})},
+		{"Glob aws", toolInput("Glob", map[string]string{"pattern": "*", "path": "~/.aws"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := ReadGuard(tt.input)
+			if code != 2 || result.Decision != "deny" {
+				t.Errorf("expected deny, got decision=%q code=%d", result.Decision, code)
+			}
+		})
+	}
+}
+
+func TestReadGuard_BlocksShell(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  string
+	}{
+		{"cat env", "cat .env"},
+		{"less pem", "less certs/server.pem"},
+		{"head tfvars", "head -n 5 prod.tfvars"},
+		{"tail credentials", "tail ~/.aws/credentials"},
+		{"grep recursive home", "grep -r AWS_SECRET ~"},
+		{"grep in ssh dir", "grep -rn PRIVATE ~/.ssh"},
+		{"sed print env", "sed -n 1,5p .env.local"},
+		{"awk env", "awk -F= '{print $2}' .env"},
+		{"cp key", "cp ~/.ssh/id_rsa ./key"},
+		{"redirect stdin", "wc -c < .env"},
+		{"nested", "echo $(cat .npmrc)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := ReadGuard(shellInput(tt.cmd))
+			if code != 2 || result.Decision != "deny" {
+				t.Errorf("expected deny for %q, got decision=%q code=%d", tt.cmd, result.Decision, code)
+			}
+			if !strings.Contains(result.Reason, "Via: ") {
+				t.Errorf("expected reason to name the command, got %q", result.Reason)
+			}
+		})
+	}
+}
+
+func TestReadGuard_Allows(t *testing.T) {
+	tests := []struct {
+		name  string
+		input HookInput
+	}{
+		{"Read source", toolInput("Read", map[string]string{"path": "main.go"})},
+		{"Read env example", toolInput("Read", map[string]string{"path": ".env.example"})},
+		{"Read public key", toolInput("Read", map[string]string{"path": "~/.ssh/id_rsa.pub"})},
+		{"Grep project", toolInput("Grep", map[string]string{"pattern": "TODO", "path": "src"})},
+		{"cat readme", shellInput("cat README.md")},
+		{"grep pattern looks like key", shellInput("grep id_rsa docs/setup.md")},
+		{"grep -e pattern", shellInput("grep -e .env .gitignore")},
+		{"grep non-recursive home file", shellInput("grep alias ~/.bashrc")},
+		{"cat env example", shellInput("cat .env.sample")},
+		{"write to env is not a read", shellInput("echo X=1 > .env")},
+		{"Write tool", writeInput(".env", "X=1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := ReadGuard(tt.input)
+			if code != 0 || result.Decision != "allow" {
+				t.Errorf("expected allow, got decision=%q code=%d reason=%q", result.Decision, code, result.Reason)
+			}
+		})
+	}
+}
+
+func TestReadGuard_Exemptions(t *testing.T) {
+	exempt := []string{".env.test", "fixtures/*.pem"}
+	for _, input := range []HookInput{
+		shellInput("cat .env.test"),
+		toolInput("Read", map[string]string{"path": "testdata/fixtures/server.pem"}),
+	} {
+		result, code := ReadGuardWithExemptions(input, exempt)
+		if code != 0 || result.Decision != "allow" {
+			t.Errorf("expected exempt path to be allowed, got decision=%q reason=%q", result.Decision, result.Reason)
+		}
+	}
+	result, code := ReadGuardWithExemptions(shellInput("cat .env.production"), exempt)
+	if code != 2 || result.Decision != "deny" {
+		t.Errorf("expected non-exempt env file to be denied, got decision=%q", result.Decision)
+	}
+}
@@ -1,7 +1,8 @@
 package hooks
 
 import (
-	"encoding/json"
+	"bufio"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -41,53 +42,263 @@ var overrideAllowed = []*regexp.Regexp{
 	regexp.MustCompile(`\.vscode/tasks\.json$`),  // Task configs are ok
 }
 
+// generatedMarkers are header lines that tools put at the top of generated files.
+var generatedMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`Code generated .*DO NOT EDIT`), // Go convention, also used by protoc plugins
+	regexp.MustCompile(`@generated\b`),
+	regexp.MustCompile(`(?i)(auto-?generated|automatically generated).*do not (edit|modify)`),
+}
+
+// generatedHeaderLines is how far into a file ReadonlyGuard looks for a marker.
+const generatedHeaderLines = 10
+
+// ReadonlyPolicy configures ReadonlyGuard (policies.readonlyGuard in config.yaml).
+// Globs are gitignore-style and relative to the repo root.
+type ReadonlyPolicy struct {
+	// Paths are extra readonly globs, e.g. protobuf or openapi output dirs ("gen/proto/").
+	Paths []string `yaml:"paths,omitempty" json:"paths,omitempty"`
+	// Allow globs override every other rule, e.g. hand-written "src/types/*.d.ts".
+	Allow []string `yaml:"allow,omitempty" json:"allow,omitempty"`
+	// DeclarationsFile lists readonly globs one per line, CODEOWNERS style
+	// ("pattern  optional note"). Default .readonly at the repo root.
+	DeclarationsFile string `yaml:"declarationsFile,omitempty" json:"declarationsFile,omitempty"`
+	// IgnoreGeneratedMarkers disables DO NOT EDIT header and linguist-generated detection.
+	IgnoreGeneratedMarkers bool `yaml:"ignoreGeneratedMarkers,omitempty" json:"ignoreGeneratedMarkers,omitempty"`
+}
+
 // ReadonlyGuard is a preToolUse hook that protects lock files, generated files,
-// and vendor directories from modification.
+// and vendor directories from modification, whether by Write/Edit tools or by
+// Shell commands that write files (redirects, sed -i, cp, mv, ...).
 func ReadonlyGuard(input HookInput) (HookResult, int) {
-	if input.ToolName != "Write" && input.ToolName != "Edit" && input.ToolName != "MultiEdit" {
-		return Allow(), 0
-	}
-
-	// Get path - Edit/MultiEdit use "file_path", Write uses "path"
-	path := input.Path()
-	if path == "" {
-		// Try file_path for Edit/MultiEdit
-		var m map[string]interface{}
-		if err := json.Unmarshal(input.ToolInput, &m); err == nil {
-			if v, ok := m["file_path"].(string); ok {
-				path = v
-			}
-		}
+	return ReadonlyGuardWithPolicy(input, "", nil)
+}
+
+// ReadonlyGuardWithPolicy runs ReadonlyGuard relative to workDir. Besides the
+// built-in patterns it treats as readonly files with a generated-code header,
+// files marked linguist-generated in .gitattributes, globs in the declarations
+// file and policy paths.
+func ReadonlyGuardWithPolicy(input HookInput, workDir string, policy *ReadonlyPolicy) (HookResult, int) {
+	switch input.ToolName {
+	case "Write", "Edit", "MultiEdit":
+		path := input.WritePath()
 		if path == "" {
 			return Allow(), 0
 		}
+		if rule := newReadonlyChecker(workDir, policy).match(path); rule != nil {
+			return Deny(readonlyReason(path, rule, "")), 2
+		}
+	case "Shell":
+		targets := shellWriteTargets(input.Command(), workDir)
+		if len(targets) == 0 {
+			return Allow(), 0
+		}
+		rc := newReadonlyChecker(workDir, policy)
+		for _, t := range targets {
+			// Deleting generated output (rm -rf dist) or creating its dir is how
+			// it gets regenerated; only in-place modification is blocked.
+			if t.Kind != shellWrite {
+				continue
+			}
+			if rule := rc.match(t.Path); rule != nil {
+				return Deny(readonlyReason(t.Path, rule, t.Command)), 2
+			}
+		}
+	}
+
+	return Allow(), 0
+}
+
+// readonlyRule describes why a file is readonly.
+type readonlyRule struct {
+	Source  string // where the rule comes from
+	Pattern string
+	Note    string
+}
+
+// readonlyChecker holds the repo context for one hook invocation.
+type readonlyChecker struct {
+	cwd          string
+	root         string // git toplevel, or cwd outside a repo
+	inGit        bool
+	policy       *ReadonlyPolicy
+	declarations []readonlyRule
+}
+
+func newReadonlyChecker(workDir string, policy *ReadonlyPolicy) *readonlyChecker {
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
+	if policy == nil {
+		policy = &ReadonlyPolicy{}
+	}
+	rc := &readonlyChecker{cwd: absFrom(".", workDir), policy: policy}
+	rc.root = gitOutput(rc.cwd, "rev-parse", "--show-toplevel")
+	rc.inGit = rc.root != ""
+	if !rc.inGit {
+		rc.root = rc.cwd
+	}
+	rc.root = resolveExistingAncestor(rc.root)
+
+	declFile := policy.DeclarationsFile
+	if declFile == "" {
+		declFile = ".readonly"
+	}
+	if data, err := os.ReadFile(absFrom(rc.root, declFile)); err == nil {
+		rc.declarations = parseReadonlyDeclarations(string(data), declFile)
+	}
+	return rc
+}
+
+// parseReadonlyDeclarations parses "pattern  optional note" lines; # starts a comment.
+func parseReadonlyDeclarations(data, source string) []readonlyRule {
+	var rules []readonlyRule
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		note := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+		rules = append(rules, readonlyRule{Source: source, Pattern: fields[0], Note: note})
 	}
+	return rules
+}
 
+// match returns the rule that makes path readonly, or nil.
+func (rc *readonlyChecker) match(path string) *readonlyRule {
 	// Normalize path separators
 	normalizedPath := filepath.ToSlash(path)
+	rel := rc.relPath(path)
 
 	// Check override patterns first
 	for _, pattern := range overrideAllowed {
 		if pattern.MatchString(normalizedPath) {
-			return Allow(), 0
+			return nil
+		}
+	}
+	for _, glob := range rc.policy.Allow {
+		if matchRepoGlob(glob, rel) {
+			return nil
 		}
 	}
 
-	// Check readonly patterns
 	for _, pattern := range readonlyPatterns {
 		if pattern.MatchString(normalizedPath) {
-			var reason strings.Builder
-			reason.WriteString("Readonly file protection triggered")
-			reason.WriteString("\n  File: " + path)
-			reason.WriteString("\n  Pattern: " + pattern.String())
-			reason.WriteString("\n\nHint: This file is auto-generated or managed by tools.")
-			reason.WriteString("\n  - Lock files: Use package manager commands instead")
-			reason.WriteString("\n  - Generated files: Modify source files instead")
-			reason.WriteString("\n  - Vendor dirs: Don't modify dependencies directly")
-
-			return Deny(reason.String()), 2
+			return &readonlyRule{Source: "built-in", Pattern: pattern.String()}
+		}
+	}
+	for _, glob := range rc.policy.Paths {
+		if matchRepoGlob(glob, rel) {
+			return &readonlyRule{Source: "policies.readonlyGuard.paths", Pattern: glob}
+		}
+	}
+	for i := range rc.declarations {
+		if matchRepoGlob(rc.declarations[i].Pattern, rel) {
+			return &rc.declarations[i]
 		}
 	}
 
-	return Allow(), 0
+	if rc.policy.IgnoreGeneratedMarkers {
+		return nil
+	}
+	abs := absFrom(rc.cwd, expandUserHome(path))
+	if rc.inGit && !strings.HasPrefix(rel, "/") && !strings.HasPrefix(rel, "../") {
+		// "<path>: linguist-generated: set|true|unset|false|unspecified"
+		out := gitOutput(rc.root, "check-attr", "linguist-generated", "--", rel)
+		if strings.HasSuffix(out, ": set") || strings.HasSuffix(out, ": true") {
+			return &readonlyRule{Source: ".gitattributes", Pattern: "linguist-generated"}
+		}
+	}
+	if marker := generatedHeader(abs); marker != "" {
+		return &readonlyRule{Source: "generated-file header", Pattern: marker}
+	}
+	return nil
+}
+
+// relPath returns path relative to the repo root with forward slashes, or the
+// normalized path itself when it lies outside the repo.
+func (rc *readonlyChecker) relPath(path string) string {
+	// Resolve symlinks on both sides: git reports the real toplevel (/private/var on macOS).
+	abs := resolveExistingAncestor(absFrom(rc.cwd, expandUserHome(filepath.FromSlash(path))))
+	if rel, err := filepath.Rel(rc.root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+// generatedHeader returns the generated-code marker line near the top of the
+// file at path, or "" if there is none or the file does not exist.
+func generatedHeader(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for i := 0; i < generatedHeaderLines && scanner.Scan(); i++ {
+		line := scanner.Text()
+		for _, marker := range generatedMarkers {
+			if marker.MatchString(line) {
+				return strings.TrimSpace(line)
+			}
+		}
+	}
+	return ""
+}
+
+// matchRepoGlob matches a gitignore-style glob against a repo-relative path.
+// A pattern without a slash matches at any depth; a leading slash anchors it to
+// the root; a pattern also matches everything under a directory it names.
+func matchRepoGlob(pattern, rel string) bool {
+	pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+	if pattern == "" {
+		return false
+	}
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var re strings.Builder
+	if anchored {
+		re.WriteString("^")
+	} else {
+		re.WriteString("(^|/)")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("(/|$)")
+	matched, err := regexp.MatchString(re.String(), rel)
+	return err == nil && matched
+}
+
+func readonlyReason(path string, rule *readonlyRule, command string) string {
+	var reason strings.Builder
+	reason.WriteString("Readonly file protection triggered")
+	reason.WriteString("\n  File: " + path)
+	if command != "" {
+		reason.WriteString("\n  Command: " + command)
+	}
+	reason.WriteString("\n  Pattern: " + rule.Pattern + " (" + rule.Source + ")")
+	if rule.Note != "" {
+		reason.WriteString("\n  Note: " + rule.Note)
+	}
+	reason.WriteString("\n\nHint: This file is auto-generated or managed by tools.")
+	reason.WriteString("\n  - Lock files: Use package manager commands instead")
+	reason.WriteString("\n  - Generated files: Modify source files (or the generator input) instead")
+	reason.WriteString("\n  - Vendor dirs: Don't modify dependencies directly")
+	reason.WriteString("\n  - Hand-written file? Add it to policies.readonlyGuard.allow in config.yaml")
+	return reason.String()
 }
@@ -2,6 +2,8 @@ package hooks
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -211,3 +213,132 @@ func TestReadonlyGuard_ReasonContainsHints(t *testing.T) {
 		t.Error("expected reason to mention package manager")
 	}
 }
+
+func TestReadonlyGuard_ShellWrites(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      string
+		wantDeny bool
+	}{
+		{"redirect to lock file", "echo '{}' > package-lock.json", true},
+		{"append to minified file", "cat extra.js >> dist/app.min.js", true},
+		{"sed -i vendor", "sed -i 's/a/b/' vendor/lib/x.go", true},
+		{"tee into node_modules", "echo x | tee node_modules/pkg/index.js", true},
+		{"cp over generated", "cp types.ts types.d.ts", true},
+		{"mv onto lock file", "mv tmp.lock yarn.lock", true},
+		{"cd then redirect", "cd web && echo x > package-lock.json", true},
+		{"read lock file", "cat package-lock.json | jq .", false},
+		{"regenerate lock file", "npm install", false},
+		{"clean build output", "rm -rf dist build", false},
+		{"create build dir", "mkdir -p build", false},
+		{"redirect to source", "echo x > src/app.js", false},
+		{"override allowed", "echo '{}' > .vscode/launch.json", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := ReadonlyGuard(shellInput(tt.cmd))
+			if tt.wantDeny {
+				if code != 2 || result.Decision != "deny" {
+					t.Fatalf("expected deny for %q, got decision=%s code=%d", tt.cmd, result.Decision, code)
+				}
+				if !strings.Contains(result.Reason, "Command: ") {
+					t.Errorf("expected reason to name the command, got %q", result.Reason)
+				}
+			} else if code != 0 || result.Decision != "allow" {
+				t.Errorf("expected allow for %q, got decision=%s code=%d reason=%q", tt.cmd, result.Decision, code, result.Reason)
+			}
+		})
+	}
+}
+
+func TestReadonlyGuard_GitAware(t *testing.T) {
+	dir := initGitRepo(t)
+	files := map[string]string{
+		"api/gen.pb.go":       "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n",
+		"api/handwritten.go":  "package api\n",
+		"web/schema.ts":       "/* @generated */\nexport type A = string\n",
+		"assets/bundle.js":    "x()\n",
+		"internal/mocks/m.go": "package mocks\n",
+		"openapi/client.go":   "package openapi\n",
+		"src/types/env.d.ts":  "declare const X: string\n",
+		".gitattributes":      "assets/*.js linguist-generated\nassets/keep.js -linguist-generated\n",
+		".readonly":           "# generated mocks\ninternal/mocks/  run make mocks\n",
+	}
+	for name, contents := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	policy := &ReadonlyPolicy{
+		Paths: []string{"openapi/"},
+		Allow: []string{"src/types/**/*.d.ts"},
+	}
+
+	tests := []struct {
+		name       string
+		input      HookInput
+		wantDeny   bool
+		wantReason string
+	}{
+		{"Go generated header", writeInput("api/gen.pb.go", "x"), true, "DO NOT EDIT"},
+		{"@generated header", writeInput("web/schema.ts", "x"), true, "@generated"},
+		{"hand-written neighbour", writeInput("api/handwritten.go", "x"), false, ""},
+		{"linguist-generated", writeInput("assets/bundle.js", "x"), true, ".gitattributes"},
+		{"linguist-generated unset", writeInput("assets/keep.js", "x"), false, ""},
+		{"declarations file", writeInput("internal/mocks/m.go", "x"), true, "run make mocks"},
+		{"policy path", writeInput("openapi/client.go", "x"), true, "policies.readonlyGuard.paths"},
+		{"allow overrides built-in", writeInput("src/types/env.d.ts", "x"), false, ""},
+		{"absolute path", writeInput(filepath.Join(dir, "api/gen.pb.go"), "x"), true, "DO NOT EDIT"},
+		{"shell redirect", shellInput("echo x >> api/gen.pb.go"), true, "Command: "},
+		{"shell cd", shellInput("cd internal && sed -i s/a/b/ mocks/m.go"), true, ".readonly"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := ReadonlyGuardWithPolicy(tt.input, dir, policy)
+			if tt.wantDeny {
+				if code != 2 || result.Decision != "deny" {
+					t.Fatalf("expected deny, got decision=%s code=%d", result.Decision, code)
+				}
+				if !strings.Contains(result.Reason, tt.wantReason) {
+					t.Errorf("expected reason to contain %q, got %q", tt.wantReason, result.Reason)
+				}
+			} else if code != 0 || result.Decision != "allow" {
+				t.Errorf("expected allow, got decision=%s code=%d reason=%q", result.Decision, code, result.Reason)
+			}
+		})
+	}
+
+	t.Run("markers ignored", func(t *testing.T) {
+		result, code := ReadonlyGuardWithPolicy(writeInput("api/gen.pb.go", "x"), dir, &ReadonlyPolicy{IgnoreGeneratedMarkers: true})
+		if code != 0 {
+			t.Errorf("expected allow with ignoreGeneratedMarkers, got %q", result.Reason)
+		}
+	})
+}
+
+func TestMatchRepoGlob(t *testing.T) {
+	tests := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"gen/", "gen/a.go", true},
+		{"gen", "pkg/gen/a.go", true},
+		{"/gen", "pkg/gen/a.go", false},
+		{"api/*.pb.go", "api/x.pb.go", true},
+		{"api/*.pb.go", "api/v1/x.pb.go", false},
+		{"api/**/*.pb.go", "api/v1/x.pb.go", true},
+		{"**/*.d.ts", "src/a.d.ts", true},
+		{"*.d.ts", "src/a.ts", false},
+	}
+	for _, tt := range tests {
+		if got := matchRepoGlob(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("matchRepoGlob(%q, %q) = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
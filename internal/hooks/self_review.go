@@ -1,9 +1,11 @@
 package hooks
 
 import (
-	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+
+	"hooks/internal/transcript"
 )
 
 var selfReviewMarkers = []string{
@@ -14,53 +16,135 @@ var selfReviewMarkers = []string{
 	"edge cases considered",
 }
 
-// SelfReview is a stop hook that checks if a session included self-review markers.
-// It reads the transcript and warns if no markers are found, but always allows (doesn't block).
+// reviewClaim is something the assistant may say about its work, and the
+// commands that would back it up.
+type reviewClaim struct {
+	what     string
+	said     *regexp.Regexp
+	commands *regexp.Regexp
+}
+
+var reviewClaims = []reviewClaim{
+	{
+		what:     "the tests pass",
+		said:     regexp.MustCompile(`(?i)\b(tests?|specs?) (all |now |still )?(pass|passes|passed|passing|succeed|are green)\b`),
+		commands: regexp.MustCompile(`\b(go test|cargo (test|nextest)|pytest|tox|jest|vitest|rspec|ctest|dotnet test|(npm|pnpm|yarn|bun)( run)? test|make test|mvn test|gradlew? test|python3? -m (pytest|unittest))\b`),
+	},
+	{
+		what:     "the build succeeds",
+		said:     regexp.MustCompile(`(?i)\b(builds|compiles|build (passes|succeeds|is green)|compiles? (cleanly|successfully|without errors))\b`),
+		commands: regexp.MustCompile(`\b(go (build|vet|test)|cargo (build|check|test)|tsc|(npm|pnpm|yarn|bun)( run)? build|make|mvn|gradlew?|dotnet build)\b`),
+	},
+}
+
+// SelfReview is a stop hook that checks the session reviewed its own work.
+// For structured transcripts it only looks at sessions that wrote files: it
+// warns when the assistant never reviewed its changes, and when it claimed
+// the tests pass or the build succeeds without running them after its last
+// edit, or after they failed. Plain-text transcripts get the marker check
+// only. It never blocks.
 func SelfReview(input HookInput) (HookResult, int) {
 	if input.StopHookActive() {
 		return NoOp(), 0
 	}
-
 	transcriptPath := input.TranscriptPath()
 	if transcriptPath == "" {
 		return NoOp(), 0
 	}
-
-	if _, err := os.Stat(transcriptPath); err != nil {
+	t, err := transcript.ParseFile(transcriptPath)
+	if err != nil {
 		return NoOp(), 0
 	}
 
-	content, err := os.ReadFile(transcriptPath)
-	if err != nil {
+	filePath := input.FilePath()
+	var concerns []string
+	if t.Format != transcript.FormatText {
+		written := t.FilesWritten()
+		if len(written) == 0 {
+			return NoOp(), 0
+		}
+		if filePath == "" {
+			filePath = written[len(written)-1]
+		}
+		concerns = unbackedClaims(t)
+	}
+	reviewed := hasReviewMarker(t.AssistantText())
+	if reviewed && len(concerns) == 0 {
 		return NoOp(), 0
 	}
 
-	contentLower := strings.ToLower(string(content))
-	hasMarker := false
-	for _, marker := range selfReviewMarkers {
-		if strings.Contains(contentLower, marker) {
-			hasMarker = true
-			break
+	var msg strings.Builder
+	if len(concerns) > 0 {
+		msg.WriteString("Claims not backed by the session:\n")
+		for _, c := range concerns {
+			msg.WriteString("  - " + c + "\n")
 		}
 	}
-
-	if hasMarker {
-		return NoOp(), 0
+	if !reviewed {
+		msg.WriteString("No self-review detected in session. Consider reviewing:\n")
+		for _, q := range generateReviewQuestions(filePath) {
+			msg.WriteString("  - " + q + "\n")
+		}
 	}
+	return NoOpMsg(msg.String()), 0
+}
 
-	// No marker found - generate warning with review questions
-	filePath := input.FilePath()
-	questions := generateReviewQuestions(filePath)
+func hasReviewMarker(text string) bool {
+	text = strings.ToLower(text)
+	for _, marker := range selfReviewMarkers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
 
-	var msg strings.Builder
-	msg.WriteString("No self-review detected in session. Consider reviewing:\n")
-	for _, q := range questions {
-		msg.WriteString("  - ")
-		msg.WriteString(q)
-		msg.WriteString("\n")
+// unbackedClaims compares what the assistant said after its last edit with
+// the commands it ran after that edit.
+func unbackedClaims(t *transcript.Transcript) []string {
+	lastWrite := -1
+	for i, m := range t.Messages {
+		for _, c := range m.ToolCalls {
+			if c.IsWrite() {
+				lastWrite = i
+			}
+		}
+	}
+	if lastWrite < 0 {
+		return nil
+	}
+	results := map[string]transcript.Call{}
+	for _, c := range t.Calls() {
+		results[c.ID] = c
 	}
 
-	return NoOpMsg(msg.String()), 0
+	var concerns []string
+	for _, claim := range reviewClaims {
+		said := false
+		for _, m := range t.Messages[lastWrite:] {
+			if m.Role == "assistant" && claim.said.MatchString(m.Text) {
+				said = true
+			}
+		}
+		if !said {
+			continue
+		}
+		var last *transcript.Call
+		for _, m := range t.Messages[lastWrite:] {
+			for _, tc := range m.ToolCalls {
+				if c := results[tc.ID]; c.IsShell() && claim.commands.MatchString(c.Command()) {
+					last = &c
+				}
+			}
+		}
+		switch {
+		case last == nil:
+			concerns = append(concerns, "you said "+claim.what+", but nothing was run to check it after the last edit")
+		case last.Failed():
+			concerns = append(concerns, "you said "+claim.what+", but `"+shortCommand(last.Command())+"` failed")
+		}
+	}
+	return concerns
 }
 
 func generateReviewQuestions(filePath string) []string {
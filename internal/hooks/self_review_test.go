@@ -112,3 +112,92 @@ func TestSelfReview_MultipleMarkers(t *testing.T) {
 		t.Errorf("expected no reason when marker found, got %q", result.Reason)
 	}
 }
+
+// writeTranscript writes Claude Code JSONL lines to a temp transcript.
+func writeTranscript(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "transcript.jsonl")
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	return path
+}
+
+const (
+	trEdit   = `{"type":"assistant","message":{"id":"m1","role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Edit","input":{"file_path":"app.py"}}]}}`
+	trEditOK = `{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}`
+)
+
+func TestSelfReview_Structured(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		want    []string
+		wantNot []string
+	}{
+		{
+			name:  "no writes",
+			lines: []string{`{"type":"assistant","message":{"id":"m0","role":"assistant","content":[{"type":"text","text":"The answer is 4."}]}}`},
+		},
+		{
+			name: "reviewed and verified",
+			lines: []string{trEdit, trEditOK,
+				`{"type":"assistant","message":{"id":"m2","role":"assistant","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"pytest"}}]}}`,
+				`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t2","content":"3 passed"}]}}`,
+				`{"type":"assistant","message":{"id":"m3","role":"assistant","content":[{"type":"text","text":"Self-review: all tests pass."}]}}`,
+			},
+		},
+		{
+			name: "marker only in user prompt",
+			lines: []string{`{"type":"user","message":{"role":"user","content":"do a self-review when done"}}`, trEdit, trEditOK,
+				`{"type":"assistant","message":{"id":"m2","role":"assistant","content":[{"type":"text","text":"Changed it."}]}}`,
+			},
+			want: []string{"No self-review detected", "type hints"},
+		},
+		{
+			name: "claims tests pass without running them",
+			lines: []string{trEdit, trEditOK,
+				`{"type":"assistant","message":{"id":"m2","role":"assistant","content":[{"type":"text","text":"Implementation complete, tests pass."}]}}`,
+			},
+			want:    []string{"you said the tests pass, but nothing was run"},
+			wantNot: []string{"No self-review detected"},
+		},
+		{
+			name: "claims tests pass after they failed",
+			lines: []string{trEdit, trEditOK,
+				`{"type":"assistant","message":{"id":"m2","role":"assistant","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"pytest -q"}}]}}`,
+				`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t2","content":"1 failed","is_error":true}]}}`,
+				`{"type":"assistant","message":{"id":"m3","role":"assistant","content":[{"type":"text","text":"Self-review done. All tests passed."}]}}`,
+			},
+			want: []string{"`pytest -q` failed"},
+		},
+		{
+			name: "tests ran before the last edit",
+			lines: []string{
+				`{"type":"assistant","message":{"id":"m0","role":"assistant","content":[{"type":"tool_use","id":"t0","name":"Bash","input":{"command":"pytest"}}]}}`,
+				trEdit, trEditOK,
+				`{"type":"assistant","message":{"id":"m2","role":"assistant","content":[{"type":"text","text":"Critical review done; the tests pass."}]}}`,
+			},
+			want: []string{"nothing was run to check it after the last edit"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := SelfReview(stopInput(writeTranscript(t, tt.lines...), "", false))
+			if code != 0 || result.Decision != "" {
+				t.Fatalf("expected NoOp, got %+v code=%d", result, code)
+			}
+			if len(tt.want) == 0 && result.Reason != "" {
+				t.Errorf("expected no reason, got %q", result.Reason)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(result.Reason, w) {
+					t.Errorf("reason %q does not contain %q", result.Reason, w)
+				}
+			}
+			for _, w := range tt.wantNot {
+				if strings.Contains(result.Reason, w) {
+					t.Errorf("reason %q should not contain %q", result.Reason, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SessionBudgetPolicy configures session-budget (policies.sessionBudget in
+// config.yaml). A zero limit is no limit.
+type SessionBudgetPolicy struct {
+	MaxToolCalls     int `yaml:"maxToolCalls,omitempty" json:"maxToolCalls,omitempty"`
+	MaxMinutes       int `yaml:"maxMinutes,omitempty" json:"maxMinutes,omitempty"`
+	MaxFilesWritten  int `yaml:"maxFilesWritten,omitempty" json:"maxFilesWritten,omitempty"`
+	MaxLinesChanged  int `yaml:"maxLinesChanged,omitempty" json:"maxLinesChanged,omitempty"`
+	MaxShellCommands int `yaml:"maxShellCommands,omitempty" json:"maxShellCommands,omitempty"`
+	// Action is what happens at a limit: deny (default) or ask.
+	Action string `yaml:"action,omitempty" json:"action,omitempty"`
+}
+
+const sessionBudgetFile = "budget.json"
+
+// sessionBudgetState is what a session has used so far.
+type sessionBudgetState struct {
+	Started       time.Time `json:"started"`
+	ToolCalls     int       `json:"tool_calls"`
+	ShellCommands int       `json:"shell_commands"`
+	Files         []string  `json:"files,omitempty"`
+	LinesChanged  int       `json:"lines_changed"`
+}
+
+// SessionBudget is a preToolUse hook that stops a session once it has used up
+// a limit from policy: tool calls, wall-clock minutes since its first tool
+// call, distinct files written, lines changed by writes, or shell commands.
+// Usage is counted per session under stateDir as calls are requested. A call
+// that would go over a limit is denied (and not counted), or with action ask
+// put to the user; the reason lists usage against every limit.
+func SessionBudget(input HookInput, workDir, stateDir string, policy *SessionBudgetPolicy) (HookResult, int) {
+	if policy == nil || input.ToolName == "" || stateDir == "" {
+		return Allow(), 0
+	}
+	if policy.MaxToolCalls <= 0 && policy.MaxMinutes <= 0 && policy.MaxFilesWritten <= 0 &&
+		policy.MaxLinesChanged <= 0 && policy.MaxShellCommands <= 0 {
+		return Allow(), 0
+	}
+	dir := SessionStateDir(stateDir, input.SessionID())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Allow(), 0
+	}
+
+	unlock, ok := lockStateFile(filepath.Join(dir, sessionBudgetFile))
+	if !ok {
+		return Allow(), 0
+	}
+	defer unlock()
+
+	now := time.Now()
+	used := loadSessionBudget(dir)
+	if used.Started.IsZero() {
+		used.Started = now
+	}
+	next := used
+	next.Files = append([]string(nil), used.Files...)
+	next.ToolCalls++
+	switch input.ToolName {
+	case "Shell":
+		next.ShellCommands++
+	case "Write", "Edit", "MultiEdit":
+		path, lines := writeChange(input, workDir)
+		if path != "" && !hasString(next.Files, path) {
+			next.Files = append(next.Files, path)
+		}
+		next.LinesChanged += lines
+	}
+
+	minutes := int(now.Sub(used.Started).Minutes())
+	var over []string
+	check := func(name string, n, limit int) {
+		if limit > 0 && n > limit {
+			over = append(over, fmt.Sprintf("%s %d/%d", name, n, limit))
+		}
+	}
+	check("tool calls", next.ToolCalls, policy.MaxToolCalls)
+	if policy.MaxMinutes > 0 && minutes >= policy.MaxMinutes {
+		over = append(over, fmt.Sprintf("minutes %d/%d", minutes, policy.MaxMinutes))
+	}
+	check("files written", len(next.Files), policy.MaxFilesWritten)
+	check("lines changed", next.LinesChanged, policy.MaxLinesChanged)
+	check("shell commands", next.ShellCommands, policy.MaxShellCommands)
+
+	if len(over) == 0 {
+		saveSessionBudget(dir, next)
+		return Allow(), 0
+	}
+	reason := "Session budget reached (" + strings.Join(over, ", ") + "). Usage: " + budgetUsage(next, minutes, policy) +
+		". Limits are set in policies.sessionBudget."
+	if policy.Action == "ask" {
+		saveSessionBudget(dir, next)
+		return Ask(reason + " Continue anyway?"), 0
+	}
+	saveSessionBudget(dir, used)
+	return Deny("Blocked: " + reason + " Finish up and hand back to the user."), 2
+}
+
+// budgetUsage lists usage against each configured limit.
+func budgetUsage(s sessionBudgetState, minutes int, p *SessionBudgetPolicy) string {
+	var parts []string
+	add := func(name string, n, limit int) {
+		if limit > 0 {
+			parts = append(parts, fmt.Sprintf("%d/%d %s", n, limit, name))
+		}
+	}
+	add("tool calls", s.ToolCalls, p.MaxToolCalls)
+	add("minutes", minutes, p.MaxMinutes)
+	add("files written", len(s.Files), p.MaxFilesWritten)
+	add("lines changed", s.LinesChanged, p.MaxLinesChanged)
+	add("shell commands", s.ShellCommands, p.MaxShellCommands)
+	return strings.Join(parts, ", ")
+}
+
+// writeChange returns the file a Write/Edit/MultiEdit call changes and how
+// many lines it adds or removes.
+func writeChange(input HookInput, workDir string) (string, int) {
+	var ti struct {
+		Path      string `json:"path"`
+		FilePath  string `json:"file_path"`
+		Contents  string `json:"contents"`
+		Content   string `json:"content"`
+		OldString string `json:"old_string"`
+		NewString string `json:"new_string"`
+		Edits     []struct {
+			OldString string `json:"old_string"`
+			NewString string `json:"new_string"`
+		} `json:"edits"`
+	}
+	json.Unmarshal(input.ToolInput, &ti)
+	path := ti.Path
+	if path == "" {
+		path = ti.FilePath
+	}
+	if path == "" {
+		return "", 0
+	}
+	if !filepath.IsAbs(path) && workDir != "" {
+		path = filepath.Join(workDir, path)
+	}
+
+	switch input.ToolName {
+	case "Write":
+		contents := ti.Contents
+		if contents == "" {
+			contents = ti.Content
+		}
+		old, _ := os.ReadFile(path)
+		return path, changedLines(string(old), contents)
+	case "Edit":
+		return path, changedLines(ti.OldString, ti.NewString)
+	}
+	lines := 0
+	for _, e := range ti.Edits {
+		lines += changedLines(e.OldString, e.NewString)
+	}
+	return path, lines
+}
+
+// changedLines counts the lines only in before plus the lines only in after,
+// ignoring order: roughly the added and removed lines of a diff.
+func changedLines(before, after string) int {
+	count := map[string]int{}
+	for _, l := range splitLines(before) {
+		count[l]++
+	}
+	changed := 0
+	for _, l := range splitLines(after) {
+		if count[l] > 0 {
+			count[l]--
+		} else {
+			changed++
+		}
+	}
+	for _, n := range count {
+		changed += n
+	}
+	return changed
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func loadSessionBudget(dir string) sessionBudgetState {
+	var s sessionBudgetState
+	if data, err := os.ReadFile(filepath.Join(dir, sessionBudgetFile)); err == nil {
+		json.Unmarshal(data, &s)
+	}
+	return s
+}
+
+func saveSessionBudget(dir string, s sessionBudgetState) {
+	data, _ := json.Marshal(s)
+	writeFileAtomic(filepath.Join(dir, sessionBudgetFile), data)
+}
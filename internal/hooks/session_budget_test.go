@@ -0,0 +1,178 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func budgetInput(tool string, fields map[string]string) HookInput {
+	m := map[string]string{"session_id": "s1"}
+	for k, v := range fields {
+		m[k] = v
+	}
+	ti, _ := json.Marshal(m)
+	return HookInput{ToolName: tool, ToolInput: ti}
+}
+
+func TestSessionBudget_NoLimits(t *testing.T) {
+	for _, policy := range []*SessionBudgetPolicy{nil, {}} {
+		result, code := SessionBudget(shellInput("ls"), t.TempDir(), t.TempDir(), policy)
+		if code != 0 || result.Decision != "allow" {
+			t.Errorf("expected allow, got %+v", result)
+		}
+	}
+}
+
+func TestSessionBudget_Limits(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy SessionBudgetPolicy
+		calls  []HookInput
+		want   string // reason fragment of the last call; empty means allowed
+	}{
+		{
+			name:   "tool calls",
+			policy: SessionBudgetPolicy{MaxToolCalls: 2},
+			calls:  []HookInput{budgetInput("Read", nil), budgetInput("Read", nil), budgetInput("Read", nil)},
+			want:   "tool calls 3/2",
+		},
+		{
+			name:   "shell commands",
+			policy: SessionBudgetPolicy{MaxShellCommands: 1, MaxToolCalls: 10},
+			calls:  []HookInput{budgetInput("Shell", map[string]string{"command": "ls"}), budgetInput("Read", nil), budgetInput("Shell", map[string]string{"command": "pwd"})},
+			want:   "Usage: 3/10 tool calls, 2/1 shell commands",
+		},
+		{
+			name:   "same file twice counts once",
+			policy: SessionBudgetPolicy{MaxFilesWritten: 1},
+			calls:  []HookInput{budgetInput("Write", map[string]string{"path": "a.go", "contents": "x"}), budgetInput("Edit", map[string]string{"file_path": "a.go", "old_string": "x", "new_string": "y"})},
+		},
+		{
+			name:   "files written",
+			policy: SessionBudgetPolicy{MaxFilesWritten: 1},
+			calls:  []HookInput{budgetInput("Write", map[string]string{"path": "a.go", "contents": "x"}), budgetInput("Write", map[string]string{"path": "b.go", "contents": "x"})},
+			want:   "files written 2/1",
+		},
+		{
+			name:   "lines changed",
+			policy: SessionBudgetPolicy{MaxLinesChanged: 4},
+			calls: []HookInput{
+				budgetInput("Write", map[string]string{"path": "a.go", "contents": "1\n2\n3\n"}),
+				budgetInput("Edit", map[string]string{"file_path": "a.go", "old_string": "2\n", "new_string": "two\n"}),
+			},
+			want: "lines changed 5/4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			work, state := t.TempDir(), t.TempDir()
+			var result HookResult
+			var code int
+			for _, c := range tt.calls {
+				result, code = SessionBudget(c, work, state, &tt.policy)
+			}
+			if tt.want == "" {
+				if result.Decision != "allow" {
+					t.Errorf("expected allow, got %+v", result)
+				}
+				return
+			}
+			if result.Decision != "deny" || code != 2 || !strings.HasPrefix(result.Reason, "Blocked:") {
+				t.Fatalf("expected deny, got %+v code=%d", result, code)
+			}
+			if !strings.Contains(result.Reason, tt.want) {
+				t.Errorf("reason %q does not contain %q", result.Reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionBudget_DeniedCallNotCounted(t *testing.T) {
+	state := t.TempDir()
+	policy := &SessionBudgetPolicy{MaxToolCalls: 1}
+	SessionBudget(budgetInput("Read", nil), "", state, policy)
+	SessionBudget(budgetInput("Read", nil), "", state, policy)
+	SessionBudget(budgetInput("Read", nil), "", state, policy)
+	if n := loadSessionBudget(SessionStateDir(state, "s1")).ToolCalls; n != 1 {
+		t.Errorf("tool calls = %d, want 1", n)
+	}
+}
+
+func TestSessionBudget_AskCounts(t *testing.T) {
+	state := t.TempDir()
+	policy := &SessionBudgetPolicy{MaxToolCalls: 1, Action: "ask"}
+	SessionBudget(budgetInput("Read", nil), "", state, policy)
+	result, code := SessionBudget(budgetInput("Read", nil), "", state, policy)
+	if result.Decision != "ask" || code != 0 {
+		t.Fatalf("expected ask, got %+v code=%d", result, code)
+	}
+	if n := loadSessionBudget(SessionStateDir(state, "s1")).ToolCalls; n != 2 {
+		t.Errorf("tool calls = %d, want 2", n)
+	}
+}
+
+func TestSessionBudget_ConcurrentCalls(t *testing.T) {
+	stateDir := t.TempDir()
+	policy := &SessionBudgetPolicy{MaxToolCalls: 1000}
+	const calls = 40
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			SessionBudget(budgetInput("Read", nil), t.TempDir(), stateDir, policy)
+		}()
+	}
+	wg.Wait()
+
+	dir := SessionStateDir(stateDir, "s1")
+	if got := loadSessionBudget(dir).ToolCalls; got != calls {
+		t.Errorf("tool calls = %d, want %d", got, calls)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(matches) > 0 || exists(filepath.Join(dir, sessionBudgetFile+".lock")) {
+		t.Errorf("temp or lock files left behind: %v", matches)
+	}
+}
+
+func TestSessionBudget_WallClock(t *testing.T) {
+	state := t.TempDir()
+	dir := SessionStateDir(state, "s1")
+	os.MkdirAll(dir, 0755)
+	saveSessionBudget(dir, sessionBudgetState{Started: time.Now().Add(-45 * time.Minute)})
+
+	result, _ := SessionBudget(budgetInput("Read", nil), "", state, &SessionBudgetPolicy{MaxMinutes: 30})
+	if result.Decision != "deny" || !strings.Contains(result.Reason, "minutes 45/30") {
+		t.Errorf("expected wall-clock deny, got %+v", result)
+	}
+}
+
+func TestChangedLines(t *testing.T) {
+	tests := []struct {
+		before, after string
+		want          int
+	}{
+		{"", "a\nb\n", 2},
+		{"a\nb\n", "a\nb\n", 0},
+		{"a\nb\nc\n", "a\nx\nc\n", 2},
+		{"a\n", "", 1},
+	}
+	for _, tt := range tests {
+		if got := changedLines(tt.before, tt.after); got != tt.want {
+			t.Errorf("changedLines(%q, %q) = %d, want %d", tt.before, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestWriteChange_ExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("1\n2\n3\n"), 0644)
+	path, lines := writeChange(budgetInput("Write", map[string]string{"path": "a.txt", "contents": "1\n2\n3\n4\n"}), dir)
+	if path != filepath.Join(dir, "a.txt") || lines != 1 {
+		t.Errorf("writeChange = %q, %d", path, lines)
+	}
+}
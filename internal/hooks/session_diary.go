@@ -1,95 +1,533 @@
 package hooks
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"hooks/internal/transcript"
 )
 
+// SessionDiaryPolicy configures session-diary (policies.sessionDiary in
+// config.yaml).
+type SessionDiaryPolicy struct {
+	// Index keeps an index.md next to each repo's diaries linking all of them.
+	Index bool `yaml:"index,omitempty" json:"index,omitempty"`
+}
+
 var auditLineRe = regexp.MustCompile(`\[([^\]]+)\]\s+tool=(\S+)\s*(.*)`)
 
-// SessionDiary is a stop hook that summarizes the session from the audit log.
+var (
+	// exitCodeRe finds the exit status in a failed shell call's output.
+	exitCodeRe = regexp.MustCompile(`(?i)\bexit(?:ed with)? (?:code|status)[: ]*(-?\d+)`)
+	// hookBlockRe finds a hook's deny reason in a tool result; hookNameRe the
+	// hook command before it, as in "[/path/to/validate-shell]: Blocked: ...".
+	hookBlockRe = regexp.MustCompile(`Blocked: [^\n"]+`)
+	hookNameRe  = regexp.MustCompile(`\[(?:[^\]\s]*/)?([\w.-]+)\]:`)
+	hunkRe      = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)`)
+)
+
+// sessionSummary is what the diary records about a session. It is also the
+// diary's JSON form.
+type sessionSummary struct {
+	SessionID      string            `json:"session_id,omitempty"`
+	Repo           string            `json:"repo,omitempty"`
+	Cwd            string            `json:"cwd,omitempty"`
+	Start          time.Time         `json:"start"`
+	End            time.Time         `json:"end"`
+	ElapsedSeconds int64             `json:"elapsed_seconds"`
+	Calls          int               `json:"tool_calls"`
+	ToolCounts     map[string]int    `json:"tools"`
+	Files          []diaryFile       `json:"files_written"`
+	Commands       []diaryCommand    `json:"commands"`
+	Blocked        []diaryBlock      `json:"blocked"`
+	TODOs          []string          `json:"todos_added"`
+	Usage          *transcript.Usage `json:"usage,omitempty"`
+	CostUSD        *float64          `json:"cost_usd,omitempty"`
+}
+
+// diaryFile is a file the session wrote, with its diff against the commit
+// the session started from. Status is changed, new (untracked), deleted,
+// binary or unchanged; empty when git knows nothing about it.
+type diaryFile struct {
+	Path    string `json:"path"`
+	Status  string `json:"status,omitempty"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+}
+
+// diaryCommand is a shell command and, when the transcript has its result,
+// how it ended.
+type diaryCommand struct {
+	Command  string `json:"command"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Failed   bool   `json:"failed,omitempty"`
+	Blocked  bool   `json:"blocked,omitempty"`
+}
+
+// diaryBlock is a tool call a hook denied.
+type diaryBlock struct {
+	Tool   string `json:"tool"`
+	Hook   string `json:"hook,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// SessionDiary is a stop hook that summarizes the session. It reads the
+// session transcript when there is one, and today's audit log otherwise.
 func SessionDiary(input HookInput, auditDir, diaryDir string) (HookResult, int) {
+	return SessionDiaryWithState(input, auditDir, "", "", diaryDir, nil)
+}
+
+// SessionDiaryWithState writes a diary for the session in input, as Markdown
+// and JSON under diaryDir/<repo>/. Each session has its own diary, rewritten
+// on every stop. The session is read from its transcript, or its activity log
+// under stateDir; only without a session id does it fall back to today's
+// audit log. Written files get diff stats and added TODOs from git, and the
+// session's cost comes from cost-estimator's totals in costDir. With
+// policy.Index, index.md in the repo's diary directory links all of them.
+func SessionDiaryWithState(input HookInput, auditDir, stateDir, costDir, diaryDir string, policy *SessionDiaryPolicy) (HookResult, int) {
 	if err := os.MkdirAll(diaryDir, 0755); err != nil {
 		return NoOp(), 0
 	}
 
-	today := time.Now().Format("2006-01-02")
-	auditFile := filepath.Join(auditDir, "audit-"+today+".log")
+	session := input.SessionID()
+	s, ok := summarizeTranscript(input.TranscriptPath())
+	switch {
+	case ok:
+	case session != "" && stateDir != "":
+		s = summarizeActivity(loadSessionEvents(stateDir, session))
+		if s.Calls == 0 {
+			return NoOpMsg("No activity recorded for this session"), 0
+		}
+	default:
+		today := time.Now().Format("2006-01-02")
+		data, err := os.ReadFile(filepath.Join(auditDir, "audit-"+today+".log"))
+		if err != nil {
+			return NoOpMsg("No audit log found for today"), 0
+		}
+		s = summarizeAuditLog(string(data))
+		if s.Calls == 0 {
+			return NoOpMsg("Empty audit log"), 0
+		}
+	}
+	if session != "" {
+		s.SessionID = session
+	}
+	if cwd := input.Cwd(); cwd != "" {
+		s.Cwd = cwd
+	}
+	if s.Cwd == "" {
+		s.Cwd, _ = os.Getwd()
+	}
+	s.Repo = repoNameFromCwd(s.Cwd)
+	if !s.Start.IsZero() {
+		s.ElapsedSeconds = int64(s.End.Sub(s.Start).Seconds())
+	}
+	for i, f := range s.Files {
+		if rel, err := filepath.Rel(s.Cwd, f.Path); err == nil && filepath.IsAbs(f.Path) && !strings.HasPrefix(rel, "..") {
+			s.Files[i].Path = rel
+		}
+	}
+	addDiffStats(&s)
+	if costDir != "" && s.SessionID != "" {
+		if c := loadCostTotals(costDir).Sessions[s.SessionID]; c != nil {
+			cost := c.CostUSD
+			s.CostUSD = &cost
+			if s.Usage == nil && c.Usage.Total() > 0 {
+				u := c.Usage
+				s.Usage = &u
+			}
+		}
+	}
 
-	data, err := os.ReadFile(auditFile)
-	if err != nil {
-		return NoOpMsg("No audit log found for today"), 0
+	dir := filepath.Join(diaryDir, s.Repo)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return NoOp(), 0
+	}
+	name := diaryName(s)
+	data, _ := json.MarshalIndent(s, "", "  ")
+	os.WriteFile(filepath.Join(dir, name+".json"), data, 0644)
+	os.WriteFile(filepath.Join(dir, name+".md"), []byte(formatDiary(s)), 0644)
+	if policy != nil && policy.Index {
+		writeDiaryIndex(dir, s.Repo)
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
-	if len(lines) == 0 {
-		return NoOpMsg("Empty audit log"), 0
+	return NoOpMsg(fmt.Sprintf("Session diary written: %d tool calls, %d files", s.Calls, len(s.Files))), 0
+}
+
+// diaryName names a session's diary files after its start and id, so every
+// stop in a session rewrites the same diary.
+func diaryName(s sessionSummary) string {
+	start := s.Start
+	if start.IsZero() {
+		start = time.Now()
 	}
+	name := "session-" + start.Local().Format("2006-01-02-150405")
+	if id := strings.Map(safeNameRune, s.SessionID); id != "" {
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		name += "-" + id
+	}
+	return name
+}
+
+func safeNameRune(r rune) rune {
+	if r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+		return r
+	}
+	return -1
+}
+
+// summarizeTranscript builds the summary from a structured transcript.
+func summarizeTranscript(path string) (sessionSummary, bool) {
+	if path == "" {
+		return sessionSummary{}, false
+	}
+	t, err := transcript.ParseFile(path)
+	if err != nil || t.Format == transcript.FormatText {
+		return sessionSummary{}, false
+	}
+	s := sessionSummary{SessionID: t.SessionID, Cwd: t.Cwd, ToolCounts: map[string]int{}}
+	for _, c := range t.Calls() {
+		s.Calls++
+		s.ToolCounts[c.Name]++
 
-	// Aggregate stats
-	toolCounts := make(map[string]int)
-	var filesWritten []string
-	var commands []string
+		var block *diaryBlock
+		if c.Failed() {
+			if i := hookBlockRe.FindStringIndex(c.Result.Content); i != nil {
+				block = &diaryBlock{Tool: c.Name, Reason: strings.TrimSpace(c.Result.Content[i[0]:i[1]])}
+				if m := hookNameRe.FindAllStringSubmatch(c.Result.Content[:i[0]], -1); m != nil {
+					block.Hook = m[len(m)-1][1]
+				}
+				s.Blocked = append(s.Blocked, *block)
+			}
+		}
+		if !c.IsShell() {
+			continue
+		}
+		cmd := diaryCommand{Command: shortCommand(c.Command()), Blocked: block != nil}
+		if c.Result != nil && block == nil {
+			code := 0
+			if c.Failed() {
+				cmd.Failed = true
+				code = -1
+				if m := exitCodeRe.FindStringSubmatch(c.Result.Content); m != nil {
+					code, _ = strconv.Atoi(m[1])
+				}
+			}
+			if code >= 0 {
+				cmd.ExitCode = &code
+			}
+		}
+		s.Commands = append(s.Commands, cmd)
+	}
+	for _, p := range t.FilesWritten() {
+		s.Files = append(s.Files, diaryFile{Path: p})
+	}
+	s.Start, s.End = t.Span()
+	if u := t.Usage(); u.Total() > 0 {
+		s.Usage = &u
+	}
+	return s, true
+}
+
+// summarizeActivity builds the summary from the session's activity log.
+func summarizeActivity(events []SessionEvent) sessionSummary {
+	s := sessionSummary{ToolCounts: map[string]int{}}
+	var written []string
+	for _, ev := range events {
+		s.Calls++
+		s.ToolCounts[ev.Tool]++
+		if s.Start.IsZero() {
+			s.Start = ev.Time
+		}
+		s.End = ev.Time
+		if ev.Cwd != "" {
+			s.Cwd = ev.Cwd
+		}
+		switch ev.Tool {
+		case "Write", "Edit", "MultiEdit":
+			if ev.Path != "" && !hasString(written, ev.Path) {
+				written = append(written, ev.Path)
+			}
+		case "Shell":
+			if ev.Command != "" {
+				s.Commands = append(s.Commands, diaryCommand{Command: shortCommand(ev.Command)})
+			}
+		}
+	}
+	for _, p := range written {
+		s.Files = append(s.Files, diaryFile{Path: p})
+	}
+	return s
+}
 
-	for _, line := range lines {
+// summarizeAuditLog builds the summary from audit log lines.
+func summarizeAuditLog(data string) sessionSummary {
+	s := sessionSummary{ToolCounts: map[string]int{}}
+	var written []string
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
 		m := auditLineRe.FindStringSubmatch(line)
 		if m == nil {
 			continue
 		}
-		tool := m[2]
-		detail := m[3]
-		toolCounts[tool]++
+		tool, detail := m[2], m[3]
+		s.Calls++
+		s.ToolCounts[tool]++
+		if t, err := time.ParseInLocation(timeLayout, m[1], time.Local); err == nil {
+			if s.Start.IsZero() {
+				s.Start = t
+			}
+			s.End = t
+		}
 
 		switch tool {
 		case "Write":
-			if strings.HasPrefix(detail, "path=") {
-				filesWritten = append(filesWritten, strings.TrimPrefix(detail, "path="))
+			if p := strings.TrimPrefix(detail, "path="); p != detail && !hasString(written, p) {
+				written = append(written, p)
 			}
 		case "Shell":
-			if strings.HasPrefix(detail, "command=") {
-				cmd := strings.TrimPrefix(detail, "command=")
-				if len(cmd) > 80 {
-					cmd = cmd[:80] + "..."
+			if c := strings.TrimPrefix(detail, "command="); c != detail {
+				s.Commands = append(s.Commands, diaryCommand{Command: shortCommand(c)})
+			}
+		}
+	}
+	for _, p := range written {
+		s.Files = append(s.Files, diaryFile{Path: p})
+	}
+	return s
+}
+
+// addDiffStats fills in each written file's diff against the commit HEAD was
+// at when the session started (so work committed during the session still
+// counts), and collects the TODO/FIXME/HACK lines it added.
+func addDiffStats(s *sessionSummary) {
+	if s.Cwd == "" || gitOutput(s.Cwd, "rev-parse", "--is-inside-work-tree") != "true" {
+		return
+	}
+	base := "HEAD"
+	if !s.Start.IsZero() {
+		if c := gitOutput(s.Cwd, "rev-list", "-1", "--before="+s.Start.Format(time.RFC3339), "HEAD"); c != "" {
+			base = c
+		}
+	}
+	hasBase := gitOutput(s.Cwd, "rev-parse", "--verify", "-q", base) != ""
+
+	for i := range s.Files {
+		f := &s.Files[i]
+		path := f.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(s.Cwd, path)
+		}
+		_, statErr := os.Stat(path)
+
+		numstat := ""
+		if hasBase {
+			numstat = gitOutput(s.Cwd, "diff", "--numstat", base, "--", path)
+		}
+		if fields := strings.Fields(numstat); len(fields) >= 2 {
+			if fields[0] == "-" {
+				f.Status = "binary"
+				continue
+			}
+			f.Added, _ = strconv.Atoi(fields[0])
+			f.Removed, _ = strconv.Atoi(fields[1])
+			f.Status = "changed"
+			if statErr != nil {
+				f.Status = "deleted"
+			}
+			s.TODOs = append(s.TODOs, addedTODOs(f.Path, gitOutput(s.Cwd, "diff", "-U0", base, "--", path))...)
+			continue
+		}
+		if gitOutput(s.Cwd, "ls-files", "--", path) != "" {
+			f.Status = "unchanged"
+			continue
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			f.Status = "new"
+			lines := splitLines(string(data))
+			f.Added = len(lines)
+			for n, line := range lines {
+				if m := todoRe.FindString(line); m != "" {
+					s.TODOs = append(s.TODOs, fmt.Sprintf("%s:%d: %s", f.Path, n+1, strings.TrimSpace(m)))
 				}
-				commands = append(commands, cmd)
 			}
 		}
 	}
+}
+
+// addedTODOs returns the TODO/FIXME/HACK comments on the added lines of a
+// zero-context diff, as "path:line: comment".
+func addedTODOs(path, diff string) []string {
+	var todos []string
+	line := 0
+	for _, l := range strings.Split(diff, "\n") {
+		if m := hunkRe.FindStringSubmatch(l); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if !strings.HasPrefix(l, "+") || strings.HasPrefix(l, "+++") {
+			continue
+		}
+		if m := todoRe.FindString(l[1:]); m != "" {
+			todos = append(todos, fmt.Sprintf("%s:%d: %s", path, line, strings.TrimSpace(m)))
+		}
+		line++
+	}
+	return todos
+}
 
-	// Build diary
+func formatDiary(s sessionSummary) string {
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("# Session Diary — %s\n\n", time.Now().Format("2006-01-02 15:04:05")))
+	when := time.Now()
+	if !s.Start.IsZero() {
+		when = s.Start
+	}
+	sb.WriteString(fmt.Sprintf("# Session Diary — %s\n\n", when.Local().Format("2006-01-02 15:04:05")))
+	if s.SessionID != "" {
+		sb.WriteString(fmt.Sprintf("Session: %s\n", s.SessionID))
+	}
+	if s.Repo != "" {
+		sb.WriteString(fmt.Sprintf("Repo: %s\n", s.Repo))
+	}
+	if !s.Start.IsZero() {
+		sb.WriteString(fmt.Sprintf("Duration: %s (%s – %s)\n", s.End.Sub(s.Start).Round(time.Second), s.Start.Local().Format("15:04"), s.End.Local().Format("15:04")))
+	}
+	if s.CostUSD != nil {
+		sb.WriteString(fmt.Sprintf("Estimated cost: $%.2f\n", *s.CostUSD))
+	}
+	sb.WriteString("\n")
 
 	sb.WriteString("## Tool Usage\n")
-	for tool, count := range toolCounts {
-		sb.WriteString(fmt.Sprintf("- %s: %d calls\n", tool, count))
+	tools := make([]string, 0, len(s.ToolCounts))
+	for tool := range s.ToolCounts {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	for _, tool := range tools {
+		sb.WriteString(fmt.Sprintf("- %s: %d calls\n", tool, s.ToolCounts[tool]))
 	}
 
-	if len(filesWritten) > 0 {
+	if len(s.Files) > 0 {
 		sb.WriteString("\n## Files Written\n")
-		seen := make(map[string]bool)
-		for _, f := range filesWritten {
-			if !seen[f] {
-				sb.WriteString(fmt.Sprintf("- %s\n", f))
-				seen[f] = true
+		for _, f := range s.Files {
+			switch f.Status {
+			case "changed", "new", "deleted":
+				sb.WriteString(fmt.Sprintf("- %s (%s, +%d -%d)\n", f.Path, f.Status, f.Added, f.Removed))
+			case "binary", "unchanged":
+				sb.WriteString(fmt.Sprintf("- %s (%s)\n", f.Path, f.Status))
+			default:
+				sb.WriteString(fmt.Sprintf("- %s\n", f.Path))
 			}
 		}
 	}
 
-	if len(commands) > 0 {
+	if len(s.Commands) > 0 {
 		sb.WriteString("\n## Commands Run\n")
-		for _, c := range commands {
-			sb.WriteString(fmt.Sprintf("- `%s`\n", c))
+		for _, c := range s.Commands {
+			switch {
+			case c.Blocked:
+				sb.WriteString(fmt.Sprintf("- `%s` (blocked)\n", c.Command))
+			case c.ExitCode != nil:
+				sb.WriteString(fmt.Sprintf("- `%s` (exit %d)\n", c.Command, *c.ExitCode))
+			case c.Failed:
+				sb.WriteString(fmt.Sprintf("- `%s` (failed)\n", c.Command))
+			default:
+				sb.WriteString(fmt.Sprintf("- `%s`\n", c.Command))
+			}
+		}
+	}
+
+	if len(s.Blocked) > 0 {
+		sb.WriteString("\n## Blocked by Hooks\n")
+		for _, b := range s.Blocked {
+			if b.Hook != "" {
+				sb.WriteString(fmt.Sprintf("- %s (%s): %s\n", b.Tool, b.Hook, b.Reason))
+			} else {
+				sb.WriteString(fmt.Sprintf("- %s: %s\n", b.Tool, b.Reason))
+			}
+		}
+	}
+
+	if len(s.TODOs) > 0 {
+		sb.WriteString("\n## TODOs Added\n")
+		for _, t := range s.TODOs {
+			sb.WriteString(fmt.Sprintf("- %s\n", t))
 		}
 	}
 
-	// Write diary
-	diaryFile := filepath.Join(diaryDir, fmt.Sprintf("session-%s.md", time.Now().Format("2006-01-02-150405")))
-	os.WriteFile(diaryFile, []byte(sb.String()), 0644)
+	if u := s.Usage; u != nil {
+		sb.WriteString("\n## Token Usage\n")
+		sb.WriteString(fmt.Sprintf("- Input: %d\n- Output: %d\n- Cache write: %d\n- Cache read: %d\n",
+			u.InputTokens, u.OutputTokens, u.CacheCreationInputTokens, u.CacheReadInputTokens))
+	}
+	return sb.String()
+}
 
-	return NoOpMsg(fmt.Sprintf("Session diary written: %d tool calls, %d files", len(lines), len(filesWritten))), 0
+// writeDiaryIndex rewrites index.md in dir from the JSON diaries there,
+// newest first.
+func writeDiaryIndex(dir, repo string) {
+	paths, _ := filepath.Glob(filepath.Join(dir, "session-*.json"))
+	type entry struct {
+		name string
+		s    sessionSummary
+	}
+	var entries []entry
+	for _, p := range paths {
+		var s sessionSummary
+		if data, err := os.ReadFile(p); err == nil && json.Unmarshal(data, &s) == nil {
+			entries = append(entries, entry{strings.TrimSuffix(filepath.Base(p), ".json"), s})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name > entries[j].name })
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Session Diaries — %s\n\n", repo))
+	sb.WriteString("| Started | Session | Duration | Files | Commands | Blocked | Cost |\n")
+	sb.WriteString("|---|---|---|---|---|---|---|\n")
+	for _, e := range entries {
+		s := e.s
+		started, id := "-", s.SessionID
+		if !s.Start.IsZero() {
+			started = s.Start.Local().Format("2006-01-02 15:04")
+		}
+		if id == "" {
+			id = e.name
+		}
+		failed := 0
+		for _, c := range s.Commands {
+			if c.Failed {
+				failed++
+			}
+		}
+		commands := strconv.Itoa(len(s.Commands))
+		if failed > 0 {
+			commands += fmt.Sprintf(" (%d failed)", failed)
+		}
+		cost := "-"
+		if s.CostUSD != nil {
+			cost = fmt.Sprintf("$%.2f", *s.CostUSD)
+		}
+		sb.WriteString(fmt.Sprintf("| %s | [%s](%s.md) | %s | %d | %s | %d | %s |\n",
+			started, id, e.name, time.Duration(s.ElapsedSeconds)*time.Second, len(s.Files), commands, len(s.Blocked), cost))
+	}
+	os.WriteFile(filepath.Join(dir, "index.md"), []byte(sb.String()), 0644)
+}
+
+// shortCommand trims a command to one line of at most 80 characters.
+func shortCommand(cmd string) string {
+	if i := strings.IndexByte(cmd, '\n'); i >= 0 {
+		cmd = cmd[:i] + " ..."
+	}
+	if len(cmd) > 80 {
+		cmd = cmd[:80] + "..."
+	}
+	return cmd
 }
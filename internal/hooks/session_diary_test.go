@@ -0,0 +1,179 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionDiary_FromTranscript(t *testing.T) {
+	transcript := writeTranscript(t,
+		`{"type":"user","sessionId":"s1","timestamp":"2025-06-01T10:00:00Z","message":{"role":"user","content":"go"}}`,
+		trEdit, trEditOK,
+		`{"type":"assistant","timestamp":"2025-06-01T10:05:00Z","message":{"id":"m2","role":"assistant","usage":{"input_tokens":12,"output_tokens":34},"content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"pytest"}}]}}`,
+		`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t2","content":"1 failed","is_error":true}]}}`,
+	)
+	diaryDir := t.TempDir()
+	ti, _ := json.Marshal(map[string]string{"transcript_path": transcript, "cwd": t.TempDir()})
+
+	result, code := SessionDiary(HookInput{ToolName: "Stop", ToolInput: ti}, t.TempDir(), diaryDir)
+	if code != 0 || !strings.Contains(result.Reason, "2 tool calls, 1 files") {
+		t.Fatalf("unexpected result %+v code=%d", result, code)
+	}
+	files, _ := filepath.Glob(filepath.Join(diaryDir, "*", "session-*-s1.md"))
+	if len(files) != 1 {
+		t.Fatalf("expected one diary, got %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	for _, want := range []string{"Session: s1", "Duration: 5m0s", "- Bash: 1 calls", "- app.py", "`pytest` (failed)", "- Output: 34"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("diary missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestSessionDiary_FromAuditLog(t *testing.T) {
+	auditDir, diaryDir := t.TempDir(), t.TempDir()
+	log := "[2025-06-01T10:00:00Z] tool=Write path=a.go\n[2025-06-01T10:00:01Z] tool=Shell command=go test ./...\n"
+	os.WriteFile(filepath.Join(auditDir, "audit-"+time.Now().Format("2006-01-02")+".log"), []byte(log), 0644)
+
+	ti, _ := json.Marshal(map[string]string{"cwd": t.TempDir()})
+	result, _ := SessionDiary(HookInput{ToolName: "Stop", ToolInput: ti}, auditDir, diaryDir)
+	if !strings.Contains(result.Reason, "2 tool calls, 1 files") {
+		t.Errorf("unexpected reason %q", result.Reason)
+	}
+}
+
+func TestSessionDiary_NoAuditLog(t *testing.T) {
+	result, _ := SessionDiary(HookInput{ToolName: "Stop", ToolInput: []byte(`{}`)}, t.TempDir(), t.TempDir())
+	if result.Reason != "No audit log found for today" {
+		t.Errorf("unexpected reason %q", result.Reason)
+	}
+}
+
+// gitCommitAll commits everything in dir.
+func gitCommitAll(t *testing.T, dir string) {
+	t.Helper()
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", "wip"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@test.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@test.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+}
+
+func TestSessionDiaryWithState_Transcript(t *testing.T) {
+	repo := initGitRepo(t)
+	os.WriteFile(filepath.Join(repo, "app.py"), []byte("a\nb\nc\n"), 0644)
+	gitCommitAll(t, repo)
+	os.WriteFile(filepath.Join(repo, "app.py"), []byte("a\nB\nc\n# TODO: handle errors\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "new.py"), []byte("x = 1\n# FIXME: magic number\n"), 0644)
+
+	transcript := writeTranscript(t,
+		`{"type":"user","sessionId":"s1","timestamp":"2025-06-01T10:00:00Z","message":{"role":"user","content":"go"}}`,
+		trEdit, trEditOK,
+		`{"type":"assistant","message":{"id":"m2","role":"assistant","content":[{"type":"tool_use","id":"t2","name":"Write","input":{"file_path":"`+filepath.Join(repo, "new.py")+`"}}]}}`,
+		`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t2","content":"ok"}]}}`,
+		`{"type":"assistant","message":{"id":"m3","role":"assistant","content":[{"type":"tool_use","id":"t3","name":"Bash","input":{"command":"pytest"}}]}}`,
+		`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t3","content":"Exit code 1\n1 failed","is_error":true}]}}`,
+		`{"type":"assistant","message":{"id":"m4","role":"assistant","content":[{"type":"tool_use","id":"t4","name":"Bash","input":{"command":"git push --force"}}]}}`,
+		`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t4","content":"PreToolUse:Bash hook error: [/home/u/.cursor/hooks/git-policy]: Blocked: force push is not allowed","is_error":true}]}}`,
+		`{"type":"assistant","timestamp":"2025-06-01T10:20:00Z","message":{"id":"m5","role":"assistant","content":[{"type":"tool_use","id":"t5","name":"Bash","input":{"command":"ls"}}]}}`,
+		`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t5","content":"app.py"}]}}`,
+	)
+	costDir := t.TempDir()
+	os.WriteFile(filepath.Join(costDir, costTotalsFile), []byte(`{"sessions":{"s1":{"cost_usd":1.5,"updated":"`+time.Now().Format(time.RFC3339)+`"}}}`), 0644)
+
+	diaryDir := t.TempDir()
+	ti, _ := json.Marshal(map[string]string{"transcript_path": transcript, "session_id": "s1", "cwd": repo})
+	input := HookInput{ToolName: "Stop", ToolInput: ti}
+	policy := &SessionDiaryPolicy{Index: true}
+	SessionDiaryWithState(input, t.TempDir(), t.TempDir(), costDir, diaryDir, policy)
+	SessionDiaryWithState(input, t.TempDir(), t.TempDir(), costDir, diaryDir, policy)
+
+	dir := filepath.Join(diaryDir, filepath.Base(repo))
+	mds, _ := filepath.Glob(filepath.Join(dir, "session-*.md"))
+	if len(mds) != 1 {
+		t.Fatalf("expected one diary for the session after two stops, got %v", mds)
+	}
+	md, _ := os.ReadFile(mds[0])
+	for _, want := range []string{
+		"Session: s1", "Duration: 20m0s", "Estimated cost: $1.50",
+		"- app.py (changed, +2 -1)", "- new.py (new, +2 -0)",
+		"`pytest` (exit 1)", "`git push --force` (blocked)", "`ls` (exit 0)",
+		"- Bash (git-policy): Blocked: force push is not allowed",
+		"- app.py:4: TODO: handle errors", "- new.py:2: FIXME: magic number",
+	} {
+		if !strings.Contains(string(md), want) {
+			t.Errorf("diary missing %q:\n%s", want, md)
+		}
+	}
+
+	var s sessionSummary
+	data, err := os.ReadFile(strings.TrimSuffix(mds[0], ".md") + ".json")
+	if err != nil || json.Unmarshal(data, &s) != nil {
+		t.Fatalf("expected JSON diary: %v", err)
+	}
+	if s.SessionID != "s1" || s.ElapsedSeconds != 1200 || len(s.Files) != 2 || len(s.Blocked) != 1 || len(s.TODOs) != 2 {
+		t.Errorf("unexpected JSON diary: %+v", s)
+	}
+
+	index, err := os.ReadFile(filepath.Join(dir, "index.md"))
+	if err != nil || !strings.Contains(string(index), "[s1]("+filepath.Base(mds[0])+")") || !strings.Contains(string(index), "$1.50") {
+		t.Errorf("unexpected index (%v):\n%s", err, index)
+	}
+}
+
+func TestSessionDiaryWithState_ActivityLogIsPerSession(t *testing.T) {
+	auditDir, stateDir, diaryDir := t.TempDir(), t.TempDir(), t.TempDir()
+	cwd := t.TempDir()
+	// Today's audit log has other sessions' work; only s2's activity counts.
+	log := "[" + time.Now().Format(timeLayout) + "] tool=Write path=other.go\n"
+	os.WriteFile(filepath.Join(auditDir, "audit-"+time.Now().Format("2006-01-02")+".log"), []byte(log), 0644)
+	for _, ti := range []string{`{"session_id":"s2","path":"a.go","cwd":"` + cwd + `"}`, `{"session_id":"s2","command":"go test ./...","cwd":"` + cwd + `"}`} {
+		tool := "Write"
+		if strings.Contains(ti, "command") {
+			tool = "Shell"
+		}
+		RecordSessionEvent(stateDir, HookInput{ToolName: tool, ToolInput: []byte(ti)})
+	}
+
+	ti, _ := json.Marshal(map[string]string{"session_id": "s2", "cwd": cwd})
+	result, _ := SessionDiaryWithState(HookInput{ToolName: "Stop", ToolInput: ti}, auditDir, stateDir, "", diaryDir, nil)
+	if !strings.Contains(result.Reason, "2 tool calls, 1 files") {
+		t.Fatalf("unexpected reason %q", result.Reason)
+	}
+	mds, _ := filepath.Glob(filepath.Join(diaryDir, "*", "session-*-s2.md"))
+	if len(mds) != 1 {
+		t.Fatalf("expected s2 diary, got %v", mds)
+	}
+	md, _ := os.ReadFile(mds[0])
+	if strings.Contains(string(md), "other.go") || !strings.Contains(string(md), "- a.go") || !strings.Contains(string(md), "`go test ./...`") {
+		t.Errorf("diary not scoped to the session:\n%s", md)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(mds[0]), "index.md")); err == nil {
+		t.Error("index written without policy.index")
+	}
+
+	ti, _ = json.Marshal(map[string]string{"session_id": "s3", "cwd": cwd})
+	result, _ = SessionDiaryWithState(HookInput{ToolName: "Stop", ToolInput: ti}, auditDir, stateDir, "", diaryDir, nil)
+	if result.Reason != "No activity recorded for this session" {
+		t.Errorf("unexpected reason %q", result.Reason)
+	}
+}
+
+func TestAddedTODOs(t *testing.T) {
+	diff := "diff --git a/x.go b/x.go\n--- a/x.go\n+++ b/x.go\n@@ -3,0 +4,2 @@\n+x := 1\n+// TODO: remove\n@@ -10 +12 @@\n-// HACK: old\n+// HACK: new\n"
+	got := addedTODOs("x.go", diff)
+	want := []string{"x.go:5: TODO: remove", "x.go:12: HACK: new"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("addedTODOs = %q, want %q", got, want)
+	}
+}
@@ -34,7 +34,11 @@ func SessionGuard(input HookInput, workDir string) (HookResult, int) {
 	}
 
 	if len(warnings) == 0 {
-		return NoOpMsg("workspace clean"), 0
+		warnings = append(warnings, "workspace clean")
+	}
+
+	if n := CountCheckpoints(workDir); n > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d checkpoint(s) available (hooks checkpoints list)", n))
 	}
 
 	return NoOpMsg(strings.Join(warnings, "; ")), 0
@@ -0,0 +1,134 @@
+package hooks
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+var unsafeSessionChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// sessionActivityFile is the per-session tool call log written by audit.
+const sessionActivityFile = "activity.jsonl"
+
+const (
+	stateLockStale = 30 * time.Second
+	stateLockWait  = 5 * time.Second
+)
+
+// SessionStateDir returns the directory under base where hooks keep state for
+// one agent session: <base>/sessions/<session>. An empty session id maps to
+// "default".
+func SessionStateDir(base, session string) string {
+	session = unsafeSessionChars.ReplaceAllString(session, "_")
+	if session == "" || session == "." || session == ".." {
+		session = "default"
+	}
+	return filepath.Join(base, "sessions", session)
+}
+
+// SessionEvent is one tool call in a session's activity log.
+type SessionEvent struct {
+	Time    time.Time `json:"time"`
+	Tool    string    `json:"tool"`
+	Path    string    `json:"path,omitempty"`
+	Command string    `json:"command,omitempty"`
+	Cwd     string    `json:"cwd,omitempty"`
+}
+
+// RecordSessionEvent appends the tool call in input to the session's
+// activity log under stateDir. Relative paths are resolved against the
+// call's cwd.
+func RecordSessionEvent(stateDir string, input HookInput) {
+	if input.ToolName == "" {
+		return
+	}
+	dir := SessionStateDir(stateDir, input.SessionID())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	ev := SessionEvent{Time: time.Now(), Tool: input.ToolName, Command: input.Command(), Cwd: input.Cwd()}
+	p := input.WritePath()
+	if p != "" {
+		if !filepath.IsAbs(p) && ev.Cwd != "" {
+			p = filepath.Join(ev.Cwd, p)
+		}
+		ev.Path = p
+	}
+	data, _ := json.Marshal(ev)
+	f, err := os.OpenFile(filepath.Join(dir, sessionActivityFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(data, '\n'))
+}
+
+// loadSessionEvents reads a session's activity log in order.
+func loadSessionEvents(stateDir, session string) []SessionEvent {
+	f, err := os.Open(filepath.Join(SessionStateDir(stateDir, session), sessionActivityFile))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var events []SessionEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var ev SessionEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) == nil {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// lockStateFile takes the lock for a state file that several hook processes
+// update (parallel tool calls run their hooks at once), waiting up to
+// stateLockWait for another holder and breaking a lock left by a crashed one.
+// Without it each process would add to the same old contents and lose the
+// others' updates.
+func lockStateFile(path string) (func(), bool) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(stateLockWait)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, true
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > stateLockStale {
+			os.Remove(lock)
+		}
+		if time.Now().After(deadline) {
+			return nil, false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// writeFileAtomic replaces path with data through a temp file of its own, so
+// readers never see a partly written file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
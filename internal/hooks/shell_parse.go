@@ -0,0 +1,459 @@
+package hooks
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// shellRedirect is a single redirection attached to a command (e.g. "> out.txt").
+type shellRedirect struct {
+	Op     string // ">", ">>", "<", "2>", "&>", "<<", "<<<", ...
+	Target string // file, fd or heredoc delimiter
+	Body   string // heredoc body for "<<" and "<<-"
+}
+
+// shellCommand is one simple command within a parsed shell command line.
+type shellCommand struct {
+	Args      []string // words with quotes removed; leading VAR=value assignments are dropped
+	Redirects []shellRedirect
+	Substs    []string // bodies of $(...), `...` and <(...) found in the command
+}
+
+// shellPipeline is a sequence of commands joined by "|".
+type shellPipeline struct {
+	Commands []shellCommand
+}
+
+// String renders the command roughly as it was written, for use in messages.
+func (c shellCommand) String() string {
+	parts := append([]string{}, c.Args...)
+	for _, r := range c.Redirects {
+		parts = append(parts, r.Op+" "+r.Target)
+	}
+	return strings.Join(parts, " ")
+}
+
+type shellToken struct {
+	op     string // non-empty for operators and redirections
+	word   string
+	substs []string
+	body   string // heredoc body, set on the delimiter word
+}
+
+var (
+	shellAssignRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\[[^\]]*\])?\+?=`)
+	shellDigitsRe = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Longest operators first so "<<<" wins over "<<" and "<".
+var shellOps = []string{"<<<", "<<-", "&>>", "<<", ">>", "&&", "||", "|&", ";;", ">&", "<&", "&>", ">|", "<>", "|", "&", ";", "(", ")", "<", ">"}
+
+var shellReservedWords = map[string]bool{
+	"if": true, "then": true, "elif": true, "else": true, "fi": true,
+	"while": true, "until": true, "do": true, "done": true,
+	"{": true, "}": true, "!": true, "case": true, "esac": true,
+}
+
+// parseShell splits a shell command line into pipelines of simple commands.
+// It understands quoting, escapes, comments, command substitution and heredocs,
+// which is enough to reason about what a command reads, writes and sends. It is
+// not a full shell parser: control flow is flattened and expansions are left as-is.
+func parseShell(s string) []shellPipeline {
+	toks := tokenizeShell(s)
+
+	var pipelines []shellPipeline
+	var pl shellPipeline
+	var cmd shellCommand
+	endCmd := func() {
+		if len(cmd.Args) > 0 || len(cmd.Redirects) > 0 {
+			pl.Commands = append(pl.Commands, cmd)
+		}
+		cmd = shellCommand{}
+	}
+	endPipeline := func() {
+		endCmd()
+		if len(pl.Commands) > 0 {
+			pipelines = append(pipelines, pl)
+		}
+		pl = shellPipeline{}
+	}
+
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		switch t.op {
+		case "":
+			cmd.Substs = append(cmd.Substs, t.substs...)
+			if len(cmd.Args) == 0 && (shellAssignRe.MatchString(t.word) || shellReservedWords[t.word]) {
+				continue
+			}
+			cmd.Args = append(cmd.Args, t.word)
+		case "|", "|&":
+			endCmd()
+		case "&&", "||", ";", ";;", "&", "\n", "(", ")":
+			endPipeline()
+		default:
+			r := shellRedirect{Op: t.op}
+			if i+1 < len(toks) && toks[i+1].op == "" {
+				i++
+				r.Target = toks[i].word
+				r.Body = toks[i].body
+				cmd.Substs = append(cmd.Substs, toks[i].substs...)
+			}
+			cmd.Redirects = append(cmd.Redirects, r)
+		}
+	}
+	endPipeline()
+	return pipelines
+}
+
+// shellCommands flattens parseShell output, including commands found inside
+// command substitutions, into a single list.
+func shellCommands(s string) []shellCommand {
+	var out []shellCommand
+	for _, pl := range parseShell(s) {
+		for _, c := range pl.Commands {
+			out = append(out, c)
+			for _, sub := range c.Substs {
+				out = append(out, shellCommands(sub)...)
+			}
+		}
+	}
+	return out
+}
+
+func tokenizeShell(s string) []shellToken {
+	var toks []shellToken
+	var cur strings.Builder
+	var substs []string
+	inWord := false
+
+	// Heredoc delimiters seen on the current line, waiting for their bodies.
+	type pendingHeredoc struct {
+		tok   int
+		strip bool
+	}
+	var pending []pendingHeredoc
+	heredocNext := ""
+
+	flush := func() {
+		if !inWord {
+			return
+		}
+		toks = append(toks, shellToken{word: cur.String(), substs: substs})
+		if heredocNext != "" {
+			pending = append(pending, pendingHeredoc{tok: len(toks) - 1, strip: heredocNext == "<<-"})
+			heredocNext = ""
+		}
+		cur.Reset()
+		substs = nil
+		inWord = false
+	}
+
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(s) {
+				if s[i+1] != '\n' {
+					cur.WriteByte(s[i+1])
+					inWord = true
+				}
+				i += 2
+				continue
+			}
+			i++
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				cur.WriteString(s[i+1:])
+				i = len(s)
+			} else {
+				cur.WriteString(s[i+1 : i+1+end])
+				i += end + 2
+			}
+			inWord = true
+		case c == '"':
+			inWord = true
+			i++
+			for i < len(s) && s[i] != '"' {
+				switch {
+				case s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0:
+					if s[i+1] != '\n' {
+						cur.WriteByte(s[i+1])
+					}
+					i += 2
+				case s[i] == '$' && i+1 < len(s) && s[i+1] == '(':
+					body, end := scanShellParen(s, i+1)
+					cur.WriteString(s[i:end])
+					substs = append(substs, body)
+					i = end
+				case s[i] == '`':
+					body, end := scanShellBacktick(s, i)
+					cur.WriteString(s[i:end])
+					substs = append(substs, body)
+					i = end
+				default:
+					cur.WriteByte(s[i])
+					i++
+				}
+			}
+			i++
+		case c == '$' && i+1 < len(s) && s[i+1] == '(':
+			body, end := scanShellParen(s, i+1)
+			cur.WriteString(s[i:end])
+			substs = append(substs, body)
+			inWord = true
+			i = end
+		case c == '`':
+			body, end := scanShellBacktick(s, i)
+			cur.WriteString(s[i:end])
+			substs = append(substs, body)
+			inWord = true
+			i = end
+		case (c == '<' || c == '>') && i+1 < len(s) && s[i+1] == '(' && !inWord:
+			// Process substitution: <(cmd) / >(cmd)
+			body, end := scanShellParen(s, i+1)
+			cur.WriteString(s[i:end])
+			substs = append(substs, body)
+			inWord = true
+			i = end
+		case c == '#' && !inWord:
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == ' ' || c == '\t' || c == '\r':
+			flush()
+			i++
+		case c == '\n':
+			flush()
+			toks = append(toks, shellToken{op: "\n"})
+			i++
+			for _, p := range pending {
+				body, next := readHeredocBody(s, i, toks[p.tok].word, p.strip)
+				toks[p.tok].body = body
+				i = next
+			}
+			pending = nil
+		case strings.IndexByte("|&;()<>", c) >= 0:
+			op := ""
+			for _, candidate := range shellOps {
+				if strings.HasPrefix(s[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			fd := ""
+			if (op[0] == '<' || op[0] == '>') && inWord && len(substs) == 0 && shellDigitsRe.MatchString(cur.String()) {
+				fd = cur.String()
+				cur.Reset()
+				inWord = false
+			}
+			flush()
+			toks = append(toks, shellToken{op: fd + op})
+			if op == "<<" || op == "<<-" {
+				heredocNext = op
+			}
+			i += len(op)
+		default:
+			cur.WriteByte(c)
+			inWord = true
+			i++
+		}
+	}
+	flush()
+	return toks
+}
+
+// scanShellParen returns the body between the "(" at open and its matching ")",
+// and the index just past the closing paren. Heredoc bodies inside it are
+// skipped, so quotes and parens in them don't unbalance the scan.
+func scanShellParen(s string, open int) (string, int) {
+	depth := 0
+	arithmetic := open+1 < len(s) && s[open+1] == '(' // $((1 << 2)) shifts, not heredocs
+	var pending []shellHeredocDelim
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\n':
+			for _, h := range pending {
+				_, next := readHeredocBody(s, i+1, h.delim, h.strip)
+				i = next - 1
+			}
+			pending = nil
+		case '<':
+			if arithmetic || !strings.HasPrefix(s[i:], "<<") || strings.HasPrefix(s[i:], "<<<") {
+				continue
+			}
+			h, end := scanHeredocDelim(s, i+2)
+			if h.delim != "" {
+				pending = append(pending, h)
+			}
+			i = end - 1
+		case '#':
+			// A comment runs to the end of the line; its quotes don't count
+			if i > 0 && strings.IndexByte(" \t\n;|&(", s[i-1]) >= 0 {
+				for i+1 < len(s) && s[i+1] != '\n' {
+					i++
+				}
+			}
+		case '\\':
+			i++
+		case '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return s[open+1:], len(s)
+			}
+			i += end + 1
+		case '"':
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s[open+1 : i], i + 1
+			}
+		}
+	}
+	return s[open+1:], len(s)
+}
+
+// shellHeredocDelim is a heredoc delimiter waiting for its body.
+type shellHeredocDelim struct {
+	delim string
+	strip bool // <<- removes leading tabs
+}
+
+// scanHeredocDelim reads the delimiter word that follows "<<" at i, quotes
+// removed, and returns it with the index just past it.
+func scanHeredocDelim(s string, i int) (shellHeredocDelim, int) {
+	var h shellHeredocDelim
+	if i < len(s) && s[i] == '-' {
+		h.strip = true
+		i++
+	}
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	var delim strings.Builder
+	for i < len(s) && strings.IndexByte(" \t\r\n;|&()<>", s[i]) < 0 {
+		switch s[i] {
+		case '\'', '"':
+			end := strings.IndexByte(s[i+1:], s[i])
+			if end < 0 {
+				delim.WriteString(s[i+1:])
+				i = len(s)
+				continue
+			}
+			delim.WriteString(s[i+1 : i+1+end])
+			i += end + 2
+		case '\\':
+			if i+1 < len(s) {
+				delim.WriteByte(s[i+1])
+			}
+			i += 2
+		default:
+			delim.WriteByte(s[i])
+			i++
+		}
+	}
+	h.delim = delim.String()
+	return h, i
+}
+
+// scanShellBacktick returns the body of a `...` substitution starting at open.
+func scanShellBacktick(s string, open int) (string, int) {
+	for i := open + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '`':
+			return s[open+1 : i], i + 1
+		}
+	}
+	return s[open+1:], len(s)
+}
+
+// readHeredocBody reads lines starting at i until one equals delim.
+// strip removes leading tabs as "<<-" does.
+func readHeredocBody(s string, i int, delim string, strip bool) (string, int) {
+	var body strings.Builder
+	for i < len(s) {
+		end := strings.IndexByte(s[i:], '\n')
+		line := s[i:]
+		next := len(s)
+		if end >= 0 {
+			line = s[i : i+end]
+			next = i + end + 1
+		}
+		if strip {
+			line = strings.TrimLeft(line, "\t")
+		}
+		i = next
+		if line == delim {
+			break
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+	return body.String(), i
+}
+
+// commandArgs strips wrapper commands (sudo, env, nohup, xargs, ...) so that
+// args[0] is the program that actually runs. An env with no program returns nil.
+func commandArgs(args []string) []string {
+	for len(args) > 0 {
+		switch filepath.Base(args[0]) {
+		case "sudo", "doas", "nohup", "time", "command", "exec", "nice", "xargs", "stdbuf", "timeout":
+			name := filepath.Base(args[0])
+			args = args[1:]
+			for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+				flag := args[0]
+				args = args[1:]
+				// Flags that take a separate value
+				if len(args) > 0 && !strings.Contains(flag, "=") && wrapperFlagTakesValue(name, flag) {
+					args = args[1:]
+				}
+			}
+			if name == "timeout" && len(args) > 0 {
+				args = args[1:] // duration
+			}
+		case "env":
+			args = args[1:]
+			for len(args) > 0 && (strings.HasPrefix(args[0], "-") || shellAssignRe.MatchString(args[0])) {
+				args = args[1:]
+			}
+		default:
+			return args
+		}
+	}
+	return nil
+}
+
+func wrapperFlagTakesValue(name, flag string) bool {
+	switch name {
+	case "sudo":
+		return flag == "-u" || flag == "-g" || flag == "-C" || flag == "-D"
+	case "nice":
+		return flag == "-n"
+	case "xargs":
+		return flag == "-I" || flag == "-n" || flag == "-P" || flag == "-L" || flag == "-d" || flag == "-a" || flag == "-E"
+	case "timeout":
+		return flag == "-s" || flag == "-k"
+	}
+	return false
+}
+
+// commandName returns the base name of the program run by args, after wrappers.
+func commandName(args []string) string {
+	args = commandArgs(args)
+	if len(args) == 0 {
+		return ""
+	}
+	return filepath.Base(args[0])
+}
@@ -0,0 +1,297 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// shellWriteKind says how a shell command touches a file.
+type shellWriteKind string
+
+const (
+	shellWrite  shellWriteKind = "write"  // create or modify contents
+	shellDelete shellWriteKind = "delete" // rm, unlink, rmdir, mv source
+	shellMkdir  shellWriteKind = "mkdir"  // create a directory
+)
+
+// shellWriteTarget is a file that a shell command will create, modify or delete.
+type shellWriteTarget struct {
+	Path    string // as written, joined onto any preceding cd
+	Kind    shellWriteKind
+	Command string // the sub-command responsible, for messages
+}
+
+// nonFileTargets are redirect targets that never touch the filesystem.
+var nonFileTargets = map[string]bool{
+	"/dev/null": true, "/dev/stdout": true, "/dev/stderr": true, "/dev/tty": true, "-": true,
+}
+
+// shellWriteTargets returns the files that cmd creates, modifies or deletes:
+// redirections, tee, cp/mv/install/rsync/ln destinations, sed/perl -i, touch,
+// truncate, dd of=, rm/unlink/rmdir and mkdir. A leading "cd dir &&" is
+// applied to the relative paths that follow it. workDir is where cmd runs;
+// it is used to tell whether a cp/mv destination is an existing directory.
+func shellWriteTargets(cmd, workDir string) []shellWriteTarget {
+	var out []shellWriteTarget
+	dir := ""
+	isDir := func(path string) bool {
+		if rest, ok := strings.CutPrefix(path, "~"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return false
+			}
+			path = home + rest
+		}
+		if !filepath.IsAbs(path) {
+			base := dir
+			if !filepath.IsAbs(base) {
+				if workDir == "" {
+					return false
+				}
+				base = filepath.Join(workDir, dir)
+			}
+			path = filepath.Join(base, path)
+		}
+		info, err := os.Stat(path)
+		return err == nil && info.IsDir()
+	}
+	add := func(path string, kind shellWriteKind, sub string) {
+		if dir != "" && !filepath.IsAbs(path) && !strings.HasPrefix(path, "~") && !strings.HasPrefix(path, "$") {
+			path = filepath.Join(dir, path)
+		}
+		out = append(out, shellWriteTarget{Path: path, Kind: kind, Command: sub})
+	}
+
+	for _, c := range shellCommands(cmd) {
+		sub := c.String()
+		for _, r := range c.Redirects {
+			if isWriteRedirect(r) {
+				add(r.Target, shellWrite, sub)
+			}
+		}
+		args := commandArgs(c.Args)
+		if commandName(args) == "cd" {
+			if len(args) > 1 {
+				if filepath.IsAbs(args[1]) || dir == "" {
+					dir = args[1]
+				} else {
+					dir = filepath.Join(dir, args[1])
+				}
+			}
+			continue
+		}
+		for _, t := range commandWriteArgs(args, isDir) {
+			add(t.Path, t.Kind, sub)
+		}
+	}
+	return out
+}
+
+func isWriteRedirect(r shellRedirect) bool {
+	if nonFileTargets[r.Target] || strings.HasPrefix(r.Target, "/dev/fd/") || r.Target == "" {
+		return false
+	}
+	switch strings.TrimLeft(r.Op, "0123456789") {
+	case ">", ">>", ">|", "&>", "&>>", "<>":
+		return true
+	case ">&":
+		// 2>&1 duplicates a descriptor; >&file (no fd digits) writes a file
+		return r.Op == ">&" && !shellDigitsRe.MatchString(r.Target)
+	}
+	return false
+}
+
+// commandWriteArgs returns the operands that args (a single command, wrappers
+// already stripped) creates, modifies or deletes. isDir reports whether a
+// path is an existing directory.
+func commandWriteArgs(args []string, isDir func(string) bool) []shellWriteTarget {
+	if len(args) == 0 {
+		return nil
+	}
+	name := commandName(args)
+	rest := args[1:]
+	var paths []string
+	kind := shellWrite
+	switch name {
+	case "tee", "touch":
+		paths = operands(rest, map[string]bool{"-d": true, "-t": true, "-r": true})
+	case "truncate":
+		paths = operands(rest, map[string]bool{"-s": true, "-r": true})
+	case "cp", "install", "ln":
+		paths = copyDestinations(name, rest, isDir)
+	case "rsync":
+		for _, p := range copyDestinations(name, rest, isDir) {
+			if !strings.Contains(p, ":") { // remote destinations are not local writes
+				paths = append(paths, p)
+			}
+		}
+	case "mv":
+		dests := copyDestinations(name, rest, isDir)
+		if len(dests) == 0 {
+			return nil
+		}
+		// The sources disappear from their old location
+		var out []shellWriteTarget
+		_, srcs, _ := destinationOperand(rest)
+		for _, src := range srcs {
+			out = append(out, shellWriteTarget{Path: src, Kind: shellDelete})
+		}
+		for _, d := range dests {
+			out = append(out, shellWriteTarget{Path: d, Kind: shellWrite})
+		}
+		return out
+	case "sed", "gsed":
+		paths = sedInPlaceFiles(rest)
+	case "perl":
+		paths = perlInPlaceFiles(rest)
+	case "dd":
+		for _, a := range rest {
+			if v, ok := strings.CutPrefix(a, "of="); ok {
+				paths = append(paths, v)
+			}
+		}
+	case "rm", "unlink", "rmdir", "shred":
+		paths = operands(rest, nil)
+		kind = shellDelete
+	case "mkdir":
+		paths = operands(rest, map[string]bool{"-m": true})
+		kind = shellMkdir
+	}
+
+	var out []shellWriteTarget
+	for _, p := range paths {
+		out = append(out, shellWriteTarget{Path: p, Kind: kind})
+	}
+	return out
+}
+
+// operands returns non-flag arguments, skipping the values of flags in valueFlags.
+func operands(args []string, valueFlags map[string]bool) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			return append(out, args[i+1:]...)
+		}
+		if strings.HasPrefix(a, "-") && a != "-" {
+			if valueFlags[a] {
+				i++
+			}
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// copyDestinations returns the files a cp/mv style command writes. When the
+// destination is a directory (-t DIR, a trailing slash, . or .., several
+// sources, or an existing directory) each source lands at dest/basename(src);
+// otherwise the destination itself is written.
+func copyDestinations(name string, args []string, isDir func(string) bool) []string {
+	dest, srcs, intoDir := destinationOperand(args)
+	if dest == "" {
+		return nil
+	}
+	if !intoDir && !hasString(args, "-T") && !hasString(args, "--no-target-directory") && isDir(dest) {
+		intoDir = true
+	}
+	if !intoDir {
+		return []string{dest}
+	}
+	var out []string
+	for _, src := range srcs {
+		// rsync src/ dest copies the contents of src, not src itself
+		if name == "rsync" && strings.HasSuffix(src, "/") {
+			out = append(out, dest)
+			continue
+		}
+		out = append(out, filepath.Join(dest, filepath.Base(src)))
+	}
+	return out
+}
+
+// destinationOperand handles cp/mv style commands: the destination is -t DIR,
+// --target-directory=DIR, or the last operand, and the other operands are
+// sources. intoDir is true when the syntax alone makes it a directory.
+func destinationOperand(args []string) (dest string, srcs []string, intoDir bool) {
+	ops := operands(args, map[string]bool{"-S": true, "--suffix": true, "-m": true, "-o": true, "-g": true, "-e": true,
+		"-t": true, "--target-directory": true})
+	for i, a := range args {
+		if (a == "-t" || a == "--target-directory") && i+1 < len(args) {
+			return args[i+1], ops, true
+		}
+		if v, ok := strings.CutPrefix(a, "--target-directory="); ok {
+			return v, ops, true
+		}
+	}
+	if len(ops) < 2 {
+		return "", nil, false
+	}
+	dest, srcs = ops[len(ops)-1], ops[:len(ops)-1]
+	base := filepath.Base(dest)
+	intoDir = len(srcs) > 1 || strings.HasSuffix(dest, "/") || base == "." || base == ".."
+	return dest, srcs, intoDir
+}
+
+// sedInPlaceFiles returns the files sed edits with -i; nil without -i.
+func sedInPlaceFiles(args []string) []string {
+	inPlace := false
+	scriptGiven := false
+	var ops []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "-i" || a == "--in-place":
+			inPlace = true
+			// BSD sed: -i '' (empty backup suffix as a separate argument)
+			if i+1 < len(args) && args[i+1] == "" {
+				i++
+			}
+		case strings.HasPrefix(a, "--in-place="), strings.HasPrefix(a, "-i") && !strings.HasPrefix(a, "--"):
+			inPlace = true
+		case a == "-e" || a == "--expression" || a == "-f" || a == "--file":
+			scriptGiven = true
+			i++
+		case strings.HasPrefix(a, "-") && !strings.HasPrefix(a, "--") && strings.Contains(a, "i"):
+			inPlace = true // combined flags such as -Ei
+		case strings.HasPrefix(a, "-"):
+		default:
+			ops = append(ops, a)
+		}
+	}
+	if !inPlace {
+		return nil
+	}
+	if !scriptGiven && len(ops) > 0 {
+		ops = ops[1:]
+	}
+	return ops
+}
+
+// perlInPlaceFiles returns the files perl -i (-pi -e ...) edits; nil without -i.
+func perlInPlaceFiles(args []string) []string {
+	inPlace := false
+	var ops []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "-e" || a == "-E":
+			i++
+		case strings.HasPrefix(a, "-") && !strings.HasPrefix(a, "--"):
+			if strings.Contains(a, "i") {
+				inPlace = true
+			}
+			if strings.HasSuffix(a, "e") || strings.HasSuffix(a, "E") {
+				i++ // -pie 's/x/y/'
+			}
+		default:
+			ops = append(ops, a)
+		}
+	}
+	if !inPlace {
+		return nil
+	}
+	return ops
+}
@@ -0,0 +1,72 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestShellWriteTargets(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  string
+		want []shellWriteTarget
+	}{
+		{"redirect", "echo hi > out.txt", []shellWriteTarget{{"out.txt", shellWrite, "echo hi > out.txt"}}},
+		{"append", "echo hi >> log.txt", []shellWriteTarget{{"log.txt", shellWrite, "echo hi >> log.txt"}}},
+		{"stderr to file", "make 2> err.log", []shellWriteTarget{{"err.log", shellWrite, "make 2> err.log"}}},
+		{"dev null ignored", "make > /dev/null 2>&1", nil},
+		{"input redirect ignored", "wc -l < in.txt", nil},
+		{"tee", "echo x | tee -a a.txt b.txt", []shellWriteTarget{{"a.txt", shellWrite, "tee -a a.txt b.txt"}, {"b.txt", shellWrite, "tee -a a.txt b.txt"}}},
+		{"sudo tee", "echo x | sudo tee /etc/hosts", []shellWriteTarget{{"/etc/hosts", shellWrite, "sudo tee /etc/hosts"}}},
+		{"cp destination", "cp -r src/ dst/", []shellWriteTarget{{"dst/src", shellWrite, "cp -r src/ dst/"}}},
+		{"cp target dir", "cp -t out a b", []shellWriteTarget{{"out/a", shellWrite, "cp -t out a b"}, {"out/b", shellWrite, "cp -t out a b"}}},
+		{"cp into dot", "cp key.pem .", []shellWriteTarget{{"key.pem", shellWrite, "cp key.pem ."}}},
+		{"cp into dir slash", "cp ~/Downloads/key.pem config/", []shellWriteTarget{{"config/key.pem", shellWrite, "cp ~/Downloads/key.pem config/"}}},
+		{"cp several sources", "cp a.txt b.txt out", []shellWriteTarget{{"out/a.txt", shellWrite, "cp a.txt b.txt out"}, {"out/b.txt", shellWrite, "cp a.txt b.txt out"}}},
+		{"mv into dir", "mv tool /usr/local/", []shellWriteTarget{{"tool", shellDelete, "mv tool /usr/local/"}, {"/usr/local/tool", shellWrite, "mv tool /usr/local/"}}},
+		{"mv target dir flag", "mv -t bin a", []shellWriteTarget{{"a", shellDelete, "mv -t bin a"}, {"bin/a", shellWrite, "mv -t bin a"}}},
+		{"rsync contents", "rsync -a dist/ out/", []shellWriteTarget{{"out/", shellWrite, "rsync -a dist/ out/"}}},
+		{"mv", "mv old.txt new.txt", []shellWriteTarget{{"old.txt", shellDelete, "mv old.txt new.txt"}, {"new.txt", shellWrite, "mv old.txt new.txt"}}},
+		{"sed -i", "sed -i 's/a/b/' x.go y.go", []shellWriteTarget{{"x.go", shellWrite, "sed -i s/a/b/ x.go y.go"}, {"y.go", shellWrite, "sed -i s/a/b/ x.go y.go"}}},
+		{"sed -i -e", "sed -i.bak -e 's/a/b/' x.go", []shellWriteTarget{{"x.go", shellWrite, "sed -i.bak -e s/a/b/ x.go"}}},
+		{"sed without -i", "sed 's/a/b/' x.go", nil},
+		{"perl -pi -e", "perl -pi -e 's/a/b/' x.pl", []shellWriteTarget{{"x.pl", shellWrite, "perl -pi -e s/a/b/ x.pl"}}},
+		{"touch", "touch a b", []shellWriteTarget{{"a", shellWrite, "touch a b"}, {"b", shellWrite, "touch a b"}}},
+		{"dd", "dd if=/dev/zero of=disk.img bs=1M", []shellWriteTarget{{"disk.img", shellWrite, "dd if=/dev/zero of=disk.img bs=1M"}}},
+		{"rm", "rm -rf build", []shellWriteTarget{{"build", shellDelete, "rm -rf build"}}},
+		{"mkdir", "mkdir -p out/x", []shellWriteTarget{{"out/x", shellMkdir, "mkdir -p out/x"}}},
+		{"rsync remote", "rsync -a dist/ host:/srv", nil},
+		{"cd applies", "cd sub && echo x > f.txt", []shellWriteTarget{{"sub/f.txt", shellWrite, "echo x > f.txt"}}},
+		{"cd absolute target untouched", "cd sub && echo x > /tmp/f", []shellWriteTarget{{"/tmp/f", shellWrite, "echo x > /tmp/f"}}},
+		{"substitution", "echo $(date > stamp)", []shellWriteTarget{{"stamp", shellWrite, "date > stamp"}}},
+		{"read only", "cat a.txt | grep x", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := shellWriteTargets(tt.cmd, "")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shellWriteTargets(%q)\n got %#v\nwant %#v", tt.cmd, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellWriteTargets_ExistingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "config"), 0755)
+
+	got := shellWriteTargets("cp key.pem config", dir)
+	want := []shellWriteTarget{{"config/key.pem", shellWrite, "cp key.pem config"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+	if got := shellWriteTargets("cp -T key.pem config", dir); len(got) != 1 || got[0].Path != "config" {
+		t.Errorf("-T should write the destination itself, got %#v", got)
+	}
+	if got := shellWriteTargets("cp key.pem new.pem", dir); len(got) != 1 || got[0].Path != "new.pem" {
+		t.Errorf("expected the destination file, got %#v", got)
+	}
+}
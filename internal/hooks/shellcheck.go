@@ -13,8 +13,46 @@ import (
 
 const shellcheckTimeout = 10 * time.Second
 
+// shellcheckFileArgs are used for script files, checked as complete programs.
+var shellcheckFileArgs = []string{
+	"--severity=warning",
+	"--enable=all",
+	"--exclude=SC1090,SC1091", // Exclude source/include checks
+}
+
+// shellcheckSnippetArgs are used for inline commands, heredoc bodies and
+// bash -c payloads. Snippets have no shebang, run in the agent's environment
+// and are often a single line, so checks that only make sense for whole
+// scripts are excluded.
+var shellcheckSnippetArgs = []string{
+	"--severity=warning",
+	"--exclude=" + strings.Join([]string{
+		"SC1090", "SC1091", // can't follow source
+		"SC2148", // missing shebang
+		"SC2154", // variable referenced but not assigned (comes from the environment)
+		"SC2034", // variable appears unused (used by a child process)
+		"SC2164", // cd without || exit
+		"SC2155", // declare and assign separately
+		"SC2016", // expressions in single quotes (intentional in bash -c, awk, jq)
+		"SC2046", // unquoted $(...) (word splitting is how commands pass lists: rm $(git ls-files -d))
+		"SC2068", // unquoted $@ (commands built on purpose from arguments)
+	}, ","),
+}
+
+// shellDialects are the shells shellcheck understands.
+var shellDialects = map[string]bool{"sh": true, "bash": true, "dash": true, "ksh": true}
+
+// shellSnippet is shell code found in a command, checked on its own.
+type shellSnippet struct {
+	Label string // how the snippet is named in messages
+	Text  string
+	Shell string // dialect passed to --shell
+}
+
 // ShellCheck is a preToolUse hook that validates shell commands and shell files using shellcheck.
-// It runs shellcheck on Shell commands and Write operations for .sh/.bash files.
+// Shell commands are checked inline along with any heredoc scripts, bash -c
+// payloads and script files they run; Write operations on shell files are
+// checked on the new contents before they reach disk.
 func ShellCheck(input HookInput, workDir string) (HookResult, int) {
 	// Handle Shell tool - check the command
 	if input.ToolName == "Shell" {
@@ -31,24 +69,58 @@ func ShellCheck(input HookInput, workDir string) (HookResult, int) {
 
 func shellCheckCommand(input HookInput, workDir string) (HookResult, int) {
 	cmd := input.Command()
-	if cmd == "" {
+	if cmd == "" || !commandExists("shellcheck") {
 		return Allow(), 0
 	}
 
-	// Skip if not a shell command or if it's just running shellcheck itself
-	if !isShellCommand(cmd) || strings.Contains(cmd, "shellcheck") {
-		return Allow(), 0
+	snippets := []shellSnippet{{Label: "command", Text: cmd, Shell: "bash"}}
+	var scripts []string
+	for _, c := range shellCommands(cmd) {
+		name := commandName(c.Args)
+		if name == "shellcheck" {
+			return Allow(), 0
+		}
+		if script := extractScriptPath(commandArgs(c.Args), workDir); script != "" {
+			scripts = append(scripts, script)
+		}
+		if !shellDialects[name] {
+			continue
+		}
+		args := commandArgs(c.Args)
+		if i := indexOf(args, "-c"); i >= 0 && i+1 < len(args) {
+			snippets = append(snippets, shellSnippet{Label: name + " -c script", Text: args[i+1], Shell: name})
+		}
+		for _, r := range c.Redirects {
+			switch r.Op {
+			case "<<", "<<-":
+				snippets = append(snippets, shellSnippet{Label: name + " heredoc script", Text: r.Body, Shell: name})
+			case "<<<":
+				snippets = append(snippets, shellSnippet{Label: name + " here-string script", Text: r.Target, Shell: name})
+			}
+		}
 	}
 
-	// Extract shell script from command if it's executing a file
-	scriptPath := extractScriptPath(cmd, workDir)
-	if scriptPath == "" {
-		// Not executing a file, check the command itself
-		return checkCommandSyntax(cmd, workDir)
+	var diags []Diagnostic
+	var raw []string
+	for _, s := range snippets {
+		d, out := runShellcheck(workDir, s.Text, s.Label, append([]string{"--shell=" + s.Shell}, shellcheckSnippetArgs...))
+		diags = append(diags, d...)
+		if out != "" {
+			raw = append(raw, s.Label+":\n"+out)
+		}
 	}
-
-	// Check the script file
-	return checkShellFile(scriptPath, workDir)
+	for _, path := range scripts {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		d, out := runShellcheck(workDir, string(data), path, shellcheckFileArgs)
+		diags = append(diags, d...)
+		if out != "" {
+			raw = append(raw, path+":\n"+out)
+		}
+	}
+	return shellcheckResult("the command", workDir, diags, raw)
 }
 
 func shellCheckFile(input HookInput, workDir string) (HookResult, int) {
@@ -57,13 +129,8 @@ func shellCheckFile(input HookInput, workDir string) (HookResult, int) {
 		return Allow(), 0
 	}
 
-	ext := filepath.Ext(path)
-	if ext != ".sh" && ext != ".bash" && !strings.HasSuffix(path, ".sh") && !strings.HasSuffix(path, ".bash") {
-		return Allow(), 0
-	}
-
 	// Skip vendor directories
-	if strings.Contains(path, "/vendor/") || strings.Contains(path, "vendor/") {
+	if strings.Contains(path, "/vendor/") || strings.HasPrefix(path, "vendor/") {
 		return Allow(), 0
 	}
 
@@ -73,116 +140,158 @@ func shellCheckFile(input HookInput, workDir string) (HookResult, int) {
 		absPath = filepath.Join(workDir, path)
 	}
 
-	return checkShellFile(absPath, workDir)
+	// Check the new contents; fall back to the file on disk when the tool
+	// input carries none (e.g. postToolUse for an edit)
+	contents := input.Contents()
+	if contents == "" {
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			return Allow(), 0 // File doesn't exist yet, allow
+		}
+		contents = string(data)
+	}
+	if !isShellScript(path, contents) || !commandExists("shellcheck") {
+		return Allow(), 0
+	}
+
+	diags, out := runShellcheck(workDir, contents, absPath, shellcheckFileArgs)
+	var raw []string
+	if out != "" {
+		raw = append(raw, out)
+	}
+	return shellcheckResult(filepath.Base(absPath), workDir, diags, raw)
 }
 
-func isShellCommand(cmd string) bool {
-	cmd = strings.TrimSpace(cmd)
-	// Check for common shell patterns
-	return strings.HasPrefix(cmd, "sh ") ||
-		strings.HasPrefix(cmd, "bash ") ||
-		strings.HasPrefix(cmd, "zsh ") ||
-		strings.Contains(cmd, ".sh") ||
-		strings.Contains(cmd, ".bash") ||
-		strings.HasPrefix(cmd, "./") ||
-		strings.HasPrefix(cmd, "/")
+// isShellScript reports whether a file is a shell script by extension or shebang.
+func isShellScript(path, contents string) bool {
+	ext := filepath.Ext(path)
+	if ext == ".sh" || ext == ".bash" {
+		return true
+	}
+	if ext != "" || !strings.HasPrefix(contents, "#!") {
+		return false
+	}
+	line, _, _ := strings.Cut(contents, "\n")
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return false
+	}
+	interp := filepath.Base(fields[0])
+	if interp == "env" && len(fields) > 1 {
+		interp = fields[1]
+	}
+	return shellDialects[interp]
 }
 
-func extractScriptPath(cmd string, workDir string) string {
-	// Look for script execution patterns: bash script.sh, sh script.sh, ./script.sh
-	parts := strings.Fields(cmd)
-	if len(parts) == 0 {
+// extractScriptPath returns the script file a command runs, or "": the first
+// operand of a shell (bash -x deploy.sh), or the command itself when it is a
+// ./relative or absolute path to a shell script. Commands that only mention a
+// script (cat x.sh, vim x.sh) run nothing.
+func extractScriptPath(args []string, workDir string) string {
+	if len(args) == 0 {
 		return ""
 	}
-
-	// Check for ./script.sh pattern (first part)
-	if strings.HasPrefix(parts[0], "./") {
-		script := parts[0]
-		if strings.HasSuffix(script, ".sh") || strings.HasSuffix(script, ".bash") {
-			// Remove ./ prefix and join with workDir
-			script = strings.TrimPrefix(script, "./")
-			return filepath.Join(workDir, script)
+	resolve := func(script string) string {
+		if filepath.IsAbs(script) {
+			return script
 		}
+		return filepath.Join(workDir, script)
 	}
 
-	// Check if second part looks like a script
-	if len(parts) >= 2 {
-		script := parts[1]
-		if strings.HasSuffix(script, ".sh") || strings.HasSuffix(script, ".bash") {
-			if filepath.IsAbs(script) {
-				return script
+	if shellDialects[filepath.Base(args[0])] {
+		for i := 1; i < len(args); i++ {
+			a := args[i]
+			switch {
+			case a == "--":
+				if i+1 < len(args) {
+					return resolve(args[i+1])
+				}
+				return ""
+			case a == "-c" || a == "-s":
+				return "" // the code comes from an argument or stdin
+			case a == "-o" || a == "+o" || a == "-O" || a == "+O" || a == "--rcfile" || a == "--init-file":
+				i++ // -o pipefail
+			case strings.HasPrefix(a, "-") || strings.HasPrefix(a, "+"):
+				if strings.ContainsAny(strings.TrimLeft(a, "-+"), "cs") && !strings.HasPrefix(a, "--") {
+					return "" // combined flags such as -ec
+				}
+			default:
+				return resolve(a)
 			}
-			return filepath.Join(workDir, script)
 		}
+		return ""
 	}
 
+	script := args[0]
+	if !strings.HasPrefix(script, "./") && !strings.HasPrefix(script, "../") && !filepath.IsAbs(script) {
+		return ""
+	}
+	if ext := filepath.Ext(script); ext == ".sh" || ext == ".bash" {
+		return resolve(script)
+	}
+	// Extensionless scripts count when their shebang names a shell
+	if data, err := os.ReadFile(resolve(script)); err == nil && isShellScript(script, string(data)) {
+		return resolve(script)
+	}
 	return ""
 }
 
-func checkCommandSyntax(cmd string, workDir string) (HookResult, int) {
-	// For inline commands, we can't easily check them without shellcheck supporting stdin
-	// So we'll allow them for now
-	return Allow(), 0
-}
-
-func checkShellFile(filePath string, workDir string) (HookResult, int) {
-	if _, err := os.Stat(filePath); err != nil {
-		return Allow(), 0 // File doesn't exist yet, allow
+// runShellcheck checks text by piping it to shellcheck on stdin. Diagnostics
+// are attributed to name. When the output can't be parsed it is returned raw.
+func runShellcheck(workDir, text, name string, args []string) ([]Diagnostic, string) {
+	if strings.TrimSpace(text) == "" {
+		return nil, ""
 	}
-
-	// Check if shellcheck is available
-	if !commandExists("shellcheck") {
-		return Allow(), 0 // Fail open if shellcheck not installed
-	}
-
-	// Run shellcheck
 	ctx, cancel := context.WithTimeout(context.Background(), shellcheckTimeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "shellcheck",
-		"--severity=warning",
-		"--enable=all",
-		"--exclude=SC1090,SC1091", // Exclude source/include checks
-		filePath)
-
+	args = append(append([]string{}, args...), "--format=json1", "-")
+	cmd := exec.CommandContext(ctx, "shellcheck", args...)
 	cmd.Dir = workDir
+	cmd.Stdin = strings.NewReader(text)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
-	exitCode := cmd.ProcessState.ExitCode()
-
-	if err != nil {
-		// Check if it's a timeout
-		if ctx.Err() == context.DeadlineExceeded {
-			return Deny("shellcheck timed out after 10 seconds"), 2
-		}
-		// Check if command not found
-		if strings.Contains(err.Error(), "executable file not found") {
-			return Allow(), 0 // Fail open if shellcheck not available
-		}
+	// Fail open on timeouts and when shellcheck can't run
+	if err := cmd.Run(); err == nil || cmd.ProcessState == nil || ctx.Err() != nil {
+		return nil, ""
 	}
 
-	if exitCode != 0 {
+	diags, ok := parseDiagnostics(formatShellcheck, stdout.String(), "", "shellcheck")
+	if !ok {
 		output := stderr.String()
 		if output == "" {
 			output = stdout.String()
 		}
-
-		var reason strings.Builder
-		reason.WriteString(fmt.Sprintf("shellcheck found issues in %s", filepath.Base(filePath)))
-		reason.WriteString("\n  Hints:")
-		reason.WriteString("\n    - Fix shellcheck warnings before continuing")
-		reason.WriteString("\n    - Run 'shellcheck " + filePath + "' to see details")
-		reason.WriteString("\n    - Common issues: unquoted variables, missing shebang, unsafe operations")
-
-		if output != "" {
-			reason.WriteString("\n\n" + output)
-		}
-
-		return Deny(reason.String()), 2
+		return nil, strings.TrimSpace(output)
 	}
+	for i := range diags {
+		diags[i].File = name
+	}
+	return diags, ""
+}
 
-	return Allow(), 0
+func shellcheckResult(subject, workDir string, diags []Diagnostic, raw []string) (HookResult, int) {
+	if len(diags) == 0 && len(raw) == 0 {
+		return Allow(), 0
+	}
+	diags = dedupeDiagnostics(diags)
+	var reason strings.Builder
+	if len(diags) > 0 {
+		reason.WriteString(fmt.Sprintf("shellcheck found %d issue(s) in %s", len(diags), subject))
+		reason.WriteString(formatDiagnostics(diags, workDir))
+	} else {
+		reason.WriteString(fmt.Sprintf("shellcheck found issues in %s", subject))
+	}
+	reason.WriteString("\n  Hints:")
+	reason.WriteString("\n    - Fix shellcheck warnings before continuing")
+	reason.WriteString("\n    - See https://www.shellcheck.net/wiki/SC<code> for each rule")
+	reason.WriteString("\n    - Common issues: unquoted variables, missing shebang, unsafe operations")
+	for _, out := range raw {
+		reason.WriteString("\n\n" + out)
+	}
+	result := Deny(reason.String())
+	result.Diagnostics = diags
+	return result, 2
 }
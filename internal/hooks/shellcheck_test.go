@@ -68,31 +68,6 @@ func TestShellCheck_NonShellFile(t *testing.T) {
 	}
 }
 
-func TestIsShellCommand(t *testing.T) {
-	tests := []struct {
-		name     string
-		cmd      string
-		expected bool
-	}{
-		{"bash script", "bash script.sh", true},
-		{"sh script", "sh script.sh", true},
-		{"executable script", "./script.sh", true},
-		{"absolute path", "/usr/bin/script.sh", true},
-		{"regular command", "ls -la", false},
-		{"git command", "git status", false},
-		{"zsh script", "zsh script.sh", true},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := isShellCommand(tt.cmd)
-			if result != tt.expected {
-				t.Errorf("isShellCommand(%q) = %v, want %v", tt.cmd, result, tt.expected)
-			}
-		})
-	}
-}
-
 func TestExtractScriptPath(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -104,12 +79,23 @@ func TestExtractScriptPath(t *testing.T) {
 		{"sh script", "sh test.sh", "/tmp", "/tmp/test.sh"},
 		{"executable script", "./script.sh", "/tmp", "/tmp/script.sh"},
 		{"absolute path", "bash /usr/bin/script.sh", "/tmp", "/usr/bin/script.sh"},
+		{"absolute script", "/opt/tools/deploy.sh --prod", "/tmp", "/opt/tools/deploy.sh"},
+		{"shell options", "bash -x script.sh", "/tmp", "/tmp/script.sh"},
+		{"option with value", "bash -e -o pipefail script.sh arg.sh", "/tmp", "/tmp/script.sh"},
+		{"shell path", "/bin/sh -- script.sh", "/tmp", "/tmp/script.sh"},
+		{"script without extension", "bash deploy", "/tmp", "/tmp/deploy"},
+		{"bash -c", "bash -c ./other.sh", "/tmp", ""},
+		{"combined -ec", "bash -ec ./other.sh", "/tmp", ""},
+		{"cat script", "cat script.sh", "/tmp", ""},
+		{"editor", "vim script.sh", "/tmp", ""},
+		{"bare relative name", "script.sh", "/tmp", ""},
+		{"shell alone", "bash", "/tmp", ""},
 		{"no script", "ls -la", "/tmp", ""},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := extractScriptPath(tt.cmd, tt.workDir)
+			result := extractScriptPath(strings.Fields(tt.cmd), tt.workDir)
 			if result != tt.expected {
 				t.Errorf("extractScriptPath(%q, %q) = %q, want %q", tt.cmd, tt.workDir, result, tt.expected)
 			}
@@ -139,6 +125,31 @@ func TestShellCheck_ExecutingScript(t *testing.T) {
 	}
 }
 
+func TestShellCheck_ScriptOperands(t *testing.T) {
+	fakeShellcheck(t)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "x.sh"), []byte("#!/bin/bash\nrm -rf $dir/build\n"), 0o644)
+
+	tests := []struct {
+		cmd      string
+		wantDeny bool
+	}{
+		{"cat x.sh", false},
+		{"grep rm x.sh", false},
+		{"bash -x x.sh", true},
+		{"sudo bash x.sh", true},
+	}
+	for _, tt := range tests {
+		result, code := ShellCheck(shellInput(tt.cmd), dir)
+		if (code == 2) != tt.wantDeny {
+			t.Errorf("%q: code = %d, want deny %v (%s)", tt.cmd, code, tt.wantDeny, result.Reason)
+		}
+		if tt.wantDeny && !strings.Contains(result.Reason, "x.sh") {
+			t.Errorf("%q: reason should name the script: %s", tt.cmd, result.Reason)
+		}
+	}
+}
+
 func TestShellCheck_NonexistentFile(t *testing.T) {
 	input := writeInput("/nonexistent/script.sh", "#!/bin/bash\necho test\n")
 	result, code := ShellCheck(input, ".")
@@ -158,3 +169,129 @@ func TestShellCheck_CommandExists(t *testing.T) {
 		t.Error("expected nonexistent command to return false")
 	}
 }
+
+// fakeShellcheck installs a shellcheck that appends each stdin it receives to
+// a log (snippets separated by "---") and reports one warning when the input
+// contains "rm -rf $dir".
+func fakeShellcheck(t *testing.T) string {
+	t.Helper()
+	log := filepath.Join(t.TempDir(), "shellcheck.log")
+	fakeTool(t, "shellcheck", `input=$(cat)
+printf '%s\n%s\n---\n' "$*" "$input" >> '`+log+`'
+case "$input" in
+*'rm -rf $dir'*)
+  echo '{"comments":[{"file":"-","line":2,"column":8,"level":"warning","code":2115,"message":"Use \"${var:?}\" to ensure this never expands to /."}]}'
+  exit 1;;
+esac
+echo '{"comments":[]}'`)
+	return log
+}
+
+func TestShellCheck_InlineCommand(t *testing.T) {
+	log := fakeShellcheck(t)
+	dir := t.TempDir()
+
+	if _, code := ShellCheck(shellInput("ls -la | grep foo"), dir); code != 0 {
+		t.Error("clean command should be allowed")
+	}
+	data, _ := os.ReadFile(log)
+	if !strings.Contains(string(data), "ls -la | grep foo") || !strings.Contains(string(data), "--shell=bash") || !strings.Contains(string(data), "SC2154") || !strings.Contains(string(data), "SC2046") || !strings.Contains(string(data), "SC2068") {
+		t.Errorf("expected inline command piped to shellcheck with snippet exclusions, got:\n%s", data)
+	}
+
+	result, code := ShellCheck(shellInput("dir=/tmp/x\nrm -rf $dir/*"), dir)
+	if code != 2 || !strings.Contains(result.Reason, "command:2:8 warning SC2115") {
+		t.Errorf("expected SC2115 deny for the command, got code=%d %q", code, result.Reason)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].File != "command" {
+		t.Errorf("expected one diagnostic for the command, got %+v", result.Diagnostics)
+	}
+}
+
+func TestShellCheck_Snippets(t *testing.T) {
+	tests := []struct {
+		name  string
+		cmd   string
+		label string
+		shell string
+	}{
+		{"heredoc", "bash <<'EOF'\nset -e\nrm -rf $dir/build\nEOF", "bash heredoc script", "--shell=bash"},
+		{"heredoc strip tabs", "sh <<-EOF\n\tcd /tmp\n\trm -rf $dir/build\n\tEOF", "sh heredoc script", "--shell=sh"},
+		{"bash -c", "bash -c 'cd /tmp\nrm -rf $dir/build'", "bash -c script", "--shell=bash"},
+		{"wrapped bash -c", "sudo -u app bash -c 'cd /tmp\nrm -rf $dir/build'", "bash -c script", "--shell=bash"},
+		{"here-string", "bash <<< 'cd /tmp\nrm -rf $dir/build'", "bash here-string script", "--shell=bash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := fakeShellcheck(t)
+			result, code := ShellCheck(shellInput(tt.cmd), t.TempDir())
+			if code != 2 || !strings.Contains(result.Reason, tt.label+":2:8") {
+				t.Errorf("expected deny naming %q, got code=%d %q", tt.label, code, result.Reason)
+			}
+			data, _ := os.ReadFile(log)
+			if !strings.Contains(string(data), tt.shell) {
+				t.Errorf("expected %s, got:\n%s", tt.shell, data)
+			}
+		})
+	}
+}
+
+func TestShellCheck_SkipsShellcheckItself(t *testing.T) {
+	log := fakeShellcheck(t)
+	if _, code := ShellCheck(shellInput("shellcheck -x deploy.sh"), t.TempDir()); code != 0 {
+		t.Error("running shellcheck should be allowed")
+	}
+	if _, err := os.Stat(log); err == nil {
+		t.Error("shellcheck should not be run on a shellcheck command")
+	}
+}
+
+func TestShellCheck_WriteChecksNewContents(t *testing.T) {
+	fakeShellcheck(t)
+	dir := t.TempDir()
+
+	// The file does not exist yet: the contents are checked before they hit disk
+	file := filepath.Join(dir, "deploy.sh")
+	result, code := ShellCheck(writeInput(file, "#!/bin/bash\nrm -rf $dir/*\n"), dir)
+	if code != 2 || !strings.Contains(result.Reason, "deploy.sh:2:8 warning SC2115") {
+		t.Errorf("expected deny for new contents, got code=%d %q", code, result.Reason)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].File != file {
+		t.Errorf("expected diagnostic attributed to %s, got %+v", file, result.Diagnostics)
+	}
+
+	// Existing clean file being overwritten with bad contents
+	os.WriteFile(file, []byte("#!/bin/bash\necho ok\n"), 0644)
+	if _, code := ShellCheck(writeInput(file, "#!/bin/bash\nrm -rf $dir/*\n"), dir); code != 2 {
+		t.Error("new contents should be checked, not the file on disk")
+	}
+
+	// Extensionless scripts are detected by shebang
+	if _, code := ShellCheck(writeInput(filepath.Join(dir, "bin", "deploy"), "#!/usr/bin/env bash\nrm -rf $dir/*\n"), dir); code != 2 {
+		t.Error("expected shebang script to be checked")
+	}
+	if _, code := ShellCheck(writeInput(filepath.Join(dir, "bin", "tool"), "#!/usr/bin/env python3\nrm -rf $dir/*\n"), dir); code != 0 {
+		t.Error("non-shell shebang should be skipped")
+	}
+}
+
+func TestIsShellScript(t *testing.T) {
+	tests := []struct {
+		path     string
+		contents string
+		expected bool
+	}{
+		{"a.sh", "", true},
+		{"a.bash", "echo", true},
+		{"run", "#!/bin/sh\necho", true},
+		{"run", "#!/usr/bin/env bash\necho", true},
+		{"run", "#!/usr/bin/env node\n", false},
+		{"run.py", "#!/bin/sh\n", false},
+		{"run", "echo", false},
+	}
+	for _, tt := range tests {
+		if got := isShellScript(tt.path, tt.contents); got != tt.expected {
+			t.Errorf("isShellScript(%q, %q) = %v, want %v", tt.path, tt.contents, got, tt.expected)
+		}
+	}
+}
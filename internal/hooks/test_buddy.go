@@ -1,60 +1,138 @@
 package hooks
 
 import (
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
-// testableExts maps source extensions to their test file patterns.
-type testPattern struct {
-	// Returns candidate test file paths given the source file path (without dir)
-	candidates func(base string) []string
+// TestBuddyPolicy configures test-buddy (policies.testBuddy in config.yaml).
+type TestBuddyPolicy struct {
+	// Layouts adds test locations per language (go, python, javascript, rust,
+	// java, kotlin), tried before the defaults. Templates are globs relative
+	// to the project root with {dir}, {name}, {ext} and {mirror} (the file's
+	// directory with src/main/ replaced by src/test/).
+	Layouts map[string][]string `yaml:"layouts,omitempty" json:"layouts,omitempty"`
+	// Ignore are repo-relative globs of files that never get a nudge.
+	Ignore []string `yaml:"ignore,omitempty" json:"ignore,omitempty"`
 }
 
-var testableExts = map[string]testPattern{
-	".go": {func(base string) []string {
-		return []string{strings.TrimSuffix(base, ".go") + "_test.go"}
-	}},
-	".py": {func(base string) []string {
-		name := strings.TrimSuffix(base, ".py")
-		return []string{"test_" + name + ".py", name + "_test.py"}
-	}},
-	".js": {func(base string) []string {
-		name := strings.TrimSuffix(base, ".js")
-		return []string{name + ".test.js", name + ".spec.js"}
-	}},
-	".ts": {func(base string) []string {
-		name := strings.TrimSuffix(base, ".ts")
-		return []string{name + ".test.ts", name + ".spec.ts"}
-	}},
-	".tsx": {func(base string) []string {
-		name := strings.TrimSuffix(base, ".tsx")
-		return []string{name + ".test.tsx", name + ".spec.tsx"}
-	}},
-	".jsx": {func(base string) []string {
-		name := strings.TrimSuffix(base, ".jsx")
-		return []string{name + ".test.jsx", name + ".spec.jsx"}
-	}},
-	".rs": {func(base string) []string {
-		// Rust tests are typically in the same file, but check for a test module file
-		name := strings.TrimSuffix(base, ".rs")
-		return []string{name + "_test.rs"}
-	}},
+// testLayout describes where a language keeps the tests for a source file.
+type testLayout struct {
+	Language string
+	Exts     []string
+	// Markers are files that mark the project root test paths are relative to.
+	Markers []string
+	// Templates are the candidate test locations; the first is suggested
+	// when none exists.
+	Templates []string
+	// Inline reports whether the file carries or is covered by tests that
+	// don't live in a file of their own (Rust test modules, Go examples).
+	Inline func(file, contents string) bool
+	// Untestable reports files not worth a nudge (entry points, config).
+	Untestable func(base, contents string) bool
 }
 
+var testLayouts = []testLayout{
+	{
+		Language:  "go",
+		Exts:      []string{".go"},
+		Markers:   []string{"go.mod"},
+		Templates: []string{"{dir}/{name}_test.go"},
+		Inline:    goPackageTestsCover,
+		Untestable: func(base, contents string) bool {
+			return base == "doc.go" || goMainPackageRe.MatchString(contents)
+		},
+	},
+	{
+		Language: "python",
+		Exts:     []string{".py"},
+		Markers:  []string{"pyproject.toml", "setup.py", "setup.cfg", "pytest.ini", "tox.ini"},
+		Templates: []string{
+			"{dir}/test_{name}.py", "{dir}/{name}_test.py",
+			"tests/**/test_{name}.py", "tests/**/{name}_test.py", "test/**/test_{name}.py",
+		},
+		Untestable: func(base, _ string) bool {
+			return hasString([]string{"__init__.py", "__main__.py", "conftest.py", "setup.py", "manage.py"}, base)
+		},
+	},
+	{
+		Language: "javascript",
+		Exts:     []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".mts", ".cts"},
+		Markers:  []string{"package.json"},
+		Templates: []string{
+			"{dir}/{name}.test{ext}", "{dir}/{name}.spec{ext}",
+			"{dir}/__tests__/{name}{ext}", "{dir}/__tests__/{name}.test{ext}", "{dir}/__tests__/{name}.spec{ext}",
+			"{dir}/{name}.test.*", "{dir}/{name}.spec.*", "{dir}/__tests__/{name}.*",
+			"test/**/{name}.test.*", "tests/**/{name}.test.*", "test/**/{name}.spec.*", "tests/**/{name}.spec.*",
+		},
+		Untestable: func(base, _ string) bool {
+			return strings.HasPrefix(base, ".") || strings.Contains(base, ".config.") ||
+				strings.HasSuffix(base, ".d.ts") || strings.Contains(base, ".stories.")
+		},
+	},
+	{
+		Language:  "rust",
+		Exts:      []string{".rs"},
+		Markers:   []string{"Cargo.toml"},
+		Templates: []string{"tests/{name}.rs", "tests/{name}_test.rs", "tests/test_{name}.rs", "{dir}/{name}/tests.rs", "{dir}/{name}_test.rs"},
+		Inline: func(_, contents string) bool {
+			return strings.Contains(contents, "#[cfg(test)]")
+		},
+		Untestable: func(base, _ string) bool {
+			return base == "main.rs" || base == "build.rs"
+		},
+	},
+	{
+		Language:  "java",
+		Exts:      []string{".java"},
+		Markers:   []string{"pom.xml", "build.gradle", "build.gradle.kts"},
+		Templates: []string{"{mirror}/{name}Test.java", "{mirror}/{name}Tests.java", "{mirror}/{name}IT.java"},
+		Untestable: func(base, _ string) bool {
+			return base == "package-info.java" || base == "module-info.java"
+		},
+	},
+	{
+		Language:  "kotlin",
+		Exts:      []string{".kt"},
+		Markers:   []string{"build.gradle.kts", "build.gradle", "pom.xml"},
+		Templates: []string{"{mirror}/{name}Test.kt", "{mirror}/{name}Tests.kt"},
+	},
+}
+
+var (
+	goMainPackageRe = regexp.MustCompile(`(?m)^package main\b`)
+	goTopLevelRe    = regexp.MustCompile(`(?m)^(?:func (?:\([^)]*\)\s*)?|type )([A-Za-z_]\w*)`)
+	goTestNameRe    = regexp.MustCompile(`(?m)^func (?:Test|Example|Benchmark|Fuzz)_?(\w+)\(`)
+)
+
 // isTestFile returns true if the file looks like a test file.
 func isTestFile(path string) bool {
 	base := filepath.Base(path)
 	lower := strings.ToLower(base)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
 	return strings.Contains(lower, "_test.") ||
 		strings.Contains(lower, ".test.") ||
 		strings.Contains(lower, ".spec.") ||
-		strings.HasPrefix(lower, "test_")
+		strings.HasPrefix(lower, "test_") ||
+		(filepath.Ext(base) == ".java" || filepath.Ext(base) == ".kt") &&
+			(strings.HasSuffix(name, "Test") || strings.HasSuffix(name, "Tests") || strings.HasSuffix(name, "IT"))
 }
 
 // TestBuddy is a postToolUse hook that nudges creation of test files.
 func TestBuddy(input HookInput, workDir string) (HookResult, int) {
+	return TestBuddyWithPolicy(input, workDir, nil)
+}
+
+// TestBuddyWithPolicy looks for the written file's tests where its language
+// keeps them (sibling files, tests/ trees, __tests__/, src/test mirrors, Rust
+// test modules, Go tests and examples in the package) and suggests where to
+// add one when there are none. Entry points, generated code and config files
+// are skipped.
+func TestBuddyWithPolicy(input HookInput, workDir string, policy *TestBuddyPolicy) (HookResult, int) {
 	if input.ToolName != "Write" {
 		return Allow(), 0
 	}
@@ -63,34 +141,170 @@ func TestBuddy(input HookInput, workDir string) (HookResult, int) {
 	if path == "" {
 		return Allow(), 0
 	}
+	absPath := path
+	if !filepath.IsAbs(path) && workDir != "" {
+		absPath = filepath.Join(workDir, path)
+	}
+	if policy == nil {
+		policy = &TestBuddyPolicy{}
+	}
 
-	ext := filepath.Ext(path)
-	pattern, ok := testableExts[ext]
-	if !ok {
+	layout, ok := testLayoutFor(absPath)
+	if !ok || isTestFile(absPath) {
 		return Allow(), 0
 	}
 
-	// Don't nudge for test files themselves
-	if isTestFile(path) {
-		return Allow(), 0
+	root := workDir
+	if root == "" {
+		root = filepath.Dir(absPath)
+	}
+	for _, dir := range dirsUpTo(filepath.Dir(absPath), root) {
+		if hasMarker(dir, layout.Markers) {
+			root = dir
+			break
+		}
+	}
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(absPath)
 	}
+	rel = filepath.ToSlash(rel)
 
-	base := filepath.Base(path)
-	dir := filepath.Dir(path)
-	if workDir != "" && !filepath.IsAbs(path) {
-		dir = workDir
+	contents := input.Contents()
+	if data, err := os.ReadFile(absPath); err == nil {
+		contents = string(data)
+	}
+	base := filepath.Base(absPath)
+	if inTestDir(rel) || generatedHeader(absPath) != "" ||
+		(layout.Untestable != nil && layout.Untestable(base, contents)) {
+		return Allow(), 0
+	}
+	for _, pattern := range policy.Ignore {
+		if matchRepoGlob(pattern, rel) {
+			return Allow(), 0
+		}
+	}
+	if layout.Inline != nil && layout.Inline(absPath, contents) {
+		return Allow(), 0
 	}
 
-	candidates := pattern.candidates(base)
+	templates := append(append([]string{}, policy.Layouts[layout.Language]...), layout.Templates...)
+	var candidates []string
+	for _, t := range templates {
+		candidates = append(candidates, renderTestTemplate(t, rel))
+	}
 	for _, c := range candidates {
-		testPath := filepath.Join(dir, c)
-		if _, err := os.Stat(testPath); err == nil {
+		if testCandidateExists(root, c) {
 			return Allow(), 0
 		}
 	}
 
 	return HookResult{
 		Decision: "allow",
-		Message:  "No test file found for " + base + ". Consider creating " + candidates[0],
+		Message:  "No test file found for " + base + ". Consider creating " + strings.TrimPrefix(candidates[0], "/"),
 	}, 0
 }
+
+func testLayoutFor(file string) (testLayout, bool) {
+	ext := filepath.Ext(file)
+	for _, l := range testLayouts {
+		if hasString(l.Exts, ext) {
+			return l, true
+		}
+	}
+	return testLayout{}, false
+}
+
+// renderTestTemplate fills a layout template for the repo-relative source
+// path rel and returns a root-anchored glob.
+func renderTestTemplate(template, rel string) string {
+	dir := path.Dir(rel)
+	if dir == "." {
+		dir = ""
+	}
+	base := path.Base(rel)
+	ext := path.Ext(base)
+	mirror := dir
+	if strings.Contains("/"+dir+"/", "/src/main/") {
+		mirror = strings.TrimSuffix(strings.Replace("/"+dir+"/", "/src/main/", "/src/test/", 1), "/")
+	}
+	r := strings.NewReplacer("{dir}", dir, "{name}", strings.TrimSuffix(base, ext), "{ext}", ext, "{mirror}", mirror)
+	return path.Clean("/" + r.Replace(template))
+}
+
+// testCandidateExists reports whether a file matching the anchored glob
+// exists under root.
+func testCandidateExists(root, glob string) bool {
+	if !strings.ContainsAny(glob, "*?[") {
+		return exists(filepath.Join(root, filepath.FromSlash(glob)))
+	}
+	// Walk from the deepest directory without wildcards
+	prefix := glob[:strings.IndexAny(glob, "*?[")]
+	start := filepath.Join(root, filepath.FromSlash(path.Dir(prefix+"x")))
+	found := false
+	filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || found {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			if n := d.Name(); p != start && (n == "node_modules" || n == ".git" || n == "vendor" || n == "target") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel, err := filepath.Rel(root, p); err == nil && matchRepoGlob(glob, filepath.ToSlash(rel)) {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// inTestDir reports whether a repo-relative path is inside a test tree.
+func inTestDir(rel string) bool {
+	for _, seg := range strings.Split(path.Dir(rel), "/") {
+		if seg == "tests" || seg == "test" || seg == "__tests__" || seg == "testdata" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMarker(dir string, markers []string) bool {
+	for _, m := range markers {
+		if exists(filepath.Join(dir, m)) {
+			return true
+		}
+	}
+	return false
+}
+
+// goPackageTestsCover reports whether a _test.go file in the package has a
+// test, example, benchmark or fuzz function named after something the file
+// declares (TestParse, ExampleClient_Do).
+func goPackageTestsCover(file, contents string) bool {
+	var names []string
+	for _, m := range goTopLevelRe.FindAllStringSubmatch(contents, -1) {
+		names = append(names, strings.ToLower(m[1]))
+	}
+	if len(names) == 0 {
+		return false
+	}
+	tests, _ := filepath.Glob(filepath.Join(filepath.Dir(file), "*_test.go"))
+	for _, t := range tests {
+		data, err := os.ReadFile(t)
+		if err != nil {
+			continue
+		}
+		for _, m := range goTestNameRe.FindAllStringSubmatch(string(data), -1) {
+			tested := strings.ToLower(m[1])
+			for _, n := range names {
+				if strings.HasPrefix(tested, n) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package hooks
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -10,9 +11,9 @@ func TestTestBuddy_NudgesWhenNoTest(t *testing.T) {
 	dir := t.TempDir()
 	// Write a Go file with no corresponding test
 	goFile := filepath.Join(dir, "handler.go")
-	os.WriteFile(goFile, []byte("package main"), 0644)
+	os.WriteFile(goFile, []byte("package handler"), 0644)
 
-	result, code := TestBuddy(writeInput(goFile, "package main"), dir)
+	result, code := TestBuddy(writeInput(goFile, "package handler"), dir)
 	if code != 0 {
 		t.Errorf("expected exit 0, got %d", code)
 	}
@@ -112,3 +113,102 @@ func TestTestBuddy_PassthroughNonWrite(t *testing.T) {
 		t.Error("should passthrough non-Write tools")
 	}
 }
+
+func TestTestBuddy_Layouts(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		file  string
+	}{
+		{"python tests tree", map[string]string{"pyproject.toml": "", "src/app/models.py": "", "tests/unit/app/test_models.py": ""}, "src/app/models.py"},
+		{"python tests suffix", map[string]string{"setup.cfg": "", "app/models.py": "", "tests/models_test.py": ""}, "app/models.py"},
+		{"js __tests__", map[string]string{"package.json": "{}", "src/utils.ts": "", "src/__tests__/utils.test.ts": ""}, "src/utils.ts"},
+		{"js test in other ext", map[string]string{"package.json": "{}", "src/Button.tsx": "", "src/Button.test.jsx": ""}, "src/Button.tsx"},
+		{"js test dir", map[string]string{"package.json": "{}", "lib/parse.js": "", "test/lib/parse.spec.js": ""}, "lib/parse.js"},
+		{"rust integration test", map[string]string{"Cargo.toml": "", "src/parser.rs": "pub fn parse() {}", "tests/parser.rs": ""}, "src/parser.rs"},
+		{"rust cfg(test)", map[string]string{"Cargo.toml": "", "src/lexer.rs": "fn lex() {}\n#[cfg(test)]\nmod tests {}"}, "src/lexer.rs"},
+		{"java mirror", map[string]string{"pom.xml": "", "src/main/java/com/acme/Order.java": "", "src/test/java/com/acme/OrderTest.java": ""}, "src/main/java/com/acme/Order.java"},
+		{"kotlin mirror", map[string]string{"build.gradle.kts": "", "src/main/kotlin/acme/Cart.kt": "", "src/test/kotlin/acme/CartTests.kt": ""}, "src/main/kotlin/acme/Cart.kt"},
+		{"go example test", map[string]string{"go.mod": "module x", "client/client.go": "package client\n\ntype Client struct{}\n", "client/example_test.go": "package client_test\n\nfunc ExampleClient_Do() {}\n"}, "client/client.go"},
+		{"nested project", map[string]string{"services/api/package.json": "{}", "services/api/src/a.ts": "", "services/api/src/a.test.ts": ""}, "services/api/src/a.ts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			// Relative paths are resolved against workDir
+			result, _ := TestBuddy(writeInput(tt.file, tt.files[tt.file]), dir)
+			if result.Message != "" {
+				t.Errorf("expected test to be found, got %q", result.Message)
+			}
+		})
+	}
+}
+
+func TestTestBuddy_Suggestions(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		file  string
+		want  string
+	}{
+		{"java", map[string]string{"pom.xml": "", "src/main/java/com/acme/Order.java": ""}, "src/main/java/com/acme/Order.java", "src/test/java/com/acme/OrderTest.java"},
+		{"rust", map[string]string{"Cargo.toml": "", "src/parser.rs": "pub fn parse() {}"}, "src/parser.rs", "tests/parser.rs"},
+		{"nested go package", map[string]string{"go.mod": "module x", "internal/store/store.go": "package store"}, "internal/store/store.go", "internal/store/store_test.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			result, _ := TestBuddy(writeInput(tt.file, tt.files[tt.file]), dir)
+			if !strings.HasSuffix(result.Message, "Consider creating "+tt.want) {
+				t.Errorf("expected suggestion %s, got %q", tt.want, result.Message)
+			}
+		})
+	}
+}
+
+func TestTestBuddy_SkipsUntestable(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		file  string
+	}{
+		{"go main package", map[string]string{"go.mod": "module x", "cmd/app/main.go": "package main\n\nfunc main() {}"}, "cmd/app/main.go"},
+		{"go generated", map[string]string{"go.mod": "module x", "api/api.pb.go": "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage api"}, "api/api.pb.go"},
+		{"python init", map[string]string{"app/__init__.py": ""}, "app/__init__.py"},
+		{"python conftest", map[string]string{"conftest.py": ""}, "conftest.py"},
+		{"js config", map[string]string{"package.json": "{}", "vite.config.ts": ""}, "vite.config.ts"},
+		{"type declarations", map[string]string{"package.json": "{}", "src/types.d.ts": ""}, "src/types.d.ts"},
+		{"rust main", map[string]string{"Cargo.toml": "", "src/main.rs": "fn main() {}"}, "src/main.rs"},
+		{"helper in tests tree", map[string]string{"tests/helpers.py": ""}, "tests/helpers.py"},
+		{"java test", map[string]string{"pom.xml": "", "src/test/java/OrderTest.java": ""}, "src/test/java/OrderTest.java"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			result, _ := TestBuddy(writeInput(tt.file, tt.files[tt.file]), dir)
+			if result.Message != "" {
+				t.Errorf("expected no nudge, got %q", result.Message)
+			}
+		})
+	}
+}
+
+func TestTestBuddy_Policy(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"pyproject.toml": "", "app/views.py": "", "spec/views_spec.py": "", "scripts/seed.py": ""})
+
+	policy := &TestBuddyPolicy{Layouts: map[string][]string{"python": {"spec/{name}_spec.py"}}}
+	if result, _ := TestBuddyWithPolicy(writeInput("app/views.py", ""), dir, policy); result.Message != "" {
+		t.Errorf("custom layout should find spec/views_spec.py, got %q", result.Message)
+	}
+	if result, _ := TestBuddyWithPolicy(writeInput("scripts/seed.py", ""), dir, policy); !strings.Contains(result.Message, "spec/seed_spec.py") {
+		t.Errorf("custom layout should be suggested first, got %q", result.Message)
+	}
+	policy.Ignore = []string{"scripts/"}
+	if result, _ := TestBuddyWithPolicy(writeInput("scripts/seed.py", ""), dir, policy); result.Message != "" {
+		t.Errorf("ignored file should not be nudged, got %q", result.Message)
+	}
+}
@@ -0,0 +1,368 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TestRunnerPolicy configures test-runner (policies.testRunner in config.yaml).
+type TestRunnerPolicy struct {
+	// OnStop defers running tests until the agent tries to stop: writes are
+	// only recorded, and the session can't end while their tests fail.
+	OnStop bool `yaml:"onStop,omitempty" json:"onStop,omitempty"`
+	// TimeoutSeconds limits each test command (default 120).
+	TimeoutSeconds int `yaml:"timeoutSeconds,omitempty" json:"timeoutSeconds,omitempty"`
+	// Disable turns runners (go test, pytest, vitest, jest) or languages off.
+	Disable []string `yaml:"disable,omitempty" json:"disable,omitempty"`
+}
+
+const (
+	defaultTestTimeout = 120 * time.Second
+	testRunnerFile     = "test-runner.json"
+	maxTestOutputLines = 60
+)
+
+// testRunner maps a changed file to the tests related to it.
+type testRunner struct {
+	Name     string
+	Language string
+	Exts     []string
+	// Detect reports whether dir is the project root the runner works from;
+	// it is tried from the file's directory up to the workspace root.
+	Detect func(dir string) bool
+	// Args builds the test command for file run from runDir, or nil when the
+	// file has no related tests or the runner is not installed.
+	Args func(file, runDir string) []string
+}
+
+var testRunners = []testRunner{
+	{
+		Name:     "go test",
+		Language: "go",
+		Exts:     []string{".go"},
+		Detect:   func(dir string) bool { return exists(filepath.Join(dir, "go.mod")) },
+		Args:     goTestArgs,
+	},
+	{
+		Name:     "pytest",
+		Language: "python",
+		Exts:     []string{".py"},
+		Detect: func(dir string) bool {
+			return exists(filepath.Join(dir, "pytest.ini")) || exists(filepath.Join(dir, "conftest.py")) ||
+				fileContains(filepath.Join(dir, "pyproject.toml"), "[tool.pytest") ||
+				fileContains(filepath.Join(dir, "setup.cfg"), "[tool:pytest]") ||
+				fileContains(filepath.Join(dir, "tox.ini"), "[pytest]")
+		},
+		Args: pytestArgs,
+	},
+	{
+		Name:     "vitest",
+		Language: "javascript",
+		Exts:     []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".mts", ".cts", ".vue"},
+		Detect:   func(dir string) bool { return fileContains(filepath.Join(dir, "package.json"), `"vitest"`) },
+		Args: func(file, runDir string) []string {
+			return nodeRunnerArgs(runDir, "vitest", "related", "--run", "--passWithNoTests", file)
+		},
+	},
+	{
+		Name:     "jest",
+		Language: "javascript",
+		Exts:     []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".mts", ".cts"},
+		Detect:   func(dir string) bool { return fileContains(filepath.Join(dir, "package.json"), `"jest"`) },
+		Args: func(file, runDir string) []string {
+			return nodeRunnerArgs(runDir, "jest", "--findRelatedTests", file, "--passWithNoTests")
+		},
+	},
+}
+
+// nodeRunnerArgs runs the project's own copy of a JavaScript test runner from
+// node_modules/.bin, or returns nil when it is not installed; npx would
+// otherwise fail the same way a failing test does.
+func nodeRunnerArgs(runDir, name string, args ...string) []string {
+	bin := nodeBin(runDir, name)
+	if bin == "" {
+		return nil
+	}
+	return append([]string{bin}, args...)
+}
+
+var goTestFuncRe = regexp.MustCompile(`(?m)^func ((?:Test|Fuzz)\w*)\(`)
+
+// goTestArgs runs the tests declared in file's _test.go companion (or in
+// file itself when it is a test), or the whole package when there is none.
+func goTestArgs(file, runDir string) []string {
+	testFile := file
+	if !strings.HasSuffix(file, "_test.go") {
+		testFile = strings.TrimSuffix(file, ".go") + "_test.go"
+	}
+	args := []string{"go", "test", goPackageDir(file, runDir)}
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		return args
+	}
+	var names []string
+	for _, m := range goTestFuncRe.FindAllStringSubmatch(string(data), -1) {
+		names = append(names, m[1])
+	}
+	if len(names) == 0 {
+		return args
+	}
+	return append(args, "-run", "^("+strings.Join(names, "|")+")$")
+}
+
+// pytestArgs runs test_<name>.py / <name>_test.py next to file or anywhere
+// under the project's tests/ or test/ directory.
+func pytestArgs(file, runDir string) []string {
+	var targets []string
+	if isTestFile(file) {
+		targets = []string{file}
+	} else {
+		name := strings.TrimSuffix(filepath.Base(file), ".py")
+		wanted := []string{"test_" + name + ".py", name + "_test.py"}
+		for _, w := range wanted {
+			if p := filepath.Join(filepath.Dir(file), w); exists(p) {
+				targets = append(targets, p)
+			}
+		}
+		for _, dir := range []string{"tests", "test"} {
+			filepath.WalkDir(filepath.Join(runDir, dir), func(p string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() && hasString(wanted, d.Name()) && !hasString(targets, p) {
+					targets = append(targets, p)
+				}
+				return nil
+			})
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	args := []string{"pytest", "-q", "-p", "no:cacheprovider"}
+	for _, t := range targets {
+		if rel, err := filepath.Rel(runDir, t); err == nil {
+			t = rel
+		}
+		args = append(args, t)
+	}
+	return args
+}
+
+// testCommand is one related-test run.
+type testCommand struct {
+	Runner string
+	Dir    string
+	Args   []string
+	File   string
+}
+
+// TestRunner is a postToolUse hook that runs the tests related to a written or
+// edited file.
+func TestRunner(input HookInput, workDir string) (HookResult, int) {
+	return TestRunnerWithPolicy(input, workDir, "", nil)
+}
+
+// TestRunnerWithPolicy runs the tests related to a written file (go test with
+// -run for the file's tests, pytest on its test modules, vitest/jest related
+// tests) and reports failures. With policy.OnStop, writes are recorded in the
+// session state under stateDir and the tests run when the Stop event fires,
+// blocking the stop while they fail.
+func TestRunnerWithPolicy(input HookInput, workDir, stateDir string, policy *TestRunnerPolicy) (HookResult, int) {
+	if policy == nil {
+		policy = &TestRunnerPolicy{}
+	}
+	if input.ToolName == "" || input.ToolName == "Stop" {
+		return testRunnerStop(input, workDir, stateDir, policy)
+	}
+	if input.ToolName != "Write" && input.ToolName != "Edit" && input.ToolName != "MultiEdit" {
+		return Allow(), 0
+	}
+
+	path := input.WritePath()
+	if path == "" {
+		return Allow(), 0
+	}
+	absPath := path
+	if !filepath.IsAbs(path) && workDir != "" {
+		absPath = filepath.Join(workDir, path)
+	}
+
+	if policy.OnStop {
+		if stateDir != "" {
+			pending := loadPendingTests(stateDir, input.SessionID())
+			if !hasString(pending, absPath) {
+				savePendingTests(stateDir, input.SessionID(), append(pending, absPath))
+			}
+		}
+		return Allow(), 0
+	}
+
+	failures := runRelatedTests(relatedTestCommands(workDir, []string{absPath}, policy), policy)
+	if len(failures) == 0 {
+		return Allow(), 0
+	}
+	return Deny(testFailureReason(failures, workDir)), 2
+}
+
+// testRunnerStop runs the tests for files recorded during the session.
+func testRunnerStop(input HookInput, workDir, stateDir string, policy *TestRunnerPolicy) (HookResult, int) {
+	if input.StopHookActive() || stateDir == "" {
+		return NoOp(), 0
+	}
+	pending := loadPendingTests(stateDir, input.SessionID())
+	if len(pending) == 0 {
+		return NoOp(), 0
+	}
+	failures := runRelatedTests(relatedTestCommands(workDir, pending, policy), policy)
+	if len(failures) == 0 {
+		savePendingTests(stateDir, input.SessionID(), nil)
+		return NoOp(), 0
+	}
+	return Block(testFailureReason(failures, workDir) + "\n\nFix the failing tests before finishing."), 0
+}
+
+// relatedTestCommands maps files to test commands, dropping duplicates.
+func relatedTestCommands(workDir string, files []string, policy *TestRunnerPolicy) []testCommand {
+	root := workDir
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+	var cmds []testCommand
+	seen := map[string]bool{}
+	for _, file := range files {
+		ext := filepath.Ext(file)
+		for _, r := range testRunners {
+			if !hasString(r.Exts, ext) || hasString(policy.Disable, r.Name) || hasString(policy.Disable, r.Language) {
+				continue
+			}
+			dir := ""
+			for _, d := range dirsUpTo(filepath.Dir(file), root) {
+				if r.Detect(d) {
+					dir = d
+					break
+				}
+			}
+			if dir == "" {
+				continue
+			}
+			args := r.Args(file, dir)
+			if len(args) > 0 {
+				key := dir + "\x00" + strings.Join(args, "\x00")
+				if !seen[key] {
+					seen[key] = true
+					cmds = append(cmds, testCommand{Runner: r.Name, Dir: dir, Args: args, File: file})
+				}
+			}
+			break // one runner per file
+		}
+	}
+	return cmds
+}
+
+type testFailure struct {
+	Command testCommand
+	Output  string
+	Timeout time.Duration
+}
+
+// runRelatedTests runs cmds and returns the ones that failed or timed out.
+// Commands whose tool or interpreter is missing are skipped.
+func runRelatedTests(cmds []testCommand, policy *TestRunnerPolicy) []testFailure {
+	timeout := defaultTestTimeout
+	if policy.TimeoutSeconds > 0 {
+		timeout = time.Duration(policy.TimeoutSeconds) * time.Second
+	}
+	var failures []testFailure
+	for _, tc := range cmds {
+		if !commandExists(tc.Args[0]) {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		cmd := exec.CommandContext(ctx, tc.Args[0], tc.Args[1:]...)
+		cmd.Dir = tc.Dir
+		cmd.WaitDelay = time.Second // don't wait on test processes that outlive the runner
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		err := cmd.Run()
+		timedOut := ctx.Err() == context.DeadlineExceeded
+		cancel()
+		switch {
+		case timedOut:
+			failures = append(failures, testFailure{Command: tc, Output: lastLines(out.String(), maxTestOutputLines), Timeout: timeout})
+		case err != nil && cmd.ProcessState != nil && cmd.ProcessState.ExitCode() == 127:
+			// The runner's interpreter (node, python) is missing: not a test failure
+		case err != nil && cmd.ProcessState != nil:
+			failures = append(failures, testFailure{Command: tc, Output: lastLines(out.String(), maxTestOutputLines)})
+		}
+	}
+	return failures
+}
+
+func testFailureReason(failures []testFailure, workDir string) string {
+	var b strings.Builder
+	var files []string
+	for _, f := range failures {
+		file := f.Command.File
+		if rel, err := filepath.Rel(workDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+		if !hasString(files, file) {
+			files = append(files, file)
+		}
+	}
+	subject := describeFiles(files, "file", "files")
+	if len(files) == 1 {
+		subject = files[0]
+	}
+	fmt.Fprintf(&b, "Tests related to %s failed", subject)
+	for _, f := range failures {
+		cmdLine := strings.Join(f.Command.Args, " ")
+		if f.Timeout > 0 {
+			fmt.Fprintf(&b, "\n\n%s timed out after %s (raise policies.testRunner.timeoutSeconds)", cmdLine, f.Timeout)
+		} else {
+			fmt.Fprintf(&b, "\n\n%s:", cmdLine)
+		}
+		if f.Output != "" {
+			b.WriteString("\n" + f.Output)
+		}
+	}
+	b.WriteString("\n  Hints:")
+	b.WriteString("\n    - Fix the code or update the tests if the behavior change is intended")
+	return b.String()
+}
+
+// lastLines returns the last n lines of s, trimmed.
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = append([]string{fmt.Sprintf("... %d lines omitted", len(lines)-n)}, lines[len(lines)-n:]...)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func loadPendingTests(stateDir, session string) []string {
+	var state struct {
+		Files []string `json:"files"`
+	}
+	if data, err := os.ReadFile(filepath.Join(SessionStateDir(stateDir, session), testRunnerFile)); err == nil {
+		json.Unmarshal(data, &state)
+	}
+	return state.Files
+}
+
+func savePendingTests(stateDir, session string, files []string) {
+	dir := SessionStateDir(stateDir, session)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	data, _ := json.Marshal(map[string][]string{"files": files})
+	os.WriteFile(filepath.Join(dir, testRunnerFile), data, 0644)
+}
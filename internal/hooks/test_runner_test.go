@@ -0,0 +1,148 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRelatedTestCommands(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		file   string
+		policy *TestRunnerPolicy
+		want   string
+	}{
+		{"go file with tests", map[string]string{"go.mod": "module x", "pkg/a.go": "package pkg", "pkg/a_test.go": "package pkg\nfunc TestA(t *testing.T) {}\nfunc TestB(t *testing.T) {}\nfunc helper() {}"},
+			"pkg/a.go", nil, "go test ./pkg -run ^(TestA|TestB)$"},
+		{"go test file", map[string]string{"go.mod": "module x", "a_test.go": "package x\nfunc TestOnly(t *testing.T) {}"},
+			"a_test.go", nil, "go test . -run ^(TestOnly)$"},
+		{"go file without tests", map[string]string{"go.mod": "module x", "pkg/a.go": "package pkg"},
+			"pkg/a.go", nil, "go test ./pkg"},
+		{"pytest tests tree", map[string]string{"pyproject.toml": "[tool.pytest.ini_options]", "src/app/models.py": "", "tests/unit/test_models.py": ""},
+			"src/app/models.py", nil, "pytest -q -p no:cacheprovider tests/unit/test_models.py"},
+		{"pytest sibling", map[string]string{"conftest.py": "", "app/util.py": "", "app/util_test.py": ""},
+			"app/util.py", nil, "pytest -q -p no:cacheprovider app/util_test.py"},
+		{"pytest no related tests", map[string]string{"pytest.ini": "", "app/util.py": ""},
+			"app/util.py", nil, ""},
+		{"vitest", map[string]string{"package.json": `{"devDependencies": {"vitest": "^1"}}`, "node_modules/.bin/vitest": "", "src/a.ts": ""},
+			"src/a.ts", nil, "{dir}/node_modules/.bin/vitest related --run --passWithNoTests "},
+		{"jest", map[string]string{"package.json": `{"devDependencies": {"jest": "^29"}}`, "node_modules/.bin/jest": "", "src/a.js": ""},
+			"src/a.js", nil, "{dir}/node_modules/.bin/jest --findRelatedTests "},
+		{"jest from workspace root", map[string]string{"node_modules/.bin/jest": "", "web/package.json": `{"devDependencies": {"jest": "^29"}}`, "web/src/a.js": ""},
+			"web/src/a.js", nil, "{dir}/node_modules/.bin/jest --findRelatedTests "},
+		{"vitest not installed", map[string]string{"package.json": `{"devDependencies": {"vitest": "^1"}}`, "src/a.ts": ""},
+			"src/a.ts", nil, ""},
+		{"no test setup", map[string]string{"src/a.ts": ""}, "src/a.ts", nil, ""},
+		{"disabled language", map[string]string{"go.mod": "module x", "a.go": "package x"}, "a.go", &TestRunnerPolicy{Disable: []string{"go"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			policy := tt.policy
+			if policy == nil {
+				policy = &TestRunnerPolicy{}
+			}
+			cmds := relatedTestCommands(dir, []string{filepath.Join(dir, tt.file)}, policy)
+			got := ""
+			want := strings.ReplaceAll(tt.want, "{dir}", dir)
+			if len(cmds) > 0 {
+				got = strings.Join(cmds[0].Args, " ")
+			}
+			if want == "" && got != "" || !strings.HasPrefix(got, want) || want != "" && got == "" {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestTestRunner_ReportsFailures(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"pytest.ini": "", "app/util.py": "", "tests/test_util.py": ""})
+	file := filepath.Join(dir, "app", "util.py")
+
+	fakeTool(t, "pytest", "echo 'FAILED tests/test_util.py::test_parse - AssertionError'\nexit 1")
+	result, code := TestRunnerWithPolicy(writeInput(file, ""), dir, "", nil)
+	if code != 2 || !strings.Contains(result.Reason, "Tests related to app/util.py failed") || !strings.Contains(result.Reason, "test_parse - AssertionError") {
+		t.Errorf("expected failing tests to deny, got code=%d %q", code, result.Reason)
+	}
+
+	fakeTool(t, "pytest", "exit 0")
+	if _, code := TestRunnerWithPolicy(writeInput(file, ""), dir, "", nil); code != 0 {
+		t.Error("passing tests should allow")
+	}
+
+	// A runner whose interpreter is gone is not a test failure
+	fakeTool(t, "pytest", "echo 'python3: not found' >&2\nexit 127")
+	if result, code := TestRunnerWithPolicy(writeInput(file, ""), dir, "", nil); code != 0 {
+		t.Errorf("a missing interpreter should not deny, got %q", result.Reason)
+	}
+
+	fakeTool(t, "pytest", "exit 1")
+	for _, tool := range []string{"Edit", "MultiEdit"} {
+		if _, code := TestRunnerWithPolicy(toolInput(tool, map[string]string{"file_path": file}), dir, "", nil); code != 2 {
+			t.Errorf("expected failing tests to deny after %s, got code=%d", tool, code)
+		}
+	}
+
+	fakeTool(t, "pytest", "sleep 5")
+	result, code = TestRunnerWithPolicy(writeInput(file, ""), dir, "", &TestRunnerPolicy{TimeoutSeconds: 1})
+	if code != 2 || !strings.Contains(result.Reason, "timed out") {
+		t.Errorf("expected timeout to be reported, got code=%d %q", code, result.Reason)
+	}
+}
+
+func TestTestRunner_OnStop(t *testing.T) {
+	dir := t.TempDir()
+	stateDir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"pytest.ini": "", "app/util.py": "", "tests/test_util.py": ""})
+	policy := &TestRunnerPolicy{OnStop: true}
+	fakeTool(t, "pytest", "echo 'FAILED tests/test_util.py::test_parse'\nexit 1")
+
+	if _, code := TestRunnerWithPolicy(writeInput(filepath.Join(dir, "app", "util.py"), ""), dir, stateDir, policy); code != 0 {
+		t.Error("writes should only be recorded in onStop mode")
+	}
+	edit := toolInput("Edit", map[string]string{"file_path": filepath.Join(dir, "app", "models.py")})
+	if _, code := TestRunnerWithPolicy(edit, dir, stateDir, policy); code != 0 {
+		t.Error("edits should only be recorded in onStop mode")
+	}
+	if pending := loadPendingTests(stateDir, ""); len(pending) != 2 {
+		t.Errorf("expected the write and the edit to be recorded, got %v", pending)
+	}
+
+	result, code := TestRunnerWithPolicy(stopInput("", "", false), dir, stateDir, policy)
+	if code != 0 || result.Decision != "block" || !strings.Contains(result.Reason, "test_parse") {
+		t.Errorf("expected stop to be blocked, got code=%d %+v", code, result)
+	}
+	if result, _ := TestRunnerWithPolicy(stopInput("", "", true), dir, stateDir, policy); result.Decision != "" {
+		t.Errorf("stop_hook_active should not block again, got %+v", result)
+	}
+
+	fakeTool(t, "pytest", "exit 0")
+	if result, _ := TestRunnerWithPolicy(stopInput("", "", false), dir, stateDir, policy); result.Decision != "" {
+		t.Errorf("passing tests should let the session stop, got %+v", result)
+	}
+	if pending := loadPendingTests(stateDir, ""); len(pending) != 0 {
+		t.Errorf("passing tests should clear the pending files, got %v", pending)
+	}
+}
+
+func TestTestRunner_IgnoresOtherTools(t *testing.T) {
+	if result, code := TestRunner(shellInput("go test ./..."), t.TempDir()); code != 0 || result.Decision != "allow" {
+		t.Errorf("expected allow for Shell, got code=%d", code)
+	}
+}
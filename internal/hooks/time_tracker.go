@@ -1,13 +1,34 @@
 package hooks
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 )
 
-// TimeTracker is a sessionStart/sessionEnd hook that logs session timestamps.
+// TimeTrackerPolicy configures time-tracker and `hooks time`
+// (policies.timeTracker in config.yaml).
+type TimeTrackerPolicy struct {
+	// IdleMinutes is the longest gap between tool calls that still counts as
+	// active time (default 10).
+	IdleMinutes int `yaml:"idleMinutes,omitempty" json:"idleMinutes,omitempty"`
+}
+
+// DefaultIdle is the default idle cutoff for active time.
+const DefaultIdle = 10 * time.Minute
+
+const timeLayout = "2006-01-02 15:04:05"
+
+var sessionLineRe = regexp.MustCompile(`^\[([^\]]+)\]\s+(START|END)\b\s*(.*)$`)
+
+// TimeTracker is a sessionStart/sessionEnd hook that logs session start and
+// end with the session id, repo, branch and working directory.
 func TimeTracker(input HookInput, event string, logDir string) (HookResult, int) {
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		return NoOp(), 0
@@ -20,12 +41,288 @@ func TimeTracker(input HookInput, event string, logDir string) (HookResult, int)
 	}
 	defer f.Close()
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	timestamp := time.Now().Format(timeLayout)
 	label := "START"
 	if event == "end" {
 		label = "END"
 	}
 
-	fmt.Fprintf(f, "[%s] %s\n", timestamp, label)
+	line := "[" + timestamp + "] " + label
+	cwd := input.Cwd()
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+	if id := input.SessionID(); id != "" {
+		line += " session=" + strings.Join(strings.Fields(id), "_")
+	}
+	if repo := repoNameFromCwd(cwd); repo != "" {
+		line += " repo=" + repo
+	}
+	if branch := gitOutput(cwd, "rev-parse", "--abbrev-ref", "HEAD"); branch != "" {
+		line += " branch=" + branch
+	}
+	if cwd != "" {
+		line += " cwd=" + cwd
+	}
+	fmt.Fprintln(f, line)
 	return NoOp(), 0
 }
+
+// TimeSession is one agent session reconstructed from sessions.log and its
+// tool calls.
+type TimeSession struct {
+	ID     string    `json:"session_id,omitempty"`
+	Repo   string    `json:"repo,omitempty"`
+	Branch string    `json:"branch,omitempty"`
+	Cwd    string    `json:"cwd,omitempty"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	// Status is "ended", "open" (no END yet, recently active) or "no end"
+	// (crashed or killed; End is its last tool call).
+	Status string `json:"status"`
+	// Active is the time between events with gaps longer than the idle
+	// cutoff left out; ActiveByDay splits it by local date.
+	Active      time.Duration            `json:"-"`
+	ActiveByDay map[string]time.Duration `json:"-"`
+	ToolCalls   int                      `json:"tool_calls"`
+}
+
+// Wall is the time from start to end.
+func (s TimeSession) Wall() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// MarshalJSON writes durations as whole seconds (active_seconds,
+// active_seconds_by_day, wall_seconds) rather than nanoseconds.
+func (s TimeSession) MarshalJSON() ([]byte, error) {
+	type session TimeSession
+	byDay := make(map[string]int64, len(s.ActiveByDay))
+	for day, d := range s.ActiveByDay {
+		byDay[day] = seconds(d)
+	}
+	return json.Marshal(struct {
+		session
+		Active      int64            `json:"active_seconds"`
+		ActiveByDay map[string]int64 `json:"active_seconds_by_day"`
+		Wall        int64            `json:"wall_seconds"`
+	}{session(s), seconds(s.Active), byDay, seconds(s.Wall())})
+}
+
+func seconds(d time.Duration) int64 {
+	return int64(d.Round(time.Second) / time.Second)
+}
+
+// LoadTimeSessions reads sessions.log in timeDir and pairs each session's
+// START with its END. Tool-call times come from the session's activity log
+// under stateDir, or for sessions without an id from the audit logs in
+// auditDir. Sessions without an END end at their last tool call.
+func LoadTimeSessions(timeDir, stateDir, auditDir string, idle time.Duration, now time.Time) ([]TimeSession, error) {
+	f, err := os.Open(filepath.Join(timeDir, "sessions.log"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if idle <= 0 {
+		idle = DefaultIdle
+	}
+
+	var sessions []*TimeSession
+	byID := map[string]*TimeSession{}
+	var anonymous *TimeSession // open session logged without an id
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m := sessionLineRe.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		at, err := time.ParseInLocation(timeLayout, m[1], time.Local)
+		if err != nil {
+			continue
+		}
+		fields := sessionLineFields(m[3])
+		id := fields["session"]
+
+		s := byID[id]
+		if id == "" {
+			s = anonymous
+		}
+		if m[2] == "START" {
+			if s == nil || id == "" {
+				s = &TimeSession{ID: id, Start: at}
+				sessions = append(sessions, s)
+				if id == "" {
+					anonymous = s
+				} else {
+					byID[id] = s
+				}
+			}
+			// A resumed session keeps its first start; the rest may change.
+			for k, v := range map[string]*string{"repo": &s.Repo, "branch": &s.Branch, "cwd": &s.Cwd} {
+				if fields[k] != "" {
+					*v = fields[k]
+				}
+			}
+			s.Status = ""
+			continue
+		}
+		if s == nil {
+			continue // END without a START
+		}
+		s.End, s.Status = at, "ended"
+		if id == "" {
+			anonymous = nil
+		}
+	}
+
+	out := make([]TimeSession, 0, len(sessions))
+	for i, s := range sessions {
+		var events []time.Time
+		if s.ID != "" && stateDir != "" {
+			for _, ev := range loadSessionEvents(stateDir, s.ID) {
+				events = append(events, ev.Time)
+			}
+		} else {
+			until := now
+			if s.Status == "ended" {
+				until = s.End
+			} else if i+1 < len(sessions) {
+				until = sessions[i+1].Start
+			}
+			events = auditEventTimes(auditDir, s.Start, until)
+		}
+		kept := events[:0]
+		for _, t := range events {
+			if t.Before(s.Start) {
+				continue
+			}
+			kept = append(kept, t)
+			if t.After(s.End) {
+				s.End = t
+			}
+		}
+		events = kept
+		s.ToolCalls = len(events)
+		if s.End.Before(s.Start) {
+			s.End = s.Start
+		}
+		if s.Status == "" {
+			s.Status = "no end"
+			if now.Sub(s.End) < idle {
+				s.Status = "open"
+			}
+		}
+
+		points := append([]time.Time{s.Start, s.End}, events...)
+		sort.Slice(points, func(a, b int) bool { return points[a].Before(points[b]) })
+		s.ActiveByDay = map[string]time.Duration{}
+		for j := 1; j < len(points); j++ {
+			if gap := points[j].Sub(points[j-1]); gap > 0 && gap <= idle {
+				s.Active += gap
+				s.ActiveByDay[points[j-1].Format("2006-01-02")] += gap
+			}
+		}
+		out = append(out, *s)
+	}
+	return out, nil
+}
+
+// sessionLineFields parses "key=value" pairs after START/END; cwd runs to the
+// end of the line since paths may contain spaces.
+func sessionLineFields(rest string) map[string]string {
+	fields := map[string]string{}
+	if i := strings.Index(rest, "cwd="); i >= 0 {
+		fields["cwd"] = strings.TrimSpace(rest[i+len("cwd="):])
+		rest = rest[:i]
+	}
+	for _, kv := range strings.Fields(rest) {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// auditEventTimes returns the times of the audit log entries between from and
+// until.
+func auditEventTimes(auditDir string, from, until time.Time) []time.Time {
+	if auditDir == "" {
+		return nil
+	}
+	var times []time.Time
+	for day := from; !day.After(until.AddDate(0, 0, 1)); day = day.AddDate(0, 0, 1) {
+		data, err := os.ReadFile(filepath.Join(auditDir, "audit-"+day.Format("2006-01-02")+".log"))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			m := auditLineRe.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			t, err := time.ParseInLocation(timeLayout, m[1], time.Local)
+			if err == nil && !t.Before(from) && !t.After(until) {
+				times = append(times, t)
+			}
+		}
+	}
+	return times
+}
+
+// TimeTotal is the time spent in one repo on one day.
+type TimeTotal struct {
+	Day      string        `json:"day,omitempty"`
+	Repo     string        `json:"repo,omitempty"`
+	Sessions int           `json:"sessions"`
+	Active   time.Duration `json:"-"`
+	Wall     time.Duration `json:"-"`
+}
+
+// MarshalJSON writes durations as whole seconds (active_seconds, wall_seconds).
+func (t TimeTotal) MarshalJSON() ([]byte, error) {
+	type total TimeTotal
+	return json.Marshal(struct {
+		total
+		Active int64 `json:"active_seconds"`
+		Wall   int64 `json:"wall_seconds"`
+	}{total(t), seconds(t.Active), seconds(t.Wall)})
+}
+
+// TimeTotals sums sessions per day and repo; with byDay false, per repo only.
+// Active time is split by the day it happened; a session's wall time and
+// count go to the day it started.
+func TimeTotals(sessions []TimeSession, byDay bool) []TimeTotal {
+	totals := map[[2]string]*TimeTotal{}
+	get := func(day, repo string) *TimeTotal {
+		if !byDay {
+			day = ""
+		}
+		key := [2]string{day, repo}
+		if totals[key] == nil {
+			totals[key] = &TimeTotal{Day: day, Repo: repo}
+		}
+		return totals[key]
+	}
+	for _, s := range sessions {
+		repo := s.Repo
+		if repo == "" {
+			repo = "-"
+		}
+		t := get(s.Start.Format("2006-01-02"), repo)
+		t.Sessions++
+		t.Wall += s.Wall()
+		for day, d := range s.ActiveByDay {
+			get(day, repo).Active += d
+		}
+	}
+	out := make([]TimeTotal, 0, len(totals))
+	for _, t := range totals {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Day != out[j].Day {
+			return out[i].Day < out[j].Day
+		}
+		return out[i].Repo < out[j].Repo
+	})
+	return out
+}
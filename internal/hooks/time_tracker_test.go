@@ -1,10 +1,12 @@
 package hooks
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestTimeTracker_LogsStart(t *testing.T) {
@@ -43,3 +45,234 @@ func TestTimeTracker_AlwaysExitsZero(t *testing.T) {
 		t.Errorf("should always exit 0, got %d", code)
 	}
 }
+
+func TestTimeTracker_LogsSessionAndCwd(t *testing.T) {
+	dir := t.TempDir()
+	cwd := t.TempDir()
+	TimeTracker(HookInput{ToolInput: []byte(`{"session_id":"abc 123","cwd":"` + cwd + `"}`)}, "start", dir)
+
+	data, _ := os.ReadFile(filepath.Join(dir, "sessions.log"))
+	line := string(data)
+	if !strings.Contains(line, "session=abc_123") {
+		t.Errorf("log missing session id, got: %s", line)
+	}
+	if !strings.Contains(line, "cwd="+cwd) {
+		t.Errorf("log missing cwd, got: %s", line)
+	}
+}
+
+func TestSessionLineFields(t *testing.T) {
+	got := sessionLineFields("session=abc repo=hooks branch=main cwd=/tmp/my repo")
+	want := map[string]string{"session": "abc", "repo": "hooks", "branch": "main", "cwd": "/tmp/my repo"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if len(sessionLineFields("")) != 0 {
+		t.Error("expected no fields for an old-style line")
+	}
+}
+
+// writeTimeFixtures writes sessions.log lines and per-session activity.
+func writeTimeFixtures(t *testing.T, lines []string, activity map[string][]time.Time) (string, string) {
+	t.Helper()
+	timeDir, stateDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(timeDir, "sessions.log"), []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	for id, times := range activity {
+		dir := SessionStateDir(stateDir, id)
+		os.MkdirAll(dir, 0755)
+		var buf strings.Builder
+		for _, at := range times {
+			data, _ := json.Marshal(SessionEvent{Time: at, Tool: "Shell"})
+			buf.Write(append(data, '\n'))
+		}
+		os.WriteFile(filepath.Join(dir, sessionActivityFile), []byte(buf.String()), 0644)
+	}
+	return timeDir, stateDir
+}
+
+func TestLoadTimeSessions(t *testing.T) {
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)
+	at := func(min int) time.Time { return base.Add(time.Duration(min) * time.Minute) }
+	stamp := func(min int) string { return "[" + at(min).Format(timeLayout) + "]" }
+	now := at(600)
+
+	tests := []struct {
+		name       string
+		lines      []string
+		activity   map[string][]time.Time
+		wantStatus []string
+		wantActive []time.Duration
+		wantWall   []time.Duration
+		wantCalls  []int
+	}{
+		{
+			name: "interleaved sessions pair by id",
+			lines: []string{
+				stamp(0) + " START session=a repo=hooks",
+				stamp(5) + " START session=b repo=web",
+				stamp(20) + " END session=a repo=hooks",
+				stamp(30) + " END session=b repo=web",
+			},
+			activity:   map[string][]time.Time{"a": {at(5), at(10), at(15)}, "b": {at(10), at(20)}},
+			wantStatus: []string{"ended", "ended"},
+			wantActive: []time.Duration{20 * time.Minute, 25 * time.Minute},
+			wantWall:   []time.Duration{20 * time.Minute, 25 * time.Minute},
+			wantCalls:  []int{3, 2},
+		},
+		{
+			name:       "idle gaps are not active",
+			lines:      []string{stamp(0) + " START session=a", stamp(120) + " END session=a"},
+			activity:   map[string][]time.Time{"a": {at(5), at(10), at(100), at(115)}},
+			wantStatus: []string{"ended"},
+			wantActive: []time.Duration{15 * time.Minute},
+			wantWall:   []time.Duration{120 * time.Minute},
+			wantCalls:  []int{4},
+		},
+		{
+			name:       "crashed session ends at its last call",
+			lines:      []string{stamp(0) + " START session=a", stamp(60) + " START session=b", stamp(70) + " END session=b"},
+			activity:   map[string][]time.Time{"a": {at(3), at(8)}},
+			wantStatus: []string{"no end", "ended"},
+			wantActive: []time.Duration{8 * time.Minute, 10 * time.Minute},
+			wantWall:   []time.Duration{8 * time.Minute, 10 * time.Minute},
+			wantCalls:  []int{2, 0},
+		},
+		{
+			name:       "session still running is open",
+			lines:      []string{stamp(590) + " START session=a"},
+			activity:   map[string][]time.Time{"a": {at(595)}},
+			wantStatus: []string{"open"},
+			wantActive: []time.Duration{5 * time.Minute},
+			wantWall:   []time.Duration{5 * time.Minute},
+			wantCalls:  []int{1},
+		},
+		{
+			name:       "anonymous sessions pair in order",
+			lines:      []string{stamp(0) + " START", stamp(5) + " END", stamp(10) + " START", stamp(40) + " END"},
+			wantStatus: []string{"ended", "ended"},
+			wantActive: []time.Duration{5 * time.Minute, 0},
+			wantWall:   []time.Duration{5 * time.Minute, 30 * time.Minute},
+			wantCalls:  []int{0, 0},
+		},
+		{
+			name:       "calls before start are ignored",
+			lines:      []string{stamp(10) + " START session=a", stamp(15) + " END session=a"},
+			activity:   map[string][]time.Time{"a": {at(0), at(12)}},
+			wantStatus: []string{"ended"},
+			wantActive: []time.Duration{5 * time.Minute},
+			wantWall:   []time.Duration{5 * time.Minute},
+			wantCalls:  []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeDir, stateDir := writeTimeFixtures(t, tt.lines, tt.activity)
+			sessions, err := LoadTimeSessions(timeDir, stateDir, "", 10*time.Minute, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sessions) != len(tt.wantStatus) {
+				t.Fatalf("got %d sessions, want %d: %+v", len(sessions), len(tt.wantStatus), sessions)
+			}
+			for i, s := range sessions {
+				if s.Status != tt.wantStatus[i] {
+					t.Errorf("session %d status = %q, want %q", i, s.Status, tt.wantStatus[i])
+				}
+				if s.Active != tt.wantActive[i] {
+					t.Errorf("session %d active = %v, want %v", i, s.Active, tt.wantActive[i])
+				}
+				if s.Wall() != tt.wantWall[i] {
+					t.Errorf("session %d wall = %v, want %v", i, s.Wall(), tt.wantWall[i])
+				}
+				if s.ToolCalls != tt.wantCalls[i] {
+					t.Errorf("session %d tool calls = %d, want %d", i, s.ToolCalls, tt.wantCalls[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadTimeSessions_AnonymousUsesAuditLog(t *testing.T) {
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)
+	timeDir, _ := writeTimeFixtures(t, []string{
+		"[" + base.Format(timeLayout) + "] START",
+		"[" + base.Add(30*time.Minute).Format(timeLayout) + "] END",
+	}, nil)
+	auditDir := t.TempDir()
+	var log strings.Builder
+	for _, min := range []int{-5, 2, 6, 28, 45} {
+		log.WriteString("[" + base.Add(time.Duration(min)*time.Minute).Format(timeLayout) + "] tool=Shell command=ls\n")
+	}
+	os.WriteFile(filepath.Join(auditDir, "audit-"+base.Format("2006-01-02")+".log"), []byte(log.String()), 0644)
+
+	sessions, err := LoadTimeSessions(timeDir, "", auditDir, 10*time.Minute, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ToolCalls != 3 {
+		t.Fatalf("expected one session with 3 calls, got %+v", sessions)
+	}
+	// 0-2-6 active, 6-28 idle, 28-30 active.
+	if sessions[0].Active != 8*time.Minute {
+		t.Errorf("active = %v, want 8m", sessions[0].Active)
+	}
+}
+
+func TestTimeTotals(t *testing.T) {
+	day1 := time.Date(2026, 3, 2, 23, 0, 0, 0, time.Local)
+	sessions := []TimeSession{
+		{Repo: "hooks", Start: day1, End: day1.Add(2 * time.Hour),
+			ActiveByDay: map[string]time.Duration{"2026-03-02": 40 * time.Minute, "2026-03-03": 30 * time.Minute}},
+		{Repo: "hooks", Start: day1.Add(12 * time.Hour), End: day1.Add(13 * time.Hour),
+			ActiveByDay: map[string]time.Duration{"2026-03-03": 20 * time.Minute}},
+		{Start: day1, End: day1.Add(time.Hour), ActiveByDay: map[string]time.Duration{"2026-03-02": 10 * time.Minute}},
+	}
+
+	byDay := TimeTotals(sessions, true)
+	want := []TimeTotal{
+		{Day: "2026-03-02", Repo: "-", Sessions: 1, Active: 10 * time.Minute, Wall: time.Hour},
+		{Day: "2026-03-02", Repo: "hooks", Sessions: 1, Active: 40 * time.Minute, Wall: 2 * time.Hour},
+		{Day: "2026-03-03", Repo: "hooks", Sessions: 1, Active: 50 * time.Minute, Wall: time.Hour},
+	}
+	if len(byDay) != len(want) {
+		t.Fatalf("got %+v, want %+v", byDay, want)
+	}
+	for i := range want {
+		if byDay[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, byDay[i], want[i])
+		}
+	}
+
+	byRepo := TimeTotals(sessions, false)
+	if len(byRepo) != 2 || byRepo[1].Repo != "hooks" || byRepo[1].Sessions != 2 || byRepo[1].Active != 90*time.Minute {
+		t.Errorf("unexpected per-repo totals: %+v", byRepo)
+	}
+}
+
+func TestTimeJSON_Seconds(t *testing.T) {
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	s := TimeSession{ID: "s1", Start: start, End: start.Add(time.Hour), Status: "ended",
+		Active: 25 * time.Minute, ActiveByDay: map[string]time.Duration{"2026-03-02": 25 * time.Minute}}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	json.Unmarshal(data, &got)
+	if got["active_seconds"] != 1500.0 || got["wall_seconds"] != 3600.0 || got["session_id"] != "s1" {
+		t.Errorf("unexpected session JSON %s", data)
+	}
+	if byDay, _ := got["active_seconds_by_day"].(map[string]interface{}); byDay["2026-03-02"] != 1500.0 {
+		t.Errorf("unexpected active_seconds_by_day in %s", data)
+	}
+	if _, ok := got["active"]; ok {
+		t.Errorf("durations should only be written in seconds, got %s", data)
+	}
+
+	data, _ = json.Marshal(TimeTotal{Repo: "hooks", Sessions: 2, Active: 90 * time.Second, Wall: 2 * time.Hour})
+	if string(data) != `{"repo":"hooks","sessions":2,"active_seconds":90,"wall_seconds":7200}` {
+		t.Errorf("unexpected total JSON %s", data)
+	}
+}
@@ -3,6 +3,9 @@ package hooks
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -11,9 +14,127 @@ import (
 	"time"
 )
 
-// TypecheckChanged is a postToolUse hook that runs TypeScript type checking on changed files.
-// It runs `npx tsc --noEmit` on .ts/.tsx files when tsconfig.json exists.
+// TypecheckPolicy configures typecheck-changed (policies.typecheckChanged in config.yaml).
+type TypecheckPolicy struct {
+	// Timeouts are seconds per language: typescript, go, python, rust.
+	Timeouts map[string]int `yaml:"timeouts,omitempty" json:"timeouts,omitempty"`
+	// Disable turns checkers (tsc, go build, go vet, mypy, pyright, cargo check)
+	// or whole languages off.
+	Disable []string `yaml:"disable,omitempty" json:"disable,omitempty"`
+}
+
+// typeChecker is one project-wide type checker for a language.
+type typeChecker struct {
+	Name     string
+	Language string
+	Exts     []string
+	// Detect reports whether dir holds the project config the checker needs;
+	// it is tried from the file's directory up to the project root and the
+	// checker runs in the first directory that matches.
+	Detect func(dir string) bool
+	// Args builds the command for file run from runDir; cacheDir is where
+	// incremental state may be kept ("" disables it). nil means the checker
+	// is not installed for this project.
+	Args   func(file, runDir, cacheDir string) []string
+	Format string
+}
+
+// typecheckTimeouts are the default per-language time limits.
+var typecheckTimeouts = map[string]time.Duration{
+	"typescript": 120 * time.Second,
+	"go":         60 * time.Second,
+	"python":     60 * time.Second,
+	"rust":       120 * time.Second,
+}
+
+var typeCheckers = []typeChecker{
+	{
+		Name:     "tsc",
+		Language: "typescript",
+		Exts:     []string{".ts", ".tsx", ".mts", ".cts"},
+		Detect:   func(dir string) bool { return exists(filepath.Join(dir, "tsconfig.json")) },
+		Args: func(_, runDir, cacheDir string) []string {
+			// Only the project's own TypeScript; without it there is nothing to run
+			tsc := nodeBin(runDir, "tsc")
+			if tsc == "" {
+				return nil
+			}
+			// tsc ignores tsconfig.json when given files, so check the project
+			// and keep build info between runs so only changed files are redone.
+			args := []string{tsc, "-p", "tsconfig.json", "--noEmit", "--pretty", "false"}
+			if cacheDir != "" {
+				args = append(args, "--incremental", "--tsBuildInfoFile", filepath.Join(cacheDir, "tsc-"+shortHash(runDir)+".tsbuildinfo"))
+			}
+			return args
+		},
+		Format: formatTsc,
+	},
+	{
+		Name:     "go build",
+		Language: "go",
+		Exts:     []string{".go"},
+		Detect:   func(dir string) bool { return exists(filepath.Join(dir, "go.mod")) },
+		Args: func(file, runDir, _ string) []string {
+			return []string{"go", "build", "-o", os.DevNull, goPackageDir(file, runDir)}
+		},
+	},
+	{
+		Name:     "go vet",
+		Language: "go",
+		Exts:     []string{".go"},
+		Detect:   func(dir string) bool { return exists(filepath.Join(dir, "go.mod")) },
+		Args: func(file, runDir, _ string) []string {
+			return []string{"go", "vet", goPackageDir(file, runDir)}
+		},
+	},
+	{
+		Name:     "pyright",
+		Language: "python",
+		Exts:     []string{".py", ".pyi"},
+		Detect: func(dir string) bool {
+			return exists(filepath.Join(dir, "pyrightconfig.json")) || fileContains(filepath.Join(dir, "pyproject.toml"), "[tool.pyright]")
+		},
+		Args:   func(file, _, _ string) []string { return []string{"pyright", "--outputjson", file} },
+		Format: formatPyright,
+	},
+	{
+		Name:     "mypy",
+		Language: "python",
+		Exts:     []string{".py", ".pyi"},
+		Detect: func(dir string) bool {
+			return exists(filepath.Join(dir, "mypy.ini")) || exists(filepath.Join(dir, ".mypy.ini")) ||
+				fileContains(filepath.Join(dir, "pyproject.toml"), "[tool.mypy]") ||
+				fileContains(filepath.Join(dir, "setup.cfg"), "[mypy]")
+		},
+		// mypy keeps its own incremental cache in .mypy_cache
+		Args: func(file, _, _ string) []string {
+			return []string{"mypy", "--show-column-numbers", "--no-error-summary", "--no-color-output", "--hide-error-context", file}
+		},
+	},
+	{
+		Name:     "cargo check",
+		Language: "rust",
+		Exts:     []string{".rs"},
+		Detect:   func(dir string) bool { return exists(filepath.Join(dir, "Cargo.toml")) },
+		Args: func(_, _, _ string) []string {
+			return []string{"cargo", "check", "--quiet", "--message-format=short"}
+		},
+	},
+}
+
+// TypecheckChanged is a postToolUse hook that type checks the project a
+// written file belongs to (tsc, go build/go vet, pyright or mypy, cargo check)
+// and reports errors in that file.
 func TypecheckChanged(input HookInput, workDir string) (HookResult, int) {
+	return TypecheckChangedWithPolicy(input, workDir, "", nil)
+}
+
+// TypecheckChangedWithPolicy runs TypecheckChanged keeping state in cacheDir:
+// tsc build info, the files written in each session (errors in any of them
+// are reported, not just the last one) and the content hash of files that
+// last passed, so rewriting a file unchanged costs nothing. An empty cacheDir
+// disables all three.
+func TypecheckChangedWithPolicy(input HookInput, workDir, cacheDir string, policy *TypecheckPolicy) (HookResult, int) {
 	if input.ToolName != "Write" {
 		return Allow(), 0
 	}
@@ -23,18 +144,14 @@ func TypecheckChanged(input HookInput, workDir string) (HookResult, int) {
 		return Allow(), 0
 	}
 
-	ext := filepath.Ext(path)
-	if ext != ".ts" && ext != ".tsx" {
-		return Allow(), 0
-	}
-
 	// Resolve absolute path
 	absPath := path
 	if !filepath.IsAbs(path) && workDir != "" {
 		absPath = filepath.Join(workDir, path)
 	}
 
-	if _, err := os.Stat(absPath); err != nil {
+	content, err := os.ReadFile(absPath)
+	if err != nil {
 		return Allow(), 0
 	}
 
@@ -43,77 +160,290 @@ func TypecheckChanged(input HookInput, workDir string) (HookResult, int) {
 		cwd, _ := os.Getwd()
 		projectRoot = cwd
 	}
+	if policy == nil {
+		policy = &TypecheckPolicy{}
+	}
+
+	checkers, runDirs := typeCheckersFor(projectRoot, absPath, policy)
+	if len(checkers) == 0 {
+		return Allow(), 0
+	}
 
-	// Check for tsconfig.json
-	tsconfig := filepath.Join(projectRoot, "tsconfig.json")
-	if _, err := os.Stat(tsconfig); err != nil {
+	state := loadTypecheckState(cacheDir)
+	hash := shortHash(string(content))
+	if state.Passed[absPath] == hash {
 		return Allow(), 0
 	}
+	session := input.SessionID()
+	touched := state.touch(session, absPath)
+
+	for i, tc := range checkers {
+		result, code, done := runTypeChecker(tc, absPath, runDirs[i], cacheDir, touched, policy)
+		if done {
+			passed := code == 0 && result.Message == ""
+			updateTypecheckState(cacheDir, func(st *typecheckState) {
+				st.touch(session, absPath)
+				if passed {
+					st.pass(absPath, hash)
+				} else {
+					delete(st.Passed, absPath)
+				}
+			})
+			return result, code
+		}
+	}
 
-	// Run tsc with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	updateTypecheckState(cacheDir, func(st *typecheckState) {
+		st.touch(session, absPath)
+		st.pass(absPath, hash)
+	})
+	return Allow(), 0
+}
+
+// typeCheckersFor returns the enabled checkers for file whose project config
+// is found, with the directory each runs in.
+func typeCheckersFor(root, file string, policy *TypecheckPolicy) ([]typeChecker, []string) {
+	var checkers []typeChecker
+	var dirs []string
+	ext := filepath.Ext(file)
+	for _, tc := range typeCheckers {
+		if !hasString(tc.Exts, ext) || hasString(policy.Disable, tc.Name) || hasString(policy.Disable, tc.Language) {
+			continue
+		}
+		for _, dir := range dirsUpTo(filepath.Dir(file), root) {
+			if tc.Detect(dir) {
+				checkers = append(checkers, tc)
+				dirs = append(dirs, dir)
+				break
+			}
+		}
+	}
+	return checkers, dirs
+}
+
+// runTypeChecker runs one checker. done is false when it passed (or could not
+// run) and the next checker for the file should go; otherwise result is final.
+func runTypeChecker(tc typeChecker, file, runDir, cacheDir string, touched []string, policy *TypecheckPolicy) (HookResult, int, bool) {
+	args := tc.Args(file, runDir, cacheDir)
+	if len(args) == 0 || !commandExists(args[0]) {
+		return Allow(), 0, false // Fail open if the checker is not installed
+	}
+	if cacheDir != "" {
+		os.MkdirAll(cacheDir, 0755)
+	}
+
+	timeout := typecheckTimeouts[tc.Language]
+	if s := policy.Timeouts[tc.Language]; s > 0 {
+		timeout = time.Duration(s) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "npx", "tsc", "--noEmit", absPath)
-	cmd.Dir = projectRoot
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = runDir
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
-	exitCode := cmd.ProcessState.ExitCode()
+	if ctx.Err() == context.DeadlineExceeded {
+		// A slow project is not the agent's fault; say so and move on
+		return AllowMsg(fmt.Sprintf("%s timed out after %s; type errors were not checked (raise policies.typecheckChanged.timeouts.%s)", tc.Name, timeout, tc.Language)), 0, true
+	}
+	if err != nil && cmd.ProcessState == nil {
+		return Allow(), 0, false
+	}
+	if cmd.ProcessState.ExitCode() == 0 {
+		return Allow(), 0, false
+	}
 
-	if err != nil {
-		// Check if it's a timeout
-		if ctx.Err() == context.DeadlineExceeded {
-			return Deny("TypeScript type checking timed out after 60 seconds"), 2
+	fileName := filepath.Base(file)
+	output := stdout.String()
+	if tc.Format != formatPyright {
+		output += "\n" + stderr.String()
+	}
+	all, parsed := parseDiagnostics(tc.Format, output, runDir, tc.Name)
+	if parsed {
+		var diags []Diagnostic
+		for _, d := range all {
+			if d.Severity != "error" {
+				continue
+			}
+			for _, t := range touched {
+				if d.File == t || sameFile(d.File, t) {
+					diags = append(diags, d)
+					break
+				}
+			}
 		}
-		// Check if command not found
-		if strings.Contains(err.Error(), "executable file not found") {
-			return Allow(), 0 // Fail open if tsc not available
+		diags = dedupeDiagnostics(diags)
+		if len(diags) == 0 {
+			return Allow(), 0, true // errors elsewhere in the project are not this session's doing
 		}
-	}
 
-	if exitCode != 0 {
-		fileName := filepath.Base(path)
-		output := stderr.String()
-		if output == "" {
-			output = stdout.String()
+		var reason strings.Builder
+		reason.WriteString(fmt.Sprintf("Type errors found in %s (%s)", describeDiagnosticFiles(diags, runDir), tc.Name))
+		reason.WriteString(formatDiagnostics(diags, runDir))
+		reason.WriteString("\n  Hints:")
+		reason.WriteString("\n    - Fix type errors before continuing")
+		for _, d := range diags {
+			if d.File != file && !sameFile(d.File, file) {
+				reason.WriteString("\n    - Some errors are in files written earlier in this session")
+				break
+			}
 		}
+		result := Deny(reason.String())
+		result.Diagnostics = diags
+		return result, 2, true
+	}
 
-		errors := parseTscOutput(output)
+	// A failure without diagnostics is the checker itself not working
+	// (missing dependencies, a broken config), not a type error in the file.
+	note := fmt.Sprintf("%s exited with code %d without reporting type errors; %s was not type checked", tc.Name, cmd.ProcessState.ExitCode(), fileName)
+	if lines := nonEmptyLines(output); len(lines) > 0 {
+		if len(lines) > 5 {
+			lines = lines[:5]
+		}
+		note += ":\n  " + strings.Join(lines, "\n  ")
+	}
+	return AllowMsg(note), 0, true
+}
 
-		var reason strings.Builder
-		reason.WriteString(fmt.Sprintf("Type errors found in %s", fileName))
-		if len(errors) > 0 {
-			reason.WriteString(fmt.Sprintf("\n  Details: %s", errors[0]))
-		} else {
-			reason.WriteString("\n  Details: See output below")
+// nodeBin returns the project-local executable name from node_modules/.bin
+// in dir or the nearest parent that has one, or "".
+func nodeBin(dir, name string) string {
+	for {
+		if p := filepath.Join(dir, "node_modules", ".bin", name); exists(p) {
+			return p
 		}
-		reason.WriteString("\n  Hints:")
-		reason.WriteString("\n    - Fix type errors before continuing")
-		reason.WriteString("\n    - Run 'npm run type-check' to see all errors")
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
 
-		if output != "" {
-			reason.WriteString("\n\n" + output)
+// describeDiagnosticFiles names the files diags are in, relative to dir.
+func describeDiagnosticFiles(diags []Diagnostic, dir string) string {
+	seen := map[string]bool{}
+	var files []string
+	for _, d := range diags {
+		f := d.File
+		if rel, err := filepath.Rel(dir, f); err == nil && !strings.HasPrefix(rel, "..") {
+			f = rel
+		}
+		if !seen[f] {
+			seen[f] = true
+			files = append(files, f)
 		}
+	}
+	if len(files) == 1 {
+		return files[0]
+	}
+	return describeFiles(files, "file", "files")
+}
 
-		return Deny(reason.String()), 2
+// goPackageDir returns the ./pkg pattern for the package containing file.
+func goPackageDir(file, runDir string) string {
+	rel, err := filepath.Rel(runDir, filepath.Dir(file))
+	if err != nil || rel == "." {
+		return "."
 	}
+	return "./" + filepath.ToSlash(rel)
+}
 
-	return Allow(), 0
+// typecheckState is kept in <cacheDir>/state.json.
+type typecheckState struct {
+	Passed   map[string]string   `json:"passed"`   // file -> content hash when it last passed
+	Sessions map[string][]string `json:"sessions"` // session -> files written
+	Updated  map[string]int64    `json:"updated"`  // session -> unix time of last write
 }
 
-func parseTscOutput(output string) []string {
-	var errors []string
-	lines := strings.Split(output, "\n")
+// typecheckSessionTTL is how long a session's touched files are remembered.
+const typecheckSessionTTL = 24 * time.Hour
+
+func loadTypecheckState(cacheDir string) *typecheckState {
+	st := &typecheckState{Passed: map[string]string{}, Sessions: map[string][]string{}, Updated: map[string]int64{}}
+	if cacheDir == "" {
+		return st
+	}
+	if data, err := os.ReadFile(filepath.Join(cacheDir, "state.json")); err == nil {
+		json.Unmarshal(data, st)
+	}
+	if st.Passed == nil {
+		st.Passed = map[string]string{}
+	}
+	if st.Sessions == nil {
+		st.Sessions = map[string][]string{}
+	}
+	if st.Updated == nil {
+		st.Updated = map[string]int64{}
+	}
+	return st
+}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" && (strings.Contains(line, "error TS") || strings.Contains(line, ": error")) {
-			errors = append(errors, line)
+// touch records file as written in session and returns every file written
+// in it so far.
+func (st *typecheckState) touch(session, file string) []string {
+	if session == "" {
+		return []string{file}
+	}
+	now := time.Now()
+	for s, ts := range st.Updated {
+		if now.Sub(time.Unix(ts, 0)) > typecheckSessionTTL {
+			delete(st.Sessions, s)
+			delete(st.Updated, s)
 		}
 	}
+	if !hasString(st.Sessions[session], file) {
+		st.Sessions[session] = append(st.Sessions[session], file)
+	}
+	st.Updated[session] = now.Unix()
+	return st.Sessions[session]
+}
+
+func (st *typecheckState) pass(file, hash string) {
+	st.Passed[file] = hash
+}
+
+// updateTypecheckState applies update to the saved state. Type checks take
+// seconds and hooks for parallel writes run at once, so the state is read
+// again under the lock rather than saved from the copy loaded before the check.
+func updateTypecheckState(cacheDir string, update func(*typecheckState)) {
+	if cacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return
+	}
+	path := filepath.Join(cacheDir, "state.json")
+	unlock, ok := lockStateFile(path)
+	if !ok {
+		return
+	}
+	defer unlock()
+	st := loadTypecheckState(cacheDir)
+	update(st)
+	data, _ := json.Marshal(st)
+	writeFileAtomic(path, data)
+}
+
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
+func fileContains(path, substr string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.Contains(string(data), substr)
+}
 
-	return errors
+func hasString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
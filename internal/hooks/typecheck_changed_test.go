@@ -0,0 +1,161 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func typecheckInput(path, session string) HookInput {
+	ti, _ := json.Marshal(map[string]string{"path": path, "session_id": session})
+	return HookInput{ToolName: "Write", ToolInput: ti}
+}
+
+func TestTypecheckChanged_Go(t *testing.T) {
+	if !commandExists("go") {
+		t.Skip("go not installed")
+	}
+	dir := t.TempDir()
+	cache := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(content), 0644)
+		return p
+	}
+	write("go.mod", "module example.com/tc\n\ngo 1.21\n")
+	a := write("pkg/a.go", "package pkg\n\nfunc A() int { return undefinedThing }\n")
+
+	result, code := TypecheckChangedWithPolicy(typecheckInput(a, "s1"), dir, cache, nil)
+	if code != 2 || !strings.Contains(result.Reason, "pkg/a.go:3") || !strings.Contains(result.Reason, "undefined") {
+		t.Fatalf("expected a type error in pkg/a.go, got code=%d %q", code, result.Reason)
+	}
+	if len(result.Diagnostics) == 0 || result.Diagnostics[0].Source != "go build" {
+		t.Errorf("expected go build diagnostics, got %+v", result.Diagnostics)
+	}
+
+	write("pkg/a.go", "package pkg\n\nfunc A() int { return 1 }\n")
+	if result, code := TypecheckChangedWithPolicy(typecheckInput(a, "s1"), dir, cache, nil); code != 0 {
+		t.Fatalf("expected allow after the fix, got %q", result.Reason)
+	}
+	st := loadTypecheckState(cache)
+	if st.Passed[a] == "" {
+		t.Error("passing file should be cached")
+	}
+
+	// Errors in a file written earlier in the session are still reported
+	b := write("pkg/b.go", "package pkg\n\nfunc B() string { return 1 }\n")
+	TypecheckChangedWithPolicy(typecheckInput(b, "s1"), dir, cache, nil)
+	write("pkg/a.go", "package pkg\n\nfunc A() int { return 2 }\n")
+	result, code = TypecheckChangedWithPolicy(typecheckInput(a, "s1"), dir, cache, nil)
+	if code != 2 || !strings.Contains(result.Reason, "pkg/b.go") || !strings.Contains(result.Reason, "written earlier") {
+		t.Errorf("expected the earlier file's error, got code=%d %q", code, result.Reason)
+	}
+
+	// A different session did not touch b.go, so its errors are not blamed on it
+	write("pkg/a.go", "package pkg\n\nfunc A() int { return 3 }\n")
+	if result, code := TypecheckChangedWithPolicy(typecheckInput(a, "s2"), dir, cache, nil); code != 0 {
+		t.Errorf("errors in untouched files should not block, got %q", result.Reason)
+	}
+
+	// Disabled languages are skipped
+	write("pkg/a.go", "package pkg\n\nfunc A() int { return nope }\n")
+	if _, code := TypecheckChangedWithPolicy(typecheckInput(a, "s3"), dir, cache, &TypecheckPolicy{Disable: []string{"go"}}); code != 0 {
+		t.Error("disabled language should not be checked")
+	}
+}
+
+func TestUpdateTypecheckState_Concurrent(t *testing.T) {
+	cache := t.TempDir()
+	const files = 30
+	var wg sync.WaitGroup
+	for i := 0; i < files; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			file := filepath.Join("/src", fmt.Sprintf("f%d.go", i))
+			updateTypecheckState(cache, func(st *typecheckState) {
+				st.touch("s1", file)
+				st.pass(file, "h")
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	st := loadTypecheckState(cache)
+	if len(st.Passed) != files || len(st.Sessions["s1"]) != files {
+		t.Errorf("expected %d files recorded, got %d passed and %d touched", files, len(st.Passed), len(st.Sessions["s1"]))
+	}
+	if matches, _ := filepath.Glob(filepath.Join(cache, "*.tmp")); len(matches) > 0 || exists(filepath.Join(cache, "state.json.lock")) {
+		t.Errorf("temp or lock files left behind: %v", matches)
+	}
+}
+
+func TestTypeCheckersFor(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "web", "src"), 0755)
+	os.WriteFile(filepath.Join(dir, "web", "tsconfig.json"), []byte("{}"), 0644)
+	os.WriteFile(filepath.Join(dir, "pyproject.toml"), []byte("[tool.mypy]\nstrict = true\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "Cargo.toml"), []byte("[package]\n"), 0644)
+
+	tests := []struct {
+		file    string
+		want    []string
+		wantDir string
+	}{
+		{"web/src/app.ts", []string{"tsc"}, "web"},
+		{"app.ts", nil, ""},
+		{"tool.py", []string{"mypy"}, "."},
+		{"src/main.rs", []string{"cargo check"}, "."},
+		{"README.md", nil, ""},
+	}
+	for _, tt := range tests {
+		checkers, dirs := typeCheckersFor(dir, filepath.Join(dir, tt.file), &TypecheckPolicy{})
+		var names []string
+		for _, c := range checkers {
+			names = append(names, c.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: checkers = %v, want %v", tt.file, names, tt.want)
+			continue
+		}
+		if len(dirs) > 0 && dirs[0] != filepath.Join(dir, tt.wantDir) {
+			t.Errorf("%s: runs in %s, want %s", tt.file, dirs[0], tt.wantDir)
+		}
+	}
+}
+
+func TestTypecheckChanged_TscResolution(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string, mode os.FileMode) string {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(content), mode)
+		return p
+	}
+	write("web/tsconfig.json", "{}", 0644)
+	app := write("web/src/app.ts", "export const x: number = 'a'\n", 0644)
+
+	// No local TypeScript: nothing runs, not even a download through npx
+	if result, code := TypecheckChangedWithPolicy(typecheckInput(app, "s1"), dir, "", nil); code != 0 || result.Message != "" {
+		t.Fatalf("expected a silent allow without node_modules/.bin/tsc, got code=%d %q", code, result.Reason)
+	}
+
+	// tsc is found in a parent's node_modules (workspace root)
+	tsc := write("node_modules/.bin/tsc", "#!/bin/sh\necho \"src/app.ts(1,14): error TS2322: Type 'string' is not assignable to type 'number'.\"\nexit 2\n", 0755)
+	result, code := TypecheckChangedWithPolicy(typecheckInput(app, "s1"), dir, "", nil)
+	if code != 2 || !strings.Contains(result.Reason, "TS2322") {
+		t.Fatalf("expected the tsc error, got code=%d %q", code, result.Reason)
+	}
+
+	// A failing tsc without diagnostics fails open with a note
+	os.WriteFile(tsc, []byte("#!/bin/sh\necho 'Cannot find module typescript'\nexit 1\n"), 0755)
+	result, code = TypecheckChangedWithPolicy(typecheckInput(app, "s1"), dir, "", nil)
+	if code != 0 || !strings.Contains(result.Message, "not type checked") || !strings.Contains(result.Message, "Cannot find module") {
+		t.Errorf("expected fail open with a note, got code=%d %q %q", code, result.Reason, result.Message)
+	}
+}
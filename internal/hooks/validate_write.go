@@ -16,61 +16,110 @@ var (
 	serviceAcctRe = regexp.MustCompile(`"type".*service_account`)
 	kubeconfigRe  = regexp.MustCompile(`\.kube/config$`)
 	tfvarsRe      = regexp.MustCompile(`\.tfvars$`)
+	cloudCredsRe  = regexp.MustCompile(`(?:^|/)(?:\.aws/(?:credentials|config)|\.docker/config\.json|\.config/gcloud/(?:credentials\.db|access_tokens\.db|application_default_credentials\.json))$`)
+	// Chrome/Firefox/Brave/Edge profile directories (cookies, saved logins)
+	browserProfileRe = regexp.MustCompile(`(?i)(?:Library/Application Support/(?:Google/Chrome|BraveSoftware|Microsoft Edge|Firefox)|\.mozilla/firefox|\.config/(?:google-chrome|chromium|BraveSoftware|microsoft-edge)|AppData/(?:Local|Roaming)/(?:Google/Chrome|Mozilla/Firefox|BraveSoftware|Microsoft/Edge))(?:/|$)`)
 )
 
-type writeRule struct {
-	check  func(path, basename, contents string) bool
-	reason string
+// sensitivePathRule classifies a path as holding secrets. The same rules back
+// ValidateWrite, ReadGuard and ExfilGuard so reads and writes agree on what is sensitive.
+type sensitivePathRule struct {
+	check func(path, basename string) bool
+	kind  string
 }
 
-var writeDenyRules = []writeRule{
+var sensitivePathRules = []sensitivePathRule{
 	{
-		check: func(_, basename, _ string) bool {
+		check: func(_, basename string) bool {
 			return envFileRe.MatchString(basename) && !envExemptRe.MatchString(basename)
 		},
-		reason: "write to env file (may contain secrets)",
+		kind: "env file (may contain secrets)",
 	},
 	{
-		check:  func(path, _, _ string) bool { return sshKeyRe.MatchString(path) },
-		reason: "write to SSH key file",
+		check: func(path, _ string) bool { return sshKeyRe.MatchString(path) },
+		kind:  "SSH key file",
 	},
 	{
-		check:  func(_, basename, _ string) bool { return certKeyRe.MatchString(basename) },
-		reason: "write to certificate/key file",
+		check: func(_, basename string) bool { return certKeyRe.MatchString(basename) },
+		kind:  "certificate/key file",
 	},
 	{
-		check:  func(_, basename, _ string) bool { return credentialsRe.MatchString(basename) },
-		reason: "write to credentials file",
+		check: func(_, basename string) bool { return credentialsRe.MatchString(basename) },
+		kind:  "credentials file",
 	},
 	{
-		check:  func(_, basename, _ string) bool { return secretsRe.MatchString(basename) },
-		reason: "write to secrets file",
+		check: func(_, basename string) bool { return secretsRe.MatchString(basename) },
+		kind:  "secrets file",
 	},
 	{
-		check:  func(_, basename, _ string) bool { return basename == ".npmrc" },
-		reason: "write to .npmrc (may contain auth tokens)",
+		check: func(_, basename string) bool { return basename == ".npmrc" },
+		kind:  ".npmrc (may contain auth tokens)",
 	},
 	{
-		check:  func(_, basename, _ string) bool { return basename == ".pypirc" },
-		reason: "write to .pypirc (may contain auth tokens)",
+		check: func(_, basename string) bool { return basename == ".pypirc" },
+		kind:  ".pypirc (may contain auth tokens)",
 	},
 	{
-		check:  func(path, _, _ string) bool { return kubeconfigRe.MatchString(path) },
-		reason: "write to kubeconfig",
+		check: func(path, _ string) bool { return kubeconfigRe.MatchString(path) },
+		kind:  "kubeconfig",
 	},
 	{
-		check: func(_, _, contents string) bool {
-			return strings.Contains(contents, `"type"`) && strings.Contains(contents, "service_account")
-		},
-		reason: "file appears to contain a service account key",
+		check: func(_, basename string) bool { return basename == ".htpasswd" },
+		kind:  ".htpasswd",
+	},
+	{
+		check: func(_, basename string) bool { return tfvarsRe.MatchString(basename) },
+		kind:  ".tfvars file (may contain secrets)",
+	},
+	{
+		check: func(path, _ string) bool { return cloudCredsRe.MatchString(path) },
+		kind:  "cloud credentials file",
+	},
+	{
+		check: func(_, basename string) bool { return basename == ".netrc" || basename == ".git-credentials" },
+		kind:  "credential store",
 	},
 	{
-		check:  func(_, basename, _ string) bool { return basename == ".htpasswd" },
-		reason: "write to .htpasswd",
+		check: func(path, _ string) bool { return browserProfileRe.MatchString(path) },
+		kind:  "browser profile data",
 	},
+}
+
+// sensitiveDirRe matches directories whose contents are secrets as a whole
+// (reading or archiving the directory is as bad as reading a key inside it).
+var sensitiveDirRe = regexp.MustCompile(`(?:^|/)(?:\.ssh|\.aws|\.gnupg|\.kube|\.docker|\.config/gcloud)/?$`)
+
+// sensitivePathKind reports whether path is a sensitive file or directory and
+// describes what it is (e.g. "SSH key file").
+func sensitivePathKind(path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	p := filepath.ToSlash(path)
+	basename := filepath.Base(p)
+	for _, rule := range sensitivePathRules {
+		if rule.check(p, basename) {
+			return rule.kind, true
+		}
+	}
+	if sensitiveDirRe.MatchString(p) {
+		return "credentials directory", true
+	}
+	return "", false
+}
+
+type writeRule struct {
+	check  func(path, basename, contents string) bool
+	reason string
+}
+
+// writeDenyRules are content-based checks applied after the path classification.
+var writeDenyRules = []writeRule{
 	{
-		check:  func(_, basename, _ string) bool { return tfvarsRe.MatchString(basename) },
-		reason: "write to .tfvars file (may contain secrets)",
+		check: func(_, _, contents string) bool {
+			return strings.Contains(contents, `"type"`) && strings.Contains(contents, "service_account")
+		},
+		reason: "file appears to contain a service account key",
 	},
 }
 
@@ -78,8 +127,12 @@ var writeDenyRules = []writeRule{
 // but we keep serviceAcctRe for potential future use.
 var _ = serviceAcctRe
 
-// ValidateWrite is a preToolUse hook that blocks writes to sensitive files.
+// ValidateWrite is a preToolUse hook that blocks writes to sensitive files,
+// including writes and deletions made by Shell commands.
 func ValidateWrite(input HookInput) (HookResult, int) {
+	if input.ToolName == "Shell" {
+		return validateShellWrites(input.Command(), input.Cwd())
+	}
 	if input.ToolName != "Write" {
 		return Allow(), 0
 	}
@@ -89,6 +142,10 @@ func ValidateWrite(input HookInput) (HookResult, int) {
 		return Allow(), 0
 	}
 
+	if kind, ok := sensitivePathKind(path); ok {
+		return Deny("Blocked: write to " + kind), 2
+	}
+
 	basename := filepath.Base(path)
 	contents := input.Contents()
 
@@ -100,3 +157,21 @@ func ValidateWrite(input HookInput) (HookResult, int) {
 
 	return Allow(), 0
 }
+
+func validateShellWrites(cmd, workDir string) (HookResult, int) {
+	for _, t := range shellWriteTargets(cmd, workDir) {
+		if t.Kind == shellMkdir {
+			continue
+		}
+		kind, ok := sensitivePathKind(t.Path)
+		if !ok {
+			continue
+		}
+		verb := "write to "
+		if t.Kind == shellDelete {
+			verb = "delete of "
+		}
+		return Deny("Blocked: " + verb + kind + " " + t.Path + " (via '" + t.Command + "')"), 2
+	}
+	return Allow(), 0
+}
@@ -86,3 +86,39 @@ func TestValidateWrite_PassthroughNonWrite(t *testing.T) {
 		t.Errorf("non-Write tool should passthrough, got code=%d decision=%q", code, result.Decision)
 	}
 }
+
+func TestValidateWrite_ShellWrites(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      string
+		wantDeny bool
+	}{
+		{"redirect to .env", "echo SECRET=x > .env", true},
+		{"append to authorized_keys", "echo key >> ~/.ssh/authorized_keys", true},
+		{"tee private key", "echo key | tee ~/.ssh/id_rsa", true},
+		{"cp over credentials", "cp creds.json config/credentials.json", true},
+		{"sed -i env file", "sed -i 's/x/y/' .env.local", true},
+		{"rm private key", "rm ~/.ssh/id_ed25519", true},
+		{"cp key into cwd", "cp key.pem .", true},
+		{"cp key into dir", "cp ~/Downloads/key.pem config/", true},
+		{"cp key into ssh dir", "cp backup/id_rsa ~/.ssh/", true},
+		{"cd then redirect", "cd config && echo '{}' > credentials.json", true},
+		{"read .env", "cat .env", false},
+		{"redirect to source", "echo x > main.go", false},
+		{"redirect from .env", "grep KEY < .env > keys.txt", false},
+		{"mkdir .ssh", "mkdir -p ~/.ssh", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, code := ValidateWrite(shellInput(tt.cmd))
+			if tt.wantDeny {
+				if code != 2 || result.Decision != "deny" {
+					t.Errorf("expected deny for %q, got code=%d decision=%q", tt.cmd, code, result.Decision)
+				}
+			} else if code != 0 || result.Decision != "allow" {
+				t.Errorf("expected allow for %q, got code=%d reason=%q", tt.cmd, code, result.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package transcript
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Calls returns every tool call in order, paired with its result.
+func (t *Transcript) Calls() []Call {
+	results := map[string]*ToolResult{}
+	for i := range t.Messages {
+		for j := range t.Messages[i].ToolResults {
+			r := &t.Messages[i].ToolResults[j]
+			results[r.ToolUseID] = r
+		}
+	}
+	var calls []Call
+	for _, m := range t.Messages {
+		for _, tc := range m.ToolCalls {
+			calls = append(calls, Call{ToolCall: tc, Result: results[tc.ID]})
+		}
+	}
+	return calls
+}
+
+// Errors returns the tool calls whose result was an error.
+func (t *Transcript) Errors() []Call {
+	var out []Call
+	for _, c := range t.Calls() {
+		if c.Failed() {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Commands returns the shell commands the agent ran.
+func (t *Transcript) Commands() []Call {
+	var out []Call
+	for _, c := range t.Calls() {
+		if c.IsShell() {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// FilesWritten returns the files written or edited, in first-touched order.
+func (t *Transcript) FilesWritten() []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, c := range t.Calls() {
+		if p := c.Path(); c.IsWrite() && p != "" && !seen[p] {
+			seen[p] = true
+			files = append(files, p)
+		}
+	}
+	return files
+}
+
+// Usage returns the token usage of the whole session.
+func (t *Transcript) Usage() Usage {
+	var u Usage
+	for _, m := range t.usageMessages() {
+		u = u.Add(m.Usage)
+	}
+	return u
+}
+
+// UsageByModel returns token usage per model.
+func (t *Transcript) UsageByModel() map[string]Usage {
+	out := map[string]Usage{}
+	for _, m := range t.usageMessages() {
+		if m.Role == "assistant" && m.Model != "" {
+			out[m.Model] = out[m.Model].Add(m.Usage)
+		}
+	}
+	return out
+}
+
+// usageMessages returns the messages to count usage from, one per message id.
+// A response whose content blocks are split by other lines (tool results of
+// parallel calls) is not folded by add and repeats the same usage on each
+// part, so the largest report for an id is kept rather than the sum.
+func (t *Transcript) usageMessages() []Message {
+	var out []Message
+	index := map[string]int{}
+	for _, m := range t.Messages {
+		if m.ID == "" {
+			out = append(out, m)
+			continue
+		}
+		key := m.Role + "\x00" + m.ID
+		if i, ok := index[key]; ok {
+			out[i].Usage = out[i].Usage.Max(m.Usage)
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, m)
+	}
+	return out
+}
+
+// AssistantText returns everything the assistant said, one message per line.
+func (t *Transcript) AssistantText() string {
+	var parts []string
+	for _, m := range t.Messages {
+		if m.Role == "assistant" && m.Text != "" {
+			parts = append(parts, m.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// UserPrompts returns the text the user typed (tool results excluded).
+func (t *Transcript) UserPrompts() []string {
+	var out []string
+	for _, m := range t.Messages {
+		if m.Role == "user" && m.Text != "" {
+			out = append(out, m.Text)
+		}
+	}
+	return out
+}
+
+// Span returns the first and last timestamps in the transcript.
+func (t *Transcript) Span() (start, end time.Time) {
+	for _, m := range t.Messages {
+		if m.Time.IsZero() {
+			continue
+		}
+		if start.IsZero() || m.Time.Before(start) {
+			start = m.Time
+		}
+		if m.Time.After(end) {
+			end = m.Time
+		}
+	}
+	return start, end
+}
+
+// IsShell reports whether the call runs a shell command.
+func (c ToolCall) IsShell() bool {
+	return c.Name == "Bash" || c.Name == "Shell" || c.Name == "run_terminal_cmd"
+}
+
+// IsWrite reports whether the call creates or modifies a file.
+func (c ToolCall) IsWrite() bool {
+	switch c.Name {
+	case "Write", "Edit", "MultiEdit", "NotebookEdit", "edit_file", "write", "search_replace":
+		return true
+	}
+	return false
+}
+
+// Command returns the shell command of a shell call.
+func (c ToolCall) Command() string {
+	return c.field("command")
+}
+
+// Path returns the file a call operates on.
+func (c ToolCall) Path() string {
+	for _, k := range []string{"file_path", "path", "notebook_path", "target_file"} {
+		if v := c.field(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (c ToolCall) field(name string) string {
+	var m map[string]interface{}
+	if json.Unmarshal(c.Input, &m) != nil {
+		return ""
+	}
+	s, _ := m[name].(string)
+	return s
+}
@@ -0,0 +1,293 @@
+// Package transcript parses agent session transcripts into typed messages,
+// tool calls, tool results and token usage.
+//
+// Claude Code writes one JSON object per line: user and assistant messages
+// whose content is text, tool_use and tool_result blocks, plus summary and
+// system entries. Cursor's JSONL transcripts use the same content blocks
+// under a top-level role. Anything else is read as a single plain-text
+// message so callers can still search it.
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Formats reported in Transcript.Format.
+const (
+	FormatClaude = "claude"
+	FormatCursor = "cursor"
+	FormatText   = "text"
+)
+
+// Transcript is a parsed session.
+type Transcript struct {
+	Format    string
+	SessionID string
+	Cwd       string
+	Messages  []Message
+}
+
+// Message is one user or assistant turn.
+type Message struct {
+	ID          string
+	Role        string // "user", "assistant" or "system"
+	Time        time.Time
+	Model       string
+	Text        string
+	ToolCalls   []ToolCall
+	ToolResults []ToolResult
+	Usage       Usage
+}
+
+// ToolCall is a tool invocation by the assistant.
+type ToolCall struct {
+	ID    string
+	Name  string
+	Input json.RawMessage
+	Time  time.Time
+}
+
+// ToolResult is the outcome of a tool call, reported back in a user turn.
+type ToolResult struct {
+	ToolUseID string
+	Content   string
+	IsError   bool
+	Time      time.Time
+}
+
+// Call pairs a tool call with its result; Result is nil while it is pending.
+type Call struct {
+	ToolCall
+	Result *ToolResult
+}
+
+// Failed reports whether the call's result is an error.
+func (c Call) Failed() bool {
+	return c.Result != nil && c.Result.IsError
+}
+
+// Usage counts tokens for one request or a whole session.
+type Usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// Add returns the sum of u and o.
+func (u Usage) Add(o Usage) Usage {
+	return Usage{
+		InputTokens:              u.InputTokens + o.InputTokens,
+		OutputTokens:             u.OutputTokens + o.OutputTokens,
+		CacheCreationInputTokens: u.CacheCreationInputTokens + o.CacheCreationInputTokens,
+		CacheReadInputTokens:     u.CacheReadInputTokens + o.CacheReadInputTokens,
+	}
+}
+
+// Sub returns u minus o.
+func (u Usage) Sub(o Usage) Usage {
+	return Usage{
+		InputTokens:              u.InputTokens - o.InputTokens,
+		OutputTokens:             u.OutputTokens - o.OutputTokens,
+		CacheCreationInputTokens: u.CacheCreationInputTokens - o.CacheCreationInputTokens,
+		CacheReadInputTokens:     u.CacheReadInputTokens - o.CacheReadInputTokens,
+	}
+}
+
+// Max returns the larger of u and o for each count. Lines of one streamed
+// response repeat its usage, so Max combines them without double counting.
+func (u Usage) Max(o Usage) Usage {
+	return Usage{
+		InputTokens:              max(u.InputTokens, o.InputTokens),
+		OutputTokens:             max(u.OutputTokens, o.OutputTokens),
+		CacheCreationInputTokens: max(u.CacheCreationInputTokens, o.CacheCreationInputTokens),
+		CacheReadInputTokens:     max(u.CacheReadInputTokens, o.CacheReadInputTokens),
+	}
+}
+
+// Total is every token counted in u.
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// ParseFile parses the transcript at path.
+func ParseFile(path string) (*Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads a transcript. It never fails on content it doesn't understand:
+// unknown lines are skipped, and input with no JSON lines at all is returned
+// as one plain-text assistant message.
+func Parse(r io.Reader) (*Transcript, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	t := &Transcript{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	parsed := false
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var e entry
+		if json.Unmarshal(line, &e) != nil {
+			continue
+		}
+		parsed = true
+		t.add(e)
+	}
+	if !parsed {
+		t.Format = FormatText
+		if text := strings.TrimSpace(string(data)); text != "" {
+			t.Messages = []Message{{Role: "assistant", Text: text}}
+		}
+	}
+	return t, nil
+}
+
+// entry is one JSONL line in either format.
+type entry struct {
+	Type      string          `json:"type"`
+	Role      string          `json:"role"`
+	SessionID string          `json:"sessionId"`
+	Cwd       string          `json:"cwd"`
+	Timestamp string          `json:"timestamp"`
+	RequestID string          `json:"requestId"`
+	Message   json.RawMessage `json:"message"`
+	Content   json.RawMessage `json:"content"`
+}
+
+type apiMessage struct {
+	ID      string          `json:"id"`
+	Role    string          `json:"role"`
+	Model   string          `json:"model"`
+	Content json.RawMessage `json:"content"`
+	Usage   *Usage          `json:"usage"`
+}
+
+type contentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error"`
+}
+
+func (t *Transcript) add(e entry) {
+	role := e.Role
+	switch e.Type {
+	case "user", "assistant":
+		role = e.Type
+		if t.Format == "" {
+			t.Format = FormatClaude
+		}
+	case "":
+		if role == "" {
+			return
+		}
+		if t.Format == "" {
+			t.Format = FormatCursor
+		}
+	default:
+		return // summary, system, file-history-snapshot, ...
+	}
+	if t.SessionID == "" {
+		t.SessionID = e.SessionID
+	}
+	if t.Cwd == "" {
+		t.Cwd = e.Cwd
+	}
+
+	var m apiMessage
+	content := e.Content
+	if len(e.Message) > 0 {
+		if json.Unmarshal(e.Message, &m) == nil {
+			content = m.Content
+			if m.Role != "" {
+				role = m.Role
+			}
+		} else {
+			content = e.Message
+		}
+	}
+	msg := Message{ID: m.ID, Role: role, Model: m.Model}
+	if msg.ID == "" {
+		msg.ID = e.RequestID
+	}
+	msg.Time, _ = time.Parse(time.RFC3339Nano, e.Timestamp)
+	if m.Usage != nil {
+		msg.Usage = *m.Usage
+	}
+
+	var texts []string
+	for _, b := range contentBlocks(content) {
+		switch b.Type {
+		case "text":
+			texts = append(texts, b.Text)
+		case "tool_use":
+			msg.ToolCalls = append(msg.ToolCalls, ToolCall{ID: b.ID, Name: b.Name, Input: b.Input, Time: msg.Time})
+		case "tool_result":
+			msg.ToolResults = append(msg.ToolResults, ToolResult{ToolUseID: b.ToolUseID, Content: blockText(b.Content), IsError: b.IsError, Time: msg.Time})
+		}
+	}
+	msg.Text = strings.Join(texts, "\n")
+
+	// Claude Code writes each content block of a response as its own line
+	// with the same message id and usage; fold them into one message.
+	if n := len(t.Messages); n > 0 && msg.ID != "" && t.Messages[n-1].ID == msg.ID && t.Messages[n-1].Role == msg.Role {
+		prev := &t.Messages[n-1]
+		if msg.Text != "" {
+			if prev.Text != "" {
+				prev.Text += "\n"
+			}
+			prev.Text += msg.Text
+		}
+		prev.ToolCalls = append(prev.ToolCalls, msg.ToolCalls...)
+		prev.ToolResults = append(prev.ToolResults, msg.ToolResults...)
+		prev.Usage = prev.Usage.Max(msg.Usage)
+		return
+	}
+	t.Messages = append(t.Messages, msg)
+}
+
+// contentBlocks decodes content that is either a string or a list of blocks.
+func contentBlocks(raw json.RawMessage) []contentBlock {
+	if len(raw) == 0 {
+		return nil
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return []contentBlock{{Type: "text", Text: s}}
+	}
+	var blocks []contentBlock
+	json.Unmarshal(raw, &blocks)
+	return blocks
+}
+
+// blockText flattens tool result content to text.
+func blockText(raw json.RawMessage) string {
+	var texts []string
+	for _, b := range contentBlocks(raw) {
+		if b.Text != "" {
+			texts = append(texts, b.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
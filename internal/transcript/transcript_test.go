@@ -0,0 +1,134 @@
+package transcript
+
+import (
+	"strings"
+	"testing"
+)
+
+const claudeTranscript = `{"type":"summary","summary":"Fix the parser","leafUuid":"x"}
+{"type":"user","sessionId":"abc","cwd":"/repo","timestamp":"2025-06-01T10:00:00Z","message":{"role":"user","content":"fix the parser"}}
+{"type":"assistant","sessionId":"abc","timestamp":"2025-06-01T10:00:05Z","message":{"id":"msg_1","role":"assistant","model":"claude-sonnet-4","content":[{"type":"text","text":"Looking at it."}],"usage":{"input_tokens":10,"output_tokens":5,"cache_creation_input_tokens":100,"cache_read_input_tokens":1000}}}
+{"type":"assistant","sessionId":"abc","timestamp":"2025-06-01T10:00:06Z","message":{"id":"msg_1","role":"assistant","model":"claude-sonnet-4","content":[{"type":"tool_use","id":"t1","name":"Edit","input":{"file_path":"/repo/parse.go","old_string":"a","new_string":"b"}}],"usage":{"input_tokens":10,"output_tokens":20,"cache_creation_input_tokens":100,"cache_read_input_tokens":1000}}}
+{"type":"user","timestamp":"2025-06-01T10:00:07Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}
+{"type":"assistant","timestamp":"2025-06-01T10:00:10Z","message":{"id":"msg_2","role":"assistant","model":"claude-sonnet-4","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"go test ./..."}}],"usage":{"input_tokens":3,"output_tokens":7}}}
+{"type":"user","timestamp":"2025-06-01T10:00:20Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t2","content":[{"type":"text","text":"FAIL parse_test.go"}],"is_error":true}]}}
+{"type":"assistant","timestamp":"2025-06-01T10:00:30Z","message":{"id":"msg_3","role":"assistant","model":"claude-opus-4","content":[{"type":"text","text":"All tests pass."}],"usage":{"input_tokens":1,"output_tokens":4}}}
+`
+
+func TestParseClaude(t *testing.T) {
+	tr, err := Parse(strings.NewReader(claudeTranscript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Format != FormatClaude || tr.SessionID != "abc" || tr.Cwd != "/repo" {
+		t.Errorf("format=%q session=%q cwd=%q", tr.Format, tr.SessionID, tr.Cwd)
+	}
+	// msg_1's two lines are folded into one message
+	if len(tr.Messages) != 6 {
+		t.Fatalf("expected 6 messages, got %d", len(tr.Messages))
+	}
+	m := tr.Messages[1]
+	if m.Text != "Looking at it." || len(m.ToolCalls) != 1 || m.Usage.OutputTokens != 20 {
+		t.Errorf("unexpected folded message: %+v", m)
+	}
+	if got := tr.UserPrompts(); len(got) != 1 || got[0] != "fix the parser" {
+		t.Errorf("UserPrompts = %q", got)
+	}
+}
+
+func TestCalls(t *testing.T) {
+	tr, _ := Parse(strings.NewReader(claudeTranscript))
+	calls := tr.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	if calls[0].Path() != "/repo/parse.go" || !calls[0].IsWrite() || calls[0].Failed() {
+		t.Errorf("unexpected edit call: %+v", calls[0])
+	}
+	if calls[1].Command() != "go test ./..." || !calls[1].IsShell() || !calls[1].Failed() {
+		t.Errorf("unexpected shell call: %+v", calls[1])
+	}
+	if calls[1].Result.Content != "FAIL parse_test.go" {
+		t.Errorf("result content = %q", calls[1].Result.Content)
+	}
+	if errs := tr.Errors(); len(errs) != 1 || errs[0].ID != "t2" {
+		t.Errorf("Errors = %+v", errs)
+	}
+	if files := tr.FilesWritten(); len(files) != 1 || files[0] != "/repo/parse.go" {
+		t.Errorf("FilesWritten = %q", files)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	tr, _ := Parse(strings.NewReader(claudeTranscript))
+	u := tr.Usage()
+	want := Usage{InputTokens: 14, OutputTokens: 31, CacheCreationInputTokens: 100, CacheReadInputTokens: 1000}
+	if u != want {
+		t.Errorf("Usage = %+v, want %+v", u, want)
+	}
+	by := tr.UsageByModel()
+	if by["claude-opus-4"].OutputTokens != 4 || by["claude-sonnet-4"].OutputTokens != 27 {
+		t.Errorf("UsageByModel = %+v", by)
+	}
+}
+
+func TestUsage_RepeatedMessageID(t *testing.T) {
+	// Parallel tool calls: the results arrive between the blocks of msg_1,
+	// each of which repeats the response's usage
+	const repeated = `{"type":"assistant","message":{"id":"msg_1","role":"assistant","model":"claude-sonnet-4","content":[{"type":"tool_use","id":"t1","name":"Read","input":{}}],"usage":{"input_tokens":10,"output_tokens":30}}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"a"}]}}
+{"type":"assistant","message":{"id":"msg_1","role":"assistant","model":"claude-sonnet-4","content":[{"type":"tool_use","id":"t2","name":"Read","input":{}}],"usage":{"input_tokens":10,"output_tokens":30}}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t2","content":"b"}]}}
+{"type":"assistant","message":{"id":"msg_2","role":"assistant","model":"claude-sonnet-4","content":[{"type":"text","text":"done"}],"usage":{"input_tokens":2,"output_tokens":5}}}
+`
+	tr, _ := Parse(strings.NewReader(repeated))
+	want := Usage{InputTokens: 12, OutputTokens: 35}
+	if u := tr.Usage(); u != want {
+		t.Errorf("Usage = %+v, want %+v", u, want)
+	}
+	if u := tr.UsageByModel()["claude-sonnet-4"]; u != want {
+		t.Errorf("UsageByModel = %+v, want %+v", u, want)
+	}
+}
+
+func TestSpan(t *testing.T) {
+	tr, _ := Parse(strings.NewReader(claudeTranscript))
+	start, end := tr.Span()
+	if d := end.Sub(start).Seconds(); d != 30 {
+		t.Errorf("span = %vs, want 30s", d)
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	in := `{"role":"user","message":{"content":[{"type":"text","text":"add a flag"}]}}
+{"role":"assistant","message":{"content":[{"type":"text","text":"Done."},{"type":"tool_use","id":"c1","name":"Write","input":{"path":"main.go"}}]}}
+`
+	tr, _ := Parse(strings.NewReader(in))
+	if tr.Format != FormatCursor {
+		t.Errorf("format = %q", tr.Format)
+	}
+	if tr.AssistantText() != "Done." {
+		t.Errorf("AssistantText = %q", tr.AssistantText())
+	}
+	if files := tr.FilesWritten(); len(files) != 1 || files[0] != "main.go" {
+		t.Errorf("FilesWritten = %q", files)
+	}
+}
+
+func TestParsePlainText(t *testing.T) {
+	tr, _ := Parse(strings.NewReader("Implementation complete.\n"))
+	if tr.Format != FormatText || tr.AssistantText() != "Implementation complete." {
+		t.Errorf("format=%q text=%q", tr.Format, tr.AssistantText())
+	}
+	if len(tr.Calls()) != 0 {
+		t.Error("plain text has no calls")
+	}
+}
+
+func TestParseSkipsBadLines(t *testing.T) {
+	in := "{not json\n" + `{"type":"user","message":{"role":"user","content":"hi"}}` + "\n"
+	tr, _ := Parse(strings.NewReader(in))
+	if tr.Format != FormatClaude || len(tr.Messages) != 1 {
+		t.Errorf("format=%q messages=%d", tr.Format, len(tr.Messages))
+	}
+}
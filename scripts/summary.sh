@@ -1,11 +1,13 @@
 #!/usr/bin/env bash
 # Print audit and cost summary. Uses HOOK_AUDIT_DIR and HOOK_COST_DIR or ~/.config/hooks/audit and ~/.config/hooks/cost.
-# Audit: line count from log files modified in last 24h (approximate). Cost: sum of tokens from cost.log.
+# Audit: line count from log files modified in last 24h (approximate). Cost: sum of tokens and cost from cost.log
+# (usage reported in session transcripts, recorded by cost-estimator).
 set -euo pipefail
 AUDIT_DIR="${HOOK_AUDIT_DIR:-$HOME/.config/hooks/audit}"
 COST_DIR="${HOOK_COST_DIR:-$HOME/.config/hooks/cost}"
 calls=0
 tokens=0
+cost="0"
 
 if [[ -d "$AUDIT_DIR" ]]; then
   while IFS= read -r f; do
@@ -19,6 +21,7 @@ if [[ -f "$COST_DIR/cost.log" ]]; then
       tokens=$((tokens + BASH_REMATCH[1]))
     fi
   done < "$COST_DIR/cost.log"
+  cost=$(awk 'match($0, /cost=[0-9.]+/) { s += substr($0, RSTART + 5, RLENGTH - 5) } END { printf "%.2f", s }' "$COST_DIR/cost.log")
 fi
 
-echo "Recent: $calls tool calls, ~$tokens tokens (estimate)"
+echo "Recent: $calls tool calls, $tokens tokens, \$$cost"